@@ -1,17 +1,88 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/benchmark"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/user"
 )
 
-func (app *application) adminHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := app.userService.ListAllUsers()
+// listUsersHandler returns a page of users whose username or email starts
+// with q. Pass the returned nextCursor as cursor to get the following page.
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := user.UserQuery{
+		Search: query.Get("q"),
+		Sort:   user.UserSort(query.Get("sort")),
+		Cursor: query.Get("cursor"),
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		http.Error(w, fmt.Sprintf("invalid order `%v`, must be asc or desc", order), http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := app.userService.ListUsers(q)
 	if err != nil {
+		if errors.Is(err, user.ErrInvalidUserQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "error listing users", http.StatusInternalServerError)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, users, nil)
+	err = app.writeJSON(w, http.StatusOK, page, nil)
+	if err != nil {
+		http.Error(w, "error writing JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+const maxBenchmarkDuration = time.Minute
+
+func (app *application) benchmarkHandler(w http.ResponseWriter, r *http.Request) {
+	d := benchmark.DefaultDuration
+	if raw := r.URL.Query().Get("duration"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 || parsed > maxBenchmarkDuration {
+			http.Error(w, fmt.Sprintf("invalid duration, must be between 0 and %v", maxBenchmarkDuration), http.StatusBadRequest)
+			return
+		}
+		d = parsed
+	}
+
+	// Every registered algorithm runs for d, which can outlast the write
+	// timeout.
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Now().Add(app.config.writeTimeout + d*time.Duration(len(hashtype.Registered()))))
+	if err != nil {
+		app.logger.Printf("error extending write deadline: %v", err)
+	}
+
+	results, err := app.benchmarkService.RunAll(d)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error running benchmark: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, results, nil)
 	if err != nil {
 		http.Error(w, "error writing JSON", http.StatusInternalServerError)
 		return
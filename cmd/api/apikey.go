@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+// createAPIKeyHandler issues a new API key to the caller. The key is only
+// ever returned here.
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	key, k, err := app.authService.CreateAPIKey(app.contextGetUser(r), input.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error creating api key: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, map[string]any{"key": key, "apiKey": k}, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := app.authService.ListAPIKeys(app.contextGetUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, keys, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = app.authService.RevokeAPIKey(app.contextGetUser(r), id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			http.Error(w, fmt.Sprintf("api key with id `%v` not found", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "api key revoked")
+}
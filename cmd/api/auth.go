@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	token, u, err := app.authService.Login(input.Email, input.Password)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			app.unauthorized(w, "invalid email or password")
+			return
+		}
+		http.Error(w, fmt.Sprintf("error logging in: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"token": token, "user": u}, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// logoutHandler ends the session the request was authenticated with.
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := requestToken(r)
+	if err != nil || !user.IsSessionToken(token) {
+		http.Error(w, "only sessions can be logged out, revoke API keys instead", http.StatusBadRequest)
+		return
+	}
+
+	err = app.authService.Logout(token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error logging out: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "logged out")
+}
+
+// changePasswordHandler replaces the caller's password, ending all of their
+// sessions.
+func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		CurrentPassword string `json:"currentPassword"`
+		Password        string `json:"password"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err = app.authService.ChangePassword(app.contextGetUser(r), input.CurrentPassword, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidCredentials):
+			http.Error(w, "current password is incorrect", http.StatusForbidden)
+		case errors.Is(err, user.ErrWeakPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, fmt.Sprintf("error changing password: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "password changed")
+}
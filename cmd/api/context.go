@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+func (app *application) contextSetUser(r *http.Request, u *user.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, u)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser returns the authenticated caller. It must only be used
+// behind requireAuthentication.
+func (app *application) contextGetUser(r *http.Request) *user.User {
+	u, ok := r.Context().Value(userContextKey).(*user.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return u
+}
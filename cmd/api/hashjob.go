@@ -1,17 +1,35 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/fmdunlap/unhash/internal/analysis"
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/hashfile"
+	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/library"
 	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+	"github.com/fmdunlap/unhash/internal/user"
 )
 
+const maxFormJSONSize = 64 << 10
+
 func (app *application) createHashJobHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		OwnerId string   `json:"ownerId"`
-		Hashes  []string `json:"hashes"`
+		TeamId   string            `json:"teamId"`
+		HashType hashtype.HashType `json:"hashType"`
+		Hashes   []string          `json:"hashes"`
+		Attack   *attack.Attack    `json:"attack"`
+		Pipeline []hashjob.Stage   `json:"pipeline"`
+		Budget   *hashjob.Budget   `json:"budget"`
 	}
 
 	err := app.readJSON(r, &input)
@@ -20,40 +38,354 @@ func (app *application) createHashJobHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	owner, err := app.userService.GetUser(input.OwnerId)
+	owner := app.contextGetUser(r)
+	if !app.checkTeam(w, owner, input.TeamId) {
+		return
+	}
+
+	stages, ok := app.resolveStages(w, input.Attack, input.Pipeline, owner)
+	if !ok {
+		return
+	}
+
+	report, err := app.hashJobService.CreateHashJob(input.HashType, hashjob.Targets(input.Hashes), owner, input.TeamId, stages, input.Budget)
+	app.writeSubmission(w, report, err)
+}
+
+// uploadHashJobHandler creates a hash job from a multipart hash file upload.
+// The `hashType` field, and optionally the `teamId` field and the `attack`,
+// `pipeline` and `budget` fields as JSON, must come before the `file` part,
+// which is parsed line by line as it is read.
+func (app *application) uploadHashJobHandler(w http.ResponseWriter, r *http.Request) {
+	app.extendDeadlines(w)
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.maxHashFileSize)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading multipart upload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var hashType, teamId string
+	var a *attack.Attack
+	var pipeline []hashjob.Stage
+	var budget *hashjob.Budget
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading multipart upload: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		switch part.FormName() {
+		case "hashType":
+			hashType, err = readFormValue(part)
+		case "teamId":
+			teamId, err = readFormValue(part)
+		case "attack":
+			err = readFormJSON(part, &a)
+		case "pipeline":
+			err = readFormJSON(part, &pipeline)
+		case "budget":
+			err = readFormJSON(part, &budget)
+		case "file":
+			owner := app.contextGetUser(r)
+			if !app.checkTeam(w, owner, teamId) {
+				return
+			}
+
+			t := hashtype.HashType(hashType)
+			if err := t.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			stages, ok := app.resolveStages(w, a, pipeline, owner)
+			if !ok {
+				return
+			}
+
+			var targets []hashjob.Target
+			err = hashfile.Parse(part, t, func(e hashfile.Entry) error {
+				targets = append(targets, hashjob.Target{Hash: e.Hash, Label: e.Label})
+				return nil
+			})
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, fmt.Sprintf("upload exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			report, err := app.hashJobService.CreateHashJob(t, targets, owner, teamId, stages, budget)
+			app.writeSubmission(w, report, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid `%v` field: %v", part.FormName(), err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	http.Error(w, "missing `file` part", http.StatusBadRequest)
+}
+
+// readHashJob gets the job named by the id parameter. Jobs the caller may
+// not access are reported as not found. On failure it writes the error
+// response and returns false.
+func (app *application) readHashJob(w http.ResponseWriter, r *http.Request) (*hashjob.HashJob, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	u := app.contextGetUser(r)
+	teams, err := app.teamService.ListTeams(u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	hj, err := app.hashJobService.GetHashJob(id)
+	if err == nil && !hj.AccessibleBy(u, teams) {
+		err = &uerr.ErrorNotFound{Err: errors.New("hash job not accessible")}
+	}
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			http.Error(w, fmt.Sprintf("hash job with id `%v` not found", id), http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return hj, true
+}
+
+// checkTeam checks that u may share a job with the team with teamId, which
+// takes being a member. An empty teamId shares with no team. On failure it
+// writes the error response and returns false.
+func (app *application) checkTeam(w http.ResponseWriter, u *user.User, teamId string) bool {
+	if teamId == "" {
+		return true
+	}
+
+	team, err := app.teamService.GetTeam(teamId, u)
+	if err == nil && !team.IsMember(u.ID) {
+		err = &uerr.ErrorNotFound{Err: errors.New("user is not a member")}
+	}
 	if err != nil {
 		if errors.Is(err, &uerr.ErrorNotFound{}) {
-			http.Error(w, fmt.Sprintf("user with id `%v` not found", input.OwnerId), http.StatusNotFound)
+			http.Error(w, fmt.Sprintf("team with id `%v` not found", teamId), http.StatusNotFound)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+// resolveStages turns a single attack or a pipeline into the job's stages
+// and checks that owner may use every resource they reference. On failure it
+// writes the error response and returns false.
+func (app *application) resolveStages(w http.ResponseWriter, a *attack.Attack, pipeline []hashjob.Stage, owner *user.User) ([]hashjob.Stage, bool) {
+	// A single attack is shorthand for a pipeline of one stage.
+	stages := pipeline
+	if a != nil {
+		if len(stages) > 0 {
+			http.Error(w, "attack and pipeline cannot both be set", http.StatusBadRequest)
+			return nil, false
+		}
+		stages = []hashjob.Stage{{Type: hashjob.StageTypeAttack, Attack: a}}
+	}
+
+	for i, stage := range stages {
+		if err := stage.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("invalid stage %d: %v", i, err), http.StatusBadRequest)
+			return nil, false
+		}
+		if stage.Attack == nil {
+			continue
+		}
+
+		resources := make(map[string]library.ResourceKind)
+		for _, id := range stage.Attack.Wordlists {
+			resources[id] = library.ResourceKindWordlist
+		}
+		if stage.Attack.Rules != "" {
+			resources[stage.Attack.Rules] = library.ResourceKindRule
+		}
+
+		for id, kind := range resources {
+			_, err := app.libraryService.GetResource(kind, id, owner)
+			if err != nil {
+				if errors.Is(err, &uerr.ErrorNotFound{}) {
+					http.Error(w, fmt.Sprintf("%v with id `%v` not found", kind, id), http.StatusNotFound)
+					return nil, false
+				}
+				http.Error(w, fmt.Sprintf("error getting %v: %v", kind, err), http.StatusInternalServerError)
+				return nil, false
+			}
+		}
+	}
+
+	return stages, true
+}
+
+// writeSubmission responds to a job creation with its submission report.
+func (app *application) writeSubmission(w http.ResponseWriter, report *hashjob.SubmissionReport, err error) {
+	if err != nil {
+		if errors.Is(err, hashjob.ErrNoValidHashes) {
+			err = app.writeJSON(w, http.StatusBadRequest, report, nil)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
 			return
 		}
-		http.Error(w, fmt.Sprintf("error getting user: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, hashjob.ErrInvalidHashJob) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, usage.ErrQuotaExceeded) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error creating hash job: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	hashjobId, err := app.hashJobService.CreateHashJob(input.Hashes, owner)
+	err = app.writeJSON(w, http.StatusCreated, report, nil)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error creating hash job: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// listHashJobsHandler lists the caller's jobs and those of their teams.
+func (app *application) listHashJobsHandler(w http.ResponseWriter, r *http.Request) {
+	u := app.contextGetUser(r)
+	teams, err := app.teamService.ListTeams(u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "%s", hashjobId)
+	jobs, err := app.hashJobService.ListHashJobs(u, teams)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, jobs, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 func (app *application) getHashJobHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	hj, ok := app.readHashJob(w, r)
+	if !ok {
+		return
+	}
+
+	response := struct {
+		*hashjob.HashJob
+		ETASeconds *float64 `json:"etaSeconds,omitempty"`
+	}{HashJob: hj}
+	if eta, ok := app.hashJobETA(hj); ok {
+		seconds := eta.Seconds()
+		response.ETASeconds = &seconds
+	}
+
+	err := app.writeJSON(w, http.StatusOK, response, nil)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// hashJobETA estimates how long the job's remaining attack stages take from
+// the benchmark of its hash type. It is unknown for finished jobs, jobs whose
+// hash type was never benchmarked, and stages whose keyspace is only known
+// once they start.
+func (app *application) hashJobETA(hj *hashjob.HashJob) (time.Duration, bool) {
+	if hj.Status.Terminal() {
+		return 0, false
+	}
+
+	keyspace, err := hj.RemainingKeyspace(func(id string) (uint64, error) {
+		res, _, err := app.libraryService.Locate(library.ResourceKindWordlist, id)
+		if err != nil {
+			return 0, err
+		}
+		return res.Lines, nil
+	})
+	if err != nil {
+		return 0, false
+	}
+
+	eta, err := app.benchmarkService.ETA(hj.HashType, keyspace)
+	if err != nil {
+		return 0, false
+	}
+	return eta, true
+}
+
+// hashJobResultsHandler streams the job's cracked targets in the format given
+// by `?format=`, defaulting to a potfile.
+func (app *application) hashJobResultsHandler(w http.ResponseWriter, r *http.Request) {
+	format := hashjob.ResultFormatPot
+	if f := r.URL.Query().Get("format"); f != "" {
+		format = hashjob.ResultFormat(f)
+	}
+	if err := format.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	hj, err := app.hashJobService.GetHashJob(id)
+	hj, ok := app.readHashJob(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", hj.ID+"."+string(format)))
+	w.WriteHeader(http.StatusOK)
+
+	err := hashjob.WriteResults(w, hj.Results(), format)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		app.logger.Printf("error writing results of hash job %v: %v", hj.ID, err)
+	}
+}
+
+// hashJobAnalysisHandler reports on the passwords cracked so far, listing
+// `?top=` entries in each ranked section.
+func (app *application) hashJobAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	top := analysis.DefaultTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		var err error
+		top, err = strconv.Atoi(value)
+		if err != nil || top < 1 {
+			http.Error(w, fmt.Sprintf("invalid top `%v`", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	hj, ok := app.readHashJob(w, r)
+	if !ok {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, hj, nil)
+	err := app.writeJSON(w, http.StatusOK, analysis.Analyze(hj, top), nil)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -61,13 +393,23 @@ func (app *application) getHashJobHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (app *application) deleteHashJobHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	hj, ok := app.readHashJob(w, r)
+	if !ok {
+		return
+	}
+
+	u := app.contextGetUser(r)
+	teams, err := app.teamService.ListTeams(u)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !hj.DeletableBy(u, teams) {
+		http.Error(w, "only the owner, team admins and admins may delete this hash job", http.StatusForbidden)
 		return
 	}
 
-	err = app.hashJobService.DeleteHashJob(id)
+	err = app.hashJobService.DeleteHashJob(hj.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -76,3 +418,62 @@ func (app *application) deleteHashJobHandler(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "hash job deleted")
 }
+
+func (app *application) addHashesHandler(w http.ResponseWriter, r *http.Request) {
+	hj, ok := app.readHashJob(w, r)
+	if !ok {
+		return
+	}
+	id := hj.ID
+
+	var input struct {
+		Hashes []string `json:"hashes"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(input.Hashes) == 0 {
+		http.Error(w, "hashes cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	report, err := app.hashJobService.AddHashes(id, hashjob.Targets(input.Hashes))
+	if err != nil {
+		switch {
+		case errors.Is(err, &uerr.ErrorNotFound{}):
+			http.Error(w, fmt.Sprintf("hash job with id `%v` not found", id), http.StatusNotFound)
+		case errors.Is(err, hashjob.ErrHashJobNotAppendable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, hashjob.ErrInvalidHashJob):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, usage.ErrQuotaExceeded):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, fmt.Sprintf("error adding hashes: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, report, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// readFormJSON decodes a JSON multipart field into dst.
+func readFormJSON(r io.Reader, dst any) error {
+	value, err := io.ReadAll(io.LimitReader(r, maxFormJSONSize+1))
+	if err != nil {
+		return err
+	}
+	if len(value) > maxFormJSONSize {
+		return fmt.Errorf("value exceeds %d bytes", maxFormJSONSize)
+	}
+
+	return json.Unmarshal(value, dst)
+}
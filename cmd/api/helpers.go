@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -46,6 +47,22 @@ func (app *application) readJSON(r *http.Request, dst interface{}) error {
 	return nil
 }
 
+// extendDeadlines lifts the server read and write timeouts for a request that
+// streams a large body, bounding it by the upload timeout instead.
+func (app *application) extendDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(app.config.uploadTimeout)
+
+	err := rc.SetReadDeadline(deadline)
+	if err != nil {
+		app.logger.Printf("error extending read deadline: %v", err)
+	}
+	err = rc.SetWriteDeadline(deadline)
+	if err != nil {
+		app.logger.Printf("error extending write deadline: %v", err)
+	}
+}
+
 func (app *application) generateID() string {
 	return uuid.New().String()
 }
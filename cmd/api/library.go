@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+const maxFormValueSize = 1024
+
+// uploadResourceHandler streams a multipart upload into the library. The
+// `name` and `shared` fields must come before the `file` part,
+// which is stored without being buffered in memory.
+func (app *application) uploadResourceHandler(kind library.ResourceKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		app.extendDeadlines(w)
+		r.Body = http.MaxBytesReader(w, r.Body, app.config.maxUploadSize)
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error reading multipart upload: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		var name string
+		shared := false
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("error reading multipart upload: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			switch part.FormName() {
+			case "name":
+				name, err = readFormValue(part)
+			case "shared":
+				var value string
+				value, err = readFormValue(part)
+				if err == nil {
+					shared, err = strconv.ParseBool(value)
+				}
+			case "file":
+				owner := app.contextGetUser(r)
+				if name == "" {
+					name = part.FileName()
+				}
+
+				res, err := app.libraryService.AddResource(kind, name, owner, shared, part)
+				if err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						http.Error(w, fmt.Sprintf("upload exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+						return
+					}
+					if errors.Is(err, library.ErrInvalidResource) {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					http.Error(w, fmt.Sprintf("error adding %v: %v", kind, err), http.StatusInternalServerError)
+					return
+				}
+
+				err = app.writeJSON(w, http.StatusCreated, res, nil)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid `%v` field: %v", part.FormName(), err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		http.Error(w, "missing `file` part", http.StatusBadRequest)
+	}
+}
+
+func (app *application) listResourcesHandler(kind library.ResourceKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u := app.contextGetUser(r)
+
+		resources, err := app.libraryService.ListResources(kind, u)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, resources, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func (app *application) getResourceHandler(kind library.ResourceKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		u := app.contextGetUser(r)
+
+		res, err := app.libraryService.GetResource(kind, id, u)
+		if err != nil {
+			if errors.Is(err, &uerr.ErrorNotFound{}) {
+				http.Error(w, fmt.Sprintf("%v with id `%v` not found", kind, id), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = app.writeJSON(w, http.StatusOK, res, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func (app *application) deleteResourceHandler(kind library.ResourceKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		u := app.contextGetUser(r)
+
+		err = app.libraryService.DeleteResource(kind, id, u)
+		if err != nil {
+			if errors.Is(err, &uerr.ErrorNotFound{}) {
+				http.Error(w, fmt.Sprintf("%v with id `%v` not found", kind, id), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, library.ErrResourceInUse) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "%v deleted", kind)
+	}
+}
+
+func readFormValue(r io.Reader) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(r, maxFormValueSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxFormValueSize {
+		return "", fmt.Errorf("value exceeds %d bytes", maxFormValueSize)
+	}
+
+	return string(value), nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/fmdunlap/unhash/internal/rediscache"
@@ -8,10 +10,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/fmdunlap/unhash/internal/benchmark"
 	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/lookup"
+	"github.com/fmdunlap/unhash/internal/mail"
+	"github.com/fmdunlap/unhash/internal/potfile"
+	"github.com/fmdunlap/unhash/internal/rainbow"
+	"github.com/fmdunlap/unhash/internal/ratelimit"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
 	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/fmdunlap/unhash/internal/worker"
 )
 
 const version = "1.0.0"
@@ -20,20 +33,52 @@ const defaultIdleTimeout = time.Minute
 
 const defaultReadTimeout = 10 * time.Second
 const defaultWriteTimeout = 30 * time.Second
+const defaultUploadTimeout = 10 * time.Minute
+
+const defaultMaxUploadSize = 4 << 30
+
+const defaultMaxHashFileSize = 256 << 20
 
 type config struct {
-	port         int
-	env          string
-	idleTimeout  time.Duration
-	readTimeout  time.Duration
-	writeTimeout time.Duration
+	port            int
+	env             string
+	idleTimeout     time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	lookupTables    []string
+	rainbowTables   []string
+	libraryDir      string
+	maxUploadSize   int64
+	maxHashFileSize int64
+	uploadTimeout   time.Duration
+	benchmark       bool
+	benchmarkTime   time.Duration
+	worker          bool
+	workerPoll      time.Duration
+	modelDir        string
+	potfile         string
+	sessionTTL      time.Duration
+	adminEmail      string
+	verificationTTL time.Duration
+	mailFile        string
+	quota           usage.Quota
+	rateLimit       ratelimit.Limit
+	submitRateLimit ratelimit.Limit
 }
 
 type application struct {
-	config         config
-	logger         *log.Logger
-	userService    *user.UserService
-	hashJobService *hashjob.HashJobService
+	config              config
+	logger              *log.Logger
+	userService         *user.UserService
+	authService         *user.AuthService
+	teamService         *user.TeamService
+	verificationService *user.VerificationService
+	hashJobService      *hashjob.HashJobService
+	userDeletionService *hashjob.UserDeletionService
+	usageService        *usage.UsageService
+	libraryService      *library.LibraryService
+	benchmarkService    *benchmark.BenchmarkService
+	rateLimiter         *ratelimit.Limiter
 }
 
 func parseFlags(cfg *config) {
@@ -42,7 +87,76 @@ func parseFlags(cfg *config) {
 	flag.DurationVar(&cfg.idleTimeout, "idle-timeout", defaultIdleTimeout, "Server idle timeout")
 	flag.DurationVar(&cfg.readTimeout, "read-timeout", defaultReadTimeout, "Server read timeout")
 	flag.DurationVar(&cfg.writeTimeout, "write-timeout", defaultWriteTimeout, "Server write timeout")
+	flag.StringVar(&cfg.libraryDir, "library-dir", "library", "Directory to store uploaded wordlists and rules in")
+	flag.Int64Var(&cfg.maxUploadSize, "max-upload-size", defaultMaxUploadSize, "Maximum size of an uploaded file in bytes")
+	flag.Int64Var(&cfg.maxHashFileSize, "max-hash-file-size", defaultMaxHashFileSize, "Maximum size of an uploaded hash file in bytes")
+	flag.DurationVar(&cfg.uploadTimeout, "upload-timeout", defaultUploadTimeout, "Read and write timeout for file uploads")
+	flag.BoolVar(&cfg.benchmark, "benchmark", false, "Benchmark every hash type on startup")
+	flag.DurationVar(&cfg.benchmarkTime, "benchmark-duration", benchmark.DefaultDuration, "How long to benchmark each hash type for")
+	flag.BoolVar(&cfg.worker, "worker", true, "Run hash job attacks in this process")
+	flag.DurationVar(&cfg.workerPoll, "worker-poll-interval", worker.DefaultPollInterval, "How often the worker checks for pending hash jobs")
+	flag.StringVar(&cfg.modelDir, "model-dir", "models", "Directory markov attacks load their models from")
+	flag.StringVar(&cfg.potfile, "potfile", "unhash.pot", "Potfile cracked hashes are recorded in and potfile stages look up")
+	flag.DurationVar(&cfg.sessionTTL, "session-ttl", user.DefaultSessionTTL, "How long a login session lasts without being used")
+	flag.DurationVar(&cfg.verificationTTL, "verification-ttl", user.DefaultVerificationTTL, "How long an email verification token stays valid")
+	flag.StringVar(&cfg.mailFile, "mail-file", "", "File to append outgoing mail to instead of logging it")
+	flag.StringVar(&cfg.adminEmail, "bootstrap-admin", "", "Email of an admin to create on startup if missing; its API key is logged")
+	flag.IntVar(&cfg.quota.MaxConcurrentJobs, "quota-concurrent-jobs", 0, "Default maximum pending and running hash jobs per user (0 = unlimited)")
+	flag.IntVar(&cfg.quota.MaxHashesPerJob, "quota-hashes-per-job", 0, "Default maximum hashes per hash job (0 = unlimited)")
+	flag.Float64Var(&cfg.quota.MaxCPUSecondsPerDay, "quota-cpu-seconds", 0, "Default maximum CPU seconds attacks may use per user per UTC day (0 = unlimited)")
+	flag.Float64Var(&cfg.rateLimit.Rate, "rate-limit", 10, "Requests per second each user or IP may make on average (0 = unlimited)")
+	flag.IntVar(&cfg.rateLimit.Burst, "rate-limit-burst", 20, "Requests each user or IP may make in a burst")
+	flag.Float64Var(&cfg.submitRateLimit.Rate, "submit-rate-limit", 0.2, "Hash job submissions and uploads per second each user or IP may make on average (0 = unlimited)")
+	flag.IntVar(&cfg.submitRateLimit.Burst, "submit-rate-limit-burst", 5, "Hash job submissions and uploads each user or IP may make in a burst")
+	flag.Func("lookup-table", "Precomputed lookup table to resolve new hash jobs against (repeatable)", func(s string) error {
+		cfg.lookupTables = append(cfg.lookupTables, s)
+		return nil
+	})
+	flag.Func("rainbow-table", "Rainbow table the worker looks hashes up in for rainbow stages (repeatable)", func(s string) error {
+		cfg.rainbowTables = append(cfg.rainbowTables, s)
+		return nil
+	})
 	flag.Parse()
+
+	cfg.rateLimit.Name = "default"
+	cfg.submitRateLimit.Name = "submit"
+}
+
+func loadRainbowTable(path string) (*rainbow.Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return rainbow.Read(f)
+}
+
+// bootstrapAdmin creates an admin with email unless a user with it exists,
+// so user management is reachable on a fresh database.
+func (app *application) bootstrapAdmin(email string) error {
+	_, err := app.userService.GetUserByEmail(email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		return err
+	}
+
+	id := app.generateID()
+	username, _, _ := strings.Cut(email, "@")
+	err = app.userService.CreateUser(id, username, email, user.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("error creating admin: %w", err)
+	}
+
+	key, _, err := app.authService.CreateAPIKey(&user.User{ID: id}, "bootstrap")
+	if err != nil {
+		return fmt.Errorf("error creating admin api key: %w", err)
+	}
+
+	app.logger.Printf("Created admin %s with API key %s", email, key)
+	return nil
 }
 
 func main() {
@@ -55,11 +169,88 @@ func main() {
 	sqliteDb := sqlite.NewSqliteStore("data.db", true)
 	redisClient := rediscache.NewRedisClient("localhost:6379", "", true)
 
+	lookups := make([]hashjob.PlaintextLookup, 0, len(cfg.lookupTables))
+	for _, path := range cfg.lookupTables {
+		table, err := lookup.Open(path)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer table.Close()
+
+		logger.Printf("Loaded %s lookup table %s with %d entries", table.HashType(), path, table.Len())
+		lookups = append(lookups, table)
+	}
+
+	libraryStorage, err := library.NewDiskStorage(cfg.libraryDir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	var mailer user.Mailer = mail.NewLogMailer(logger)
+	if cfg.mailFile != "" {
+		mailer = mail.NewFileMailer(cfg.mailFile)
+	}
+
+	userService := user.NewUserService(sqliteDb, redisClient)
+	authService := user.NewAuthService(sqliteDb, sqliteDb, redisClient, userService, cfg.sessionTTL)
+	usageService := usage.NewUsageService(sqliteDb, cfg.quota)
+	hashJobService := hashjob.NewHashJobService(sqliteDb, redisClient, usageService, lookups...)
 	app := &application{
-		config:         cfg,
-		logger:         logger,
-		userService:    user.NewUserService(sqliteDb, redisClient),
-		hashJobService: hashjob.NewHashJobService(sqliteDb, redisClient),
+		config:              cfg,
+		logger:              logger,
+		userService:         userService,
+		authService:         authService,
+		teamService:         user.NewTeamService(sqliteDb, userService),
+		verificationService: user.NewVerificationService(redisClient, userService, mailer, cfg.verificationTTL),
+		hashJobService:      hashJobService,
+		userDeletionService: hashjob.NewUserDeletionService(sqliteDb, hashJobService, userService, authService),
+		usageService:        usageService,
+		libraryService:      library.NewLibraryService(sqliteDb, libraryStorage, hashJobService),
+		benchmarkService:    benchmark.NewBenchmarkService(sqliteDb),
+		rateLimiter:         ratelimit.NewLimiter(redisClient),
+	}
+
+	if cfg.adminEmail != "" {
+		err := app.bootstrapAdmin(cfg.adminEmail)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	if cfg.benchmark {
+		results, err := app.benchmarkService.RunAll(cfg.benchmarkTime)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		for _, r := range results {
+			logger.Printf("Benchmark %s: %.0f candidates/s", r.HashType, r.CandidatesPerSecond)
+		}
+	}
+
+	if cfg.worker {
+		pot, err := potfile.Open(cfg.potfile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer pot.Close()
+		logger.Printf("Loaded potfile %s with %d entries", cfg.potfile, pot.Len())
+
+		rainbows := make([]worker.RainbowTable, 0, len(cfg.rainbowTables))
+		for _, path := range cfg.rainbowTables {
+			table, err := loadRainbowTable(path)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Printf("Loaded %s rainbow table %s with %d chains", table.HashType(), path, table.Len())
+			rainbows = append(rainbows, table)
+		}
+
+		w := worker.NewWorker(app.hashJobService, app.libraryService, pot, app.usageService, app.benchmarkService, rainbows, cfg.modelDir, cfg.workerPoll, logger)
+		go func() {
+			err := w.Run(context.Background())
+			logger.Fatal(err)
+		}()
 	}
 
 	srv := &http.Server{
@@ -71,6 +262,6 @@ func main() {
 	}
 
 	logger.Printf("Starting %s server on %s", cfg.env, srv.Addr)
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	logger.Fatal(err)
 }
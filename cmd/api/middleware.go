@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/ratelimit"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// requireAuthentication resolves the API key or session token in the
+// `Authorization: Bearer` or `X-API-Key` header to a user and puts it in the
+// request context, rejecting the request if there is none or it is invalid.
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		token, err := requestToken(r)
+		if err != nil {
+			app.unauthorized(w, err.Error())
+			return
+		}
+
+		u, err := app.authService.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, user.ErrInvalidCredentials) {
+				app.unauthorized(w, "invalid, revoked or expired credentials")
+				return
+			}
+			http.Error(w, fmt.Sprintf("error authenticating: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetUser(r, u))
+	})
+}
+
+// requireRole rejects callers whose role does not grant everything min
+// does. It must be used behind requireAuthentication.
+func (app *application) requireRole(min user.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.contextGetUser(r).Role.AtLeast(min) {
+				http.Error(w, fmt.Sprintf("this action requires the %v role", min), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimit refuses requests beyond limit with 429 Too Many Requests. Behind
+// requireAuthentication callers are told apart by user, elsewhere by IP, so
+// made up credentials cannot buy fresh buckets. Requests are let through if
+// the limiter is unavailable.
+func (app *application) rateLimit(limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, err := app.rateLimiter.Allow(limit, rateLimitClient(r))
+			if err != nil {
+				app.logger.Printf("Error rate limiting %v: %v", r.URL.Path, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+			if !d.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClient identifies the caller of r for rate limiting: the
+// authenticated user if there is one, otherwise the remote IP.
+func rateLimitClient(r *http.Request) string {
+	if u, ok := r.Context().Value(userContextKey).(*user.User); ok {
+		return "user:" + u.ID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// requestToken returns the API key or session token the request carries.
+func requestToken(r *http.Request) (string, error) {
+	token := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, value, ok := strings.Cut(auth, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", errors.New("invalid Authorization header")
+		}
+		token = value
+	}
+	if token == "" {
+		return "", errors.New("authentication required")
+	}
+
+	return token, nil
+}
+
+func (app *application) unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/ratelimit"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// MockAPIKeyStore implements user.APIKeyStore without any keys
+
+type MockAPIKeyStore struct{}
+
+func (m *MockAPIKeyStore) InsertAPIKey(k *user.APIKey) error {
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetAPIKeyByHash(hash string) (*user.APIKey, error) {
+	return nil, &uerr.ErrorNotFound{}
+}
+
+func (m *MockAPIKeyStore) ListAPIKeys(userId string) ([]user.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockAPIKeyStore) UpdateAPIKey(k *user.APIKey) error {
+	return &uerr.ErrorNotFound{}
+}
+
+// MockBucketStore implements ratelimit.BucketStore
+
+type MockBucketStore struct {
+	Tokens map[string]int
+}
+
+func (m *MockBucketStore) TakeToken(key string, rate float64, burst int) (*ratelimit.Decision, error) {
+	tokens, ok := m.Tokens[key]
+	if !ok {
+		tokens = burst
+	}
+	if tokens == 0 {
+		return &ratelimit.Decision{Limit: burst, RetryAfter: time.Second}, nil
+	}
+	m.Tokens[key] = tokens - 1
+	return &ratelimit.Decision{Allowed: true, Limit: burst, Remaining: tokens - 1}, nil
+}
+
+func TestRateLimit_BadCredentials(t *testing.T) {
+	app := &application{
+		config:      config{rateLimit: ratelimit.Limit{Name: "default", Rate: 1, Burst: 3}},
+		logger:      log.New(io.Discard, "", 0),
+		authService: user.NewAuthService(&MockAPIKeyStore{}, nil, nil, nil, user.DefaultSessionTTL),
+		rateLimiter: ratelimit.NewLimiter(&MockBucketStore{Tokens: make(map[string]int)}),
+	}
+	routes := app.routes()
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       int
+	}{
+		{"first guess", "192.0.2.1:1234", "X-API-Key", http.StatusUnauthorized},
+		{"second guess", "192.0.2.1:1234", "Authorization", http.StatusUnauthorized},
+		{"third guess", "192.0.2.1:1234", "X-API-Key", http.StatusUnauthorized},
+		{"guess over the limit", "192.0.2.1:1234", "X-API-Key", http.StatusTooManyRequests},
+		{"missing credentials over the limit", "192.0.2.1:1234", "", http.StatusTooManyRequests},
+		{"guess from another IP", "192.0.2.2:1234", "X-API-Key", http.StatusUnauthorized},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/hashjob", nil)
+			r.RemoteAddr = tt.remoteAddr
+			token := fmt.Sprintf("uh_guess%d", i)
+			switch tt.header {
+			case "X-API-Key":
+				r.Header.Set("X-API-Key", token)
+			case "Authorization":
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("GET /v1/hashjob status = %v, want %v", w.Code, tt.want)
+			}
+		})
+	}
+}
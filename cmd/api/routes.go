@@ -1,23 +1,91 @@
 package main
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/go-chi/chi/v5"
+)
 
 func (app *application) routes() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Route("/v1", func(r chi.Router) {
-		r.Get("/admin", app.adminHandler)
-		r.Get("/healthcheck", app.healthcheckHandler)
-		r.Route("/hashjob", func(r chi.Router) {
-			r.Post("/", app.createHashJobHandler)
-			r.Get("/{id}", app.getHashJobHandler)
-			r.Delete("/{id}", app.deleteHashJobHandler)
+		// Unauthenticated requests, logins included, are limited per IP.
+		r.Group(func(r chi.Router) {
+			r.Use(app.rateLimit(app.config.rateLimit))
+
+			r.Get("/healthcheck", app.healthcheckHandler)
+			r.Post("/auth/login", app.loginHandler)
+			r.Post("/user/verify", app.verifyEmailHandler)
 		})
-		r.Route("/user", func(r chi.Router) {
-			r.Post("/", app.createUserHandler)
-			r.Get("/", app.getUserQueryHandler)
-			r.Get("/{id}", app.getUserByIdHandler)
-			r.Delete("/{id}", app.deleteUserHandler)
+
+		// Authenticated requests are limited per IP before their credentials
+		// are checked, so guessing keys and tokens is limited too, and per user
+		// after.
+		r.Group(func(r chi.Router) {
+			r.Use(app.rateLimit(app.config.rateLimit))
+			r.Use(app.requireAuthentication)
+			r.Use(app.rateLimit(app.config.rateLimit))
+
+			// Every role may manage its own credentials and read what it
+			// has access to.
+			r.Post("/auth/logout", app.logoutHandler)
+			r.Route("/apikeys", func(r chi.Router) {
+				r.Post("/", app.createAPIKeyHandler)
+				r.Get("/", app.listAPIKeysHandler)
+				r.Delete("/{id}", app.revokeAPIKeyHandler)
+			})
+			r.Put("/user/password", app.changePasswordHandler)
+			r.Get("/user/{id}", app.getUserByIdHandler)
+			r.Patch("/user/{id}", app.updateUserHandler)
+			r.Post("/user/{id}/verification", app.resendVerificationHandler)
+			r.Get("/user/{id}/usage", app.userUsageHandler)
+			r.Get("/hashjob", app.listHashJobsHandler)
+			r.Get("/hashjob/{id}", app.getHashJobHandler)
+			r.Get("/hashjob/{id}/results", app.hashJobResultsHandler)
+			r.Get("/hashjob/{id}/analysis", app.hashJobAnalysisHandler)
+			r.Get("/wordlists", app.listResourcesHandler(library.ResourceKindWordlist))
+			r.Get("/wordlists/{id}", app.getResourceHandler(library.ResourceKindWordlist))
+			r.Get("/rules", app.listResourcesHandler(library.ResourceKindRule))
+			r.Get("/rules/{id}", app.getResourceHandler(library.ResourceKindRule))
+			r.Get("/teams", app.listTeamsHandler)
+			r.Get("/teams/{id}", app.getTeamHandler)
+			r.Put("/teams/{id}/members", app.setTeamMemberHandler)
+			r.Delete("/teams/{id}/members/{userId}", app.removeTeamMemberHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole(user.RoleAnalyst))
+
+				r.Post("/teams", app.createTeamHandler)
+				r.Delete("/hashjob/{id}", app.deleteHashJobHandler)
+				r.Delete("/wordlists/{id}", app.deleteResourceHandler(library.ResourceKindWordlist))
+				r.Delete("/rules/{id}", app.deleteResourceHandler(library.ResourceKindRule))
+
+				// Submissions and uploads start work or take up disk, so
+				// they have a tighter limit of their own.
+				r.Group(func(r chi.Router) {
+					r.Use(app.rateLimit(app.config.submitRateLimit))
+
+					r.Post("/hashjob", app.createHashJobHandler)
+					r.Post("/hashjob/upload", app.uploadHashJobHandler)
+					r.Post("/hashjob/{id}/hashes", app.addHashesHandler)
+					r.Post("/wordlists", app.uploadResourceHandler(library.ResourceKindWordlist))
+					r.Post("/rules", app.uploadResourceHandler(library.ResourceKindRule))
+				})
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole(user.RoleAdmin))
+
+				r.Get("/admin/users", app.listUsersHandler)
+				r.Get("/admin/benchmark", app.benchmarkHandler)
+				r.Post("/user", app.createUserHandler)
+				r.Get("/user", app.getUserQueryHandler)
+				r.Delete("/user/{id}", app.deleteUserHandler)
+				r.Post("/user/{id}/restore", app.restoreUserHandler)
+				r.Put("/user/{id}/quota", app.setUserQuotaHandler)
+				r.Put("/teams/{id}/quota", app.setTeamQuotaHandler)
+			})
 		})
 	})
 
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) createTeamHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	team, err := app.teamService.CreateTeam(input.Name, app.contextGetUser(r))
+	if err != nil {
+		if errors.Is(err, user.ErrEmptyTeamName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error creating team: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, team, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) listTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	teams, err := app.teamService.ListTeams(app.contextGetUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, teams, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) getTeamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	team, err := app.teamService.GetTeam(id, app.contextGetUser(r))
+	if err != nil {
+		app.teamError(w, id, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, team, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// setTeamMemberHandler adds a user to the team or changes whether they are a
+// team admin.
+func (app *application) setTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		UserId string `json:"userId"`
+		Admin  bool   `json:"admin"`
+	}
+
+	err = app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	team, err := app.teamService.SetMember(id, app.contextGetUser(r), input.UserId, input.Admin)
+	if err != nil {
+		app.teamError(w, id, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, team, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) removeTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	team, err := app.teamService.RemoveMember(id, app.contextGetUser(r), chi.URLParam(r, "userId"))
+	if err != nil {
+		app.teamError(w, id, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, team, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) teamError(w http.ResponseWriter, id string, err error) {
+	switch {
+	case errors.Is(err, &uerr.ErrorNotFound{}):
+		http.Error(w, fmt.Sprintf("team with id `%v` or member not found", id), http.StatusNotFound)
+	case errors.Is(err, user.ErrNotTeamAdmin):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, user.ErrLastTeamAdmin):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("error updating team: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+)
+
+// userUsageHandler sums up the work a user's jobs did between the from and
+// to query parameters, RFC 3339 times defaulting to the start of the UTC day
+// and now. Only admins may view the usage of users other than themselves.
+func (app *application) userUsageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := app.contextGetUser(r)
+	if id != caller.ID && !caller.IsAdmin() {
+		http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	from, err := queryTime(r, "from", now.Truncate(24*time.Hour))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := queryTime(r, "to", now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, "to cannot be before from", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := app.usageService.Usage(usage.UserSubject(id), from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error getting usage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, summary, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (app *application) setUserQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = app.userService.GetUser(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	app.setQuota(w, r, usage.UserSubject(id))
+}
+
+func (app *application) setTeamQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = app.teamService.GetTeam(id, app.contextGetUser(r))
+	if err != nil {
+		app.teamError(w, id, err)
+		return
+	}
+
+	app.setQuota(w, r, usage.TeamSubject(id))
+}
+
+// setQuota replaces the quota of subject with the one in the request body.
+func (app *application) setQuota(w http.ResponseWriter, r *http.Request, subject string) {
+	var input usage.Quota
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = app.usageService.SetQuota(subject, input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error setting quota: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, input, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// queryTime parses the RFC 3339 time in query parameter key, returning def
+// if it is missing.
+func queryTime(r *http.Request, key string, def time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %v: %w", key, err)
+	}
+
+	return t, nil
+}
@@ -5,14 +5,19 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/fmdunlap/unhash/internal/hashjob"
 	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
 )
 
 func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
+		Username string    `json:"username"`
+		Email    string    `json:"email"`
+		Password string    `json:"password"`
+		Role     user.Role `json:"role"`
 	}
 
 	err := app.readJSON(r, &input)
@@ -21,28 +26,60 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	existingUser, err := app.userService.GetUserByEmail(input.Email)
-	if err != nil {
-		if !errors.Is(err, &uerr.ErrorNotFound{}) {
-			http.Error(w, fmt.Sprintf("error getting user when checking for existing user: %v", err), http.StatusInternalServerError)
+	if input.Role == "" {
+		input.Role = user.RoleAnalyst
+	}
+
+	// The password is optional, but checked before the user is inserted so a
+	// weak one does not leave a user behind that a retry collides with.
+	if input.Password != "" {
+		err = user.ValidatePassword(input.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
 
-	if existingUser != nil {
-		http.Error(w, fmt.Sprintf("user with email `%s` already exists", input.Email), http.StatusConflict)
+	userId := app.generateID()
+	err = app.userService.CreateUser(userId, input.Username, input.Email, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidRole), errors.Is(err, user.ErrInvalidUser):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, user.ErrEmailTaken), errors.Is(err, user.ErrUsernameTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, fmt.Sprintf("error inserting user: %v", err), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	userId := app.generateID()
-	err = app.userService.CreateUser(userId, input.Username, input.Email)
+	// New users get a first API key whether they have a password or not.
+	if input.Password != "" {
+		err = app.authService.SetPassword(&user.User{ID: userId}, input.Password)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error setting password: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	key, _, err := app.authService.CreateAPIKey(&user.User{ID: userId}, "default")
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error inserting user: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("error creating api key: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "%s", userId)
+	// The user exists either way; a lost email can be sent again.
+	err = app.verificationService.SendVerification(&user.User{ID: userId, Username: input.Username, Email: user.NormalizeEmail(input.Email)})
+	if err != nil {
+		app.logger.Printf("Error sending verification to user %v: %v", userId, err)
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, map[string]string{"id": userId, "key": key}, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 func (app *application) getUserQueryHandler(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +104,8 @@ func (app *application) getUserQueryHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// getUserByIdHandler returns a user. Only admins may look up users other
+// than themselves.
 func (app *application) getUserByIdHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -74,6 +113,12 @@ func (app *application) getUserByIdHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	caller := app.contextGetUser(r)
+	if id != caller.ID && !caller.IsAdmin() {
+		http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		return
+	}
+
 	user, err := app.userService.GetUser(id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error getting user: %v", err), http.StatusInternalServerError)
@@ -87,6 +132,133 @@ func (app *application) getUserByIdHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// updateUserHandler changes the username and/or email of a user. Only admins
+// may update users other than themselves.
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := app.contextGetUser(r)
+	if id != caller.ID && !caller.IsAdmin() {
+		http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		return
+	}
+
+	var input user.UserUpdate
+	err = app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if input.Username == nil && input.Email == nil {
+		http.Error(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	u, err := app.userService.UpdateUser(id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, &uerr.ErrorNotFound{}):
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		case errors.Is(err, user.ErrInvalidUser):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, user.ErrUsernameTaken), errors.Is(err, user.ErrEmailTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, fmt.Sprintf("error updating user: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if input.Email != nil && !u.EmailVerified {
+		err = app.verificationService.SendVerification(u)
+		if err != nil {
+			app.logger.Printf("Error sending verification to user %v: %v", u.ID, err)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, u, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing JSON: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// verifyEmailHandler redeems a token mailed by a verification. It needs no
+// other authentication, the token being proof enough.
+func (app *application) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(r, &input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	u, err := app.verificationService.Verify(input.Token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidVerificationToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error verifying email: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, u, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing JSON: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// resendVerificationHandler mails a user a new verification token. Only
+// admins may do so for users other than themselves.
+func (app *application) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := app.contextGetUser(r)
+	if id != caller.ID && !caller.IsAdmin() {
+		http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		return
+	}
+
+	u, err := app.userService.GetUser(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if u.EmailVerified {
+		http.Error(w, "email is already verified", http.StatusConflict)
+		return
+	}
+
+	err = app.verificationService.SendVerification(u)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error sending verification: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// deleteUserHandler deletes a user and deals with its hash jobs as the
+// policy query parameter says: refuse (the default), cascade or reassign to
+// the newOwner and/or team parameters. With soft=true the user is only
+// marked deleted and can be restored.
 func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -95,13 +267,93 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	u, err := app.userService.GetUser(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("error getting user: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	deletion := hashjob.UserDeletion{Policy: hashjob.DeletionPolicyRefuse}
+	if policy := query.Get("policy"); policy != "" {
+		deletion.Policy = hashjob.DeletionPolicy(policy)
+	}
+	if soft := query.Get("soft"); soft != "" {
+		deletion.Soft, err = strconv.ParseBool(soft)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid soft: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if ownerId := query.Get("newOwner"); ownerId != "" {
+		deletion.NewOwner, err = app.userService.GetUser(ownerId)
+		if err != nil {
+			if errors.Is(err, &uerr.ErrorNotFound{}) {
+				http.Error(w, fmt.Sprintf("new owner `%v` not found", ownerId), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("error getting new owner: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if teamId := query.Get("team"); teamId != "" {
+		deletion.Team, err = app.teamService.GetTeam(teamId, app.contextGetUser(r))
+		if err != nil {
+			if errors.Is(err, &uerr.ErrorNotFound{}) {
+				http.Error(w, fmt.Sprintf("team `%v` not found", teamId), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("error getting team: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
 
-	err = app.userService.DeleteUser(u)
+	err = app.userDeletionService.DeleteUser(u, deletion)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("error deleting user: %v", err), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, hashjob.ErrInvalidDeletion):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, hashjob.ErrOwnerHasActiveJobs), errors.Is(err, hashjob.ErrOwnerHasJobs), errors.Is(err, user.ErrLastTeamAdmin):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, &uerr.ErrorNotFound{}):
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		default:
+			http.Error(w, fmt.Sprintf("error deleting user: %v", err), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "User with id `%s` deleted", id)
 }
+
+// restoreUserHandler undoes a soft deletion.
+func (app *application) restoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := app.userService.RestoreUser(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, &uerr.ErrorNotFound{}):
+			http.Error(w, fmt.Sprintf("user with id `%v` not found", id), http.StatusNotFound)
+		case errors.Is(err, user.ErrUserNotDeleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, fmt.Sprintf("error restoring user: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, u, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing JSON: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/potfile"
+)
+
+type config struct {
+	potfile   string
+	out       string
+	maxLength int
+}
+
+func parseFlags(cfg *config) {
+	flag.StringVar(&cfg.potfile, "potfile", "unhash.pot", "Potfile of recovered plaintexts to train on")
+	flag.StringVar(&cfg.out, "out", filepath.Join("models", "unhash.markov"), "Path to write the markov model to, inside the API's -model-dir")
+	flag.IntVar(&cfg.maxLength, "max-length", 16, "Number of positions to keep separate statistics for")
+	flag.Parse()
+}
+
+func main() {
+	var cfg config
+
+	parseFlags(&cfg)
+
+	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	in, err := os.Open(cfg.potfile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer in.Close()
+
+	entries, err := potfile.Read(in)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	model, err := attack.NewMarkovModel(cfg.maxLength)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, e := range entries {
+		model.Add(e.Plain)
+	}
+
+	err = os.MkdirAll(filepath.Dir(cfg.out), 0755)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Write to a temporary file first so a running job never sees a partial model.
+	tmp := cfg.out + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	_, err = model.WriteTo(out)
+	if err != nil {
+		out.Close()
+		logger.Fatal(err)
+	}
+	err = out.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = os.Rename(tmp, cfg.out)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Printf("Trained markov model on %d plaintexts, written to %s", len(entries), cfg.out)
+}
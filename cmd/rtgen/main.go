@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/rainbow"
+)
+
+type config struct {
+	hashType    string
+	charset     string
+	length      int
+	chainLength int
+	chains      int
+	index       int
+	out         string
+}
+
+func parseFlags(cfg *config) {
+	flag.StringVar(&cfg.hashType, "type", string(hashtype.HashTypeNTLM), "Hash type to build the table for (lm|ntlm|md5)")
+	flag.StringVar(&cfg.charset, "charset", "abcdefghijklmnopqrstuvwxyz0123456789", "Characters plaintexts are built from")
+	flag.IntVar(&cfg.length, "length", 6, "Plaintext length")
+	flag.IntVar(&cfg.chainLength, "chain-length", 2000, "Number of hash/reduce steps per chain")
+	flag.IntVar(&cfg.chains, "chains", 1000000, "Number of chains to generate")
+	flag.IntVar(&cfg.index, "index", 0, "Table index, selecting the reduction functions")
+	flag.StringVar(&cfg.out, "out", "", "Path to write the rainbow table to")
+	flag.Parse()
+}
+
+func main() {
+	var cfg config
+
+	parseFlags(&cfg)
+
+	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	if cfg.out == "" {
+		logger.Fatal("-out is required")
+	}
+
+	table, err := rainbow.Generate(rainbow.Params{
+		HashType:    hashtype.HashType(cfg.hashType),
+		Charset:     cfg.charset,
+		Length:      cfg.length,
+		ChainLength: cfg.chainLength,
+		Index:       cfg.index,
+	}, cfg.chains)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	tmp := cfg.out + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	_, err = table.WriteTo(out)
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		logger.Fatal(err)
+	}
+	err = out.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = os.Rename(tmp, cfg.out)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Printf("Wrote %s rainbow table with %d chains to %s", cfg.hashType, table.Len(), cfg.out)
+}
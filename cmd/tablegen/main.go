@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/lookup"
+)
+
+type config struct {
+	hashType string
+	wordlist string
+	out      string
+}
+
+func parseFlags(cfg *config) {
+	flag.StringVar(&cfg.hashType, "type", string(hashtype.HashTypeMD5), "Hash type to build the table for (md5|sha1|ntlm)")
+	flag.StringVar(&cfg.wordlist, "wordlist", "", "Wordlist to precompute hashes for")
+	flag.StringVar(&cfg.out, "out", "", "Path to write the lookup table to")
+	flag.Parse()
+}
+
+func main() {
+	var cfg config
+
+	parseFlags(&cfg)
+
+	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	if cfg.wordlist == "" || cfg.out == "" {
+		logger.Fatal("both -wordlist and -out are required")
+	}
+
+	in, err := os.Open(cfg.wordlist)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer in.Close()
+
+	tmp := cfg.out + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	count, err := lookup.Build(out, hashtype.HashType(cfg.hashType), in)
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		logger.Fatal(err)
+	}
+	err = out.Close()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = os.Rename(tmp, cfg.out)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Printf("Wrote %s lookup table with %d entries to %s", cfg.hashType, count, cfg.out)
+}
@@ -0,0 +1,168 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fmdunlap/unhash/internal/hashjob"
+)
+
+// DefaultTop is how many entries the ranked sections of a report list.
+const DefaultTop = 10
+
+// minBaseWordLength skips base words too short to tell anything about how
+// passwords are chosen.
+const minBaseWordLength = 3
+
+// Count is how often a value occurs among the cracked targets.
+type Count struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Report summarizes the passwords cracked in a job. Every cracked target
+// counts, so a password shared by several users weighs once per user.
+type Report struct {
+	Targets   int         `json:"targets"`
+	Cracked   int         `json:"cracked"`
+	Unique    int         `json:"unique"`
+	Lengths   map[int]int `json:"lengths"`
+	Charsets  []Count     `json:"charsets"`
+	BaseWords []Count     `json:"baseWords"`
+	Masks     []Count     `json:"masks"`
+	Reused    []Count     `json:"reused"`
+}
+
+// charsets names each combination of character classes, indexed by a bit
+// set of lower (1), upper (2), digit (4) and special (8).
+var charsets = [16]string{
+	"empty",
+	"loweralpha",
+	"upperalpha",
+	"mixedalpha",
+	"numeric",
+	"loweralphanum",
+	"upperalphanum",
+	"mixedalphanum",
+	"special",
+	"loweralphaspecial",
+	"upperalphaspecial",
+	"mixedalphaspecial",
+	"specialnum",
+	"loweralphaspecialnum",
+	"upperalphaspecialnum",
+	"mixedalphaspecialnum",
+}
+
+// Analyze reports on the passwords cracked in hj, listing the top most
+// common charsets, base words, masks and reused passwords.
+func Analyze(hj *hashjob.HashJob, top int) *Report {
+	report := &Report{Lengths: make(map[int]int)}
+	for _, hash := range hj.Hashes {
+		report.Targets += max(len(hj.Labels[hash]), 1)
+	}
+
+	charsets := make(map[string]int)
+	baseWords := make(map[string]int)
+	masks := make(map[string]int)
+	plains := make(map[string]int)
+	for _, r := range hj.Results() {
+		report.Cracked++
+		report.Lengths[len([]rune(r.Plain))]++
+		charsets[Charset(r.Plain)]++
+		masks[Mask(r.Plain)]++
+		plains[r.Plain]++
+		if word := BaseWord(r.Plain); len(word) >= minBaseWordLength {
+			baseWords[word]++
+		}
+	}
+
+	reused := make(map[string]int)
+	for plain, n := range plains {
+		if n > 1 {
+			reused[plain] = n
+		}
+	}
+
+	report.Unique = len(plains)
+	report.Charsets = rank(charsets, top)
+	report.BaseWords = rank(baseWords, top)
+	report.Masks = rank(masks, top)
+	report.Reused = rank(reused, top)
+
+	return report
+}
+
+// Charset names the character classes plain is made of, as pipal does.
+// Anything that is not an ASCII letter or digit counts as special.
+func Charset(plain string) string {
+	set := 0
+	for _, c := range plain {
+		switch {
+		case c >= 'a' && c <= 'z':
+			set |= 1
+		case c >= 'A' && c <= 'Z':
+			set |= 2
+		case c >= '0' && c <= '9':
+			set |= 4
+		default:
+			set |= 8
+		}
+	}
+
+	return charsets[set]
+}
+
+// Mask returns the hashcat mask matching plain. Characters outside printable
+// ASCII are matched by `?b`.
+func Mask(plain string) string {
+	var b strings.Builder
+	for i := 0; i < len(plain); i++ {
+		c := plain[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			b.WriteString("?l")
+		case c >= 'A' && c <= 'Z':
+			b.WriteString("?u")
+		case c >= '0' && c <= '9':
+			b.WriteString("?d")
+		case c >= 0x20 && c <= 0x7e:
+			b.WriteString("?s")
+		default:
+			b.WriteString("?b")
+		}
+	}
+
+	return b.String()
+}
+
+// BaseWord strips the non-letters plain starts and ends with and lowercases
+// the rest, so `Summer2024!` and `summer` share the base word `summer`.
+func BaseWord(plain string) string {
+	return strings.ToLower(strings.TrimFunc(plain, func(c rune) bool {
+		return !unicode.IsLetter(c)
+	}))
+}
+
+// rank sorts counts by how common they are, most common first, keeping at
+// most top of them.
+func rank(counts map[string]int, top int) []Count {
+	ranked := make([]Count, 0, len(counts))
+	for value, n := range counts {
+		ranked = append(ranked, Count{Value: value, Count: n})
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Value < ranked[j].Value
+	})
+
+	if top > 0 && len(ranked) > top {
+		ranked = ranked[:top]
+	}
+
+	return ranked
+}
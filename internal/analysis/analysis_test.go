@@ -0,0 +1,88 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/hashjob"
+)
+
+func TestCharset(t *testing.T) {
+	tests := []struct {
+		plain string
+		want  string
+	}{
+		{plain: "password", want: "loweralpha"},
+		{plain: "PASSWORD", want: "upperalpha"},
+		{plain: "123456", want: "numeric"},
+		{plain: "Password1", want: "mixedalphanum"},
+		{plain: "pass word", want: "loweralphaspecial"},
+		{plain: "Summer2024!", want: "mixedalphaspecialnum"},
+		{plain: "", want: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test Charset "+tt.plain, func(t *testing.T) {
+			if got := Charset(tt.plain); got != tt.want {
+				t.Errorf("Charset() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	if got := Mask("Pa5$é"); got != "?u?l?d?s?b?b" {
+		t.Errorf("Mask() got = %v, want ?u?l?d?s?b?b", got)
+	}
+}
+
+func TestBaseWord(t *testing.T) {
+	tests := map[string]string{
+		"Summer2024!": "summer",
+		"123password": "password",
+		"p4ssw0rd":    "p4ssw0rd",
+		"2024":        "",
+	}
+
+	for plain, want := range tests {
+		if got := BaseWord(plain); got != want {
+			t.Errorf("BaseWord(%q) got = %v, want %v", plain, got, want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	hj := &hashjob.HashJob{
+		Hashes: []string{"a", "b", "c", "d"},
+		Labels: map[string][]string{"a": {"alice", "bob", "carol"}},
+		Cracked: map[string]string{
+			"a": "Summer2024",
+			"b": "summer!",
+			"c": "123456",
+		},
+	}
+
+	report := Analyze(hj, 2)
+	if report.Targets != 6 || report.Cracked != 5 || report.Unique != 3 {
+		t.Errorf("Analyze() got %v targets, %v cracked, %v unique, want 6, 5, 3", report.Targets, report.Cracked, report.Unique)
+	}
+	if !reflect.DeepEqual(report.Lengths, map[int]int{10: 3, 7: 1, 6: 1}) {
+		t.Errorf("Analyze() lengths = %v", report.Lengths)
+	}
+	wantCharsets := []Count{{Value: "mixedalphanum", Count: 3}, {Value: "loweralphaspecial", Count: 1}}
+	if !reflect.DeepEqual(report.Charsets, wantCharsets) {
+		t.Errorf("Analyze() charsets = %v, want %v", report.Charsets, wantCharsets)
+	}
+	wantBaseWords := []Count{{Value: "summer", Count: 4}}
+	if !reflect.DeepEqual(report.BaseWords, wantBaseWords) {
+		t.Errorf("Analyze() base words = %v, want %v", report.BaseWords, wantBaseWords)
+	}
+	wantMasks := []Count{{Value: "?u?l?l?l?l?l?d?d?d?d", Count: 3}, {Value: "?d?d?d?d?d?d", Count: 1}}
+	if !reflect.DeepEqual(report.Masks, wantMasks) {
+		t.Errorf("Analyze() masks = %v, want %v", report.Masks, wantMasks)
+	}
+	wantReused := []Count{{Value: "Summer2024", Count: 3}}
+	if !reflect.DeepEqual(report.Reused, wantReused) {
+		t.Errorf("Analyze() reused = %v, want %v", report.Reused, wantReused)
+	}
+}
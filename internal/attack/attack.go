@@ -0,0 +1,261 @@
+package attack
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+type AttackType string
+
+const (
+	AttackTypeDictionary         AttackType = "dictionary"
+	AttackTypeCombinator         AttackType = "combinator"
+	AttackTypeHybridWordlistMask AttackType = "hybrid-wordlist-mask"
+	AttackTypeHybridMaskWordlist AttackType = "hybrid-mask-wordlist"
+	AttackTypeMarkov             AttackType = "markov"
+	AttackTypePrince             AttackType = "prince"
+	AttackTypeMask               AttackType = "mask"
+)
+
+var ErrKeyspaceOverflow = errors.New("keyspace overflows uint64")
+
+// Attack describes how candidates for a hash job are generated. Wordlists are
+// library resource IDs, in the order the attack type consumes them, and Rules
+// is the library ID of a rule file applied by dictionary attacks.
+type Attack struct {
+	Type      AttackType `json:"type"`
+	Wordlists []string   `json:"wordlists,omitempty"`
+	Rules     string     `json:"rules,omitempty"`
+	Mask      string     `json:"mask,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	Threshold int        `json:"threshold,omitempty"`
+	MinLength int        `json:"minLength,omitempty"`
+	MaxLength int        `json:"maxLength,omitempty"`
+}
+
+// Resources are the loaded contents of everything an Attack references.
+// Dictionary attacks pass over their wordlist once and read it from Streams,
+// sized by the matching Lines; every other attack needs random access and
+// reads it from Wordlists.
+type Resources struct {
+	Wordlists [][]string
+	Streams   []*wordlist.Reader
+	Lines     []uint64
+	Rules     []Rule
+	Markov    *MarkovModel
+}
+
+// Generator produces the candidates of an attack in a fixed order, so any
+// position in its keyspace can be checkpointed, resumed from or handed out as
+// a chunk.
+type Generator interface {
+	Keyspace() uint64
+	Position() uint64
+	Seek(pos uint64) error
+	Next() (string, error)
+}
+
+// OffsetSeeker is a Generator streaming a wordlist. Offset is where in the
+// decompressed wordlist the line of its position starts, and resuming from
+// it with SeekOffset skips reading the lines before it one by one.
+type OffsetSeeker interface {
+	Generator
+	Offset() uint64
+	SeekOffset(pos, offset uint64) error
+}
+
+func (a *Attack) Validate() error {
+	if a.Rules != "" && a.Type != AttackTypeDictionary {
+		return fmt.Errorf("%v attack does not take rules", a.Type)
+	}
+
+	switch a.Type {
+	case AttackTypeDictionary:
+		if len(a.Wordlists) != 1 {
+			return fmt.Errorf("dictionary attack requires exactly 1 wordlist, got %d", len(a.Wordlists))
+		}
+		if a.Mask != "" {
+			return errors.New("dictionary attack does not take a mask")
+		}
+	case AttackTypeCombinator:
+		if len(a.Wordlists) != 2 {
+			return fmt.Errorf("combinator attack requires exactly 2 wordlists, got %d", len(a.Wordlists))
+		}
+		if a.Mask != "" {
+			return errors.New("combinator attack does not take a mask")
+		}
+	case AttackTypeHybridWordlistMask, AttackTypeHybridMaskWordlist:
+		if len(a.Wordlists) != 1 {
+			return fmt.Errorf("hybrid attack requires exactly 1 wordlist, got %d", len(a.Wordlists))
+		}
+		if _, err := ParseMask(a.Mask); err != nil {
+			return fmt.Errorf("invalid hybrid mask: %w", err)
+		}
+	case AttackTypeMarkov:
+		if len(a.Wordlists) != 0 {
+			return errors.New("markov attack does not take wordlists")
+		}
+		if a.Model == "" {
+			return errors.New("markov attack requires a model")
+		}
+		if a.Threshold < 0 {
+			return fmt.Errorf("markov threshold cannot be negative, got %d", a.Threshold)
+		}
+		if _, err := ParseMask(a.Mask); err != nil {
+			return fmt.Errorf("invalid markov mask: %w", err)
+		}
+	case AttackTypePrince:
+		if len(a.Wordlists) != 1 {
+			return fmt.Errorf("prince attack requires exactly 1 wordlist, got %d", len(a.Wordlists))
+		}
+		if a.MinLength <= 0 || a.MaxLength < a.MinLength || a.MaxLength > MaxPrinceLength {
+			return fmt.Errorf("invalid prince length range %d-%d, must be within 1-%d", a.MinLength, a.MaxLength, MaxPrinceLength)
+		}
+	case AttackTypeMask:
+		if len(a.Wordlists) != 0 {
+			return errors.New("mask attack does not take wordlists")
+		}
+		if _, err := ParseMask(a.Mask); err != nil {
+			return fmt.Errorf("invalid mask: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown attack type `%v`", a.Type)
+	}
+
+	return nil
+}
+
+// Keyspace returns the number of candidates the attack produces, given the
+// line count of each of its wordlists. The keyspace of prince attacks and of
+// dictionary attacks with rules depends on the file contents and is only known
+// once their Generator is built.
+func (a *Attack) Keyspace(wordlistLines []uint64) (uint64, error) {
+	err := a.Validate()
+	if err != nil {
+		return 0, err
+	}
+	if len(wordlistLines) != len(a.Wordlists) {
+		return 0, fmt.Errorf("expected line counts for %d wordlists, got %d", len(a.Wordlists), len(wordlistLines))
+	}
+
+	switch a.Type {
+	case AttackTypeDictionary:
+		if a.Rules != "" {
+			return 0, errors.New("rules keyspace depends on the rule file contents")
+		}
+		return wordlistLines[0], nil
+	case AttackTypeMask:
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return 0, err
+		}
+		return m.Keyspace()
+	case AttackTypeCombinator:
+		return mulKeyspace(wordlistLines[0], wordlistLines[1])
+	case AttackTypeMarkov:
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return 0, err
+		}
+		return markovKeyspace(m, a.Threshold)
+	case AttackTypePrince:
+		return 0, errors.New("prince keyspace depends on the wordlist contents")
+	default:
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return 0, err
+		}
+		maskKeyspace, err := m.Keyspace()
+		if err != nil {
+			return 0, err
+		}
+		return mulKeyspace(wordlistLines[0], maskKeyspace)
+	}
+}
+
+// Generator builds the candidate generator for the attack from its loaded
+// resources.
+func (a *Attack) Generator(res Resources) (Generator, error) {
+	err := a.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Type == AttackTypeDictionary {
+		if len(res.Streams) != 1 || len(res.Lines) != 1 {
+			return nil, errors.New("dictionary attack requires 1 streamed wordlist and its line count")
+		}
+		if a.Rules != "" {
+			return NewDictionaryRules(res.Streams[0], res.Lines[0], res.Rules)
+		}
+		return NewDictionary(res.Streams[0], res.Lines[0]), nil
+	}
+	if a.Type == AttackTypeMask {
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return nil, err
+		}
+		return NewMask(m)
+	}
+
+	if len(res.Wordlists) != len(a.Wordlists) {
+		return nil, fmt.Errorf("expected %d wordlists, got %d", len(a.Wordlists), len(res.Wordlists))
+	}
+
+	switch a.Type {
+	case AttackTypeCombinator:
+		return NewCombinator(res.Wordlists[0], res.Wordlists[1])
+	case AttackTypeMarkov:
+		if res.Markov == nil {
+			return nil, errors.New("markov attack requires a loaded model")
+		}
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return nil, err
+		}
+		return NewMarkov(res.Markov, m, a.Threshold)
+	case AttackTypePrince:
+		return NewPrince(res.Wordlists[0], a.MinLength, a.MaxLength)
+	default:
+		m, err := ParseMask(a.Mask)
+		if err != nil {
+			return nil, err
+		}
+		return NewHybrid(res.Wordlists[0], m, a.Type == AttackTypeHybridMaskWordlist)
+	}
+}
+
+type Chunk struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// Partition splits a keyspace into contiguous [Start, End) chunks of at most
+// chunkSize candidates.
+func Partition(keyspace, chunkSize uint64) []Chunk {
+	if keyspace == 0 || chunkSize == 0 {
+		return nil
+	}
+
+	chunks := make([]Chunk, 0, (keyspace+chunkSize-1)/chunkSize)
+	for start, end := uint64(0), uint64(0); start < keyspace; start = end {
+		end = start + chunkSize
+		if end > keyspace || end < start {
+			end = keyspace
+		}
+		chunks = append(chunks, Chunk{Start: start, End: end})
+	}
+
+	return chunks
+}
+
+func mulKeyspace(a, b uint64) (uint64, error) {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return 0, ErrKeyspaceOverflow
+	}
+	return lo, nil
+}
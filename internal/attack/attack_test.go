@@ -0,0 +1,424 @@
+package attack
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Helpers
+
+func drain(t *testing.T, g Generator) []string {
+	candidates := make([]string, 0)
+	for {
+		c, err := g.Next()
+		if errors.Is(err, io.EOF) {
+			return candidates
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		candidates = append(candidates, c)
+	}
+}
+
+func checkCandidates(t *testing.T, got, want []string) {
+	if len(got) != len(want) {
+		t.Errorf("got %d candidates, want %d", len(got), len(want))
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidate %d got = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// Mask Tests
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		name         string
+		mask         string
+		wantLen      int
+		wantKeyspace uint64
+		wantErr      bool
+	}{
+		{name: "Test digits", mask: "?d?d?d?d", wantLen: 4, wantKeyspace: 10000},
+		{name: "Test mixed charsets and literals", mask: "a?l?u!", wantLen: 4, wantKeyspace: 26 * 26},
+		{name: "Test all printable", mask: "?a", wantLen: 1, wantKeyspace: 95},
+		{name: "Test literal question mark", mask: "??", wantLen: 1, wantKeyspace: 1},
+		{name: "Test empty mask", mask: "", wantErr: true},
+		{name: "Test incomplete charset", mask: "?d?", wantErr: true},
+		{name: "Test unknown charset", mask: "?z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMask(tt.mask)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseMask() got = %v, want error", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseMask() error = %v", err)
+				return
+			}
+
+			if m.Len() != tt.wantLen {
+				t.Errorf("Len() got = %v, want %v", m.Len(), tt.wantLen)
+			}
+
+			keyspace, err := m.Keyspace()
+			if err != nil {
+				t.Errorf("Keyspace() error = %v", err)
+			}
+			if keyspace != tt.wantKeyspace {
+				t.Errorf("Keyspace() got = %v, want %v", keyspace, tt.wantKeyspace)
+			}
+		})
+	}
+}
+
+func TestMask_KeyspaceOverflow(t *testing.T) {
+	m, err := ParseMask(strings.Repeat("?a", 12))
+	if err != nil {
+		t.Fatalf("ParseMask() error = %v", err)
+	}
+
+	_, err = m.Keyspace()
+	if !errors.Is(err, ErrKeyspaceOverflow) {
+		t.Errorf("Keyspace() error = %v, want %v", err, ErrKeyspaceOverflow)
+	}
+}
+
+func TestMask_Candidate(t *testing.T) {
+	m, err := ParseMask("x?d?h")
+	if err != nil {
+		t.Fatalf("ParseMask() error = %v", err)
+	}
+
+	tests := []struct {
+		i    uint64
+		want string
+	}{
+		{i: 0, want: "x00"},
+		{i: 1, want: "x01"},
+		{i: 15, want: "x0f"},
+		{i: 16, want: "x10"},
+		{i: 159, want: "x9f"},
+	}
+
+	for _, tt := range tests {
+		if got := m.Candidate(tt.i); got != tt.want {
+			t.Errorf("Candidate(%d) got = %v, want %v", tt.i, got, tt.want)
+		}
+	}
+}
+
+// Attack Tests
+
+func TestAttack_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		attack  Attack
+		wantErr bool
+	}{
+		{
+			name:   "Test combinator",
+			attack: Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+		},
+		{
+			name:    "Test combinator with one wordlist",
+			attack:  Attack{Type: AttackTypeCombinator, Wordlists: []string{"a"}},
+			wantErr: true,
+		},
+		{
+			name:    "Test combinator with mask",
+			attack:  Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}, Mask: "?d"},
+			wantErr: true,
+		},
+		{
+			name:   "Test hybrid wordlist mask",
+			attack: Attack{Type: AttackTypeHybridWordlistMask, Wordlists: []string{"a"}, Mask: "?d?d"},
+		},
+		{
+			name:   "Test hybrid mask wordlist",
+			attack: Attack{Type: AttackTypeHybridMaskWordlist, Wordlists: []string{"a"}, Mask: "?d?d"},
+		},
+		{
+			name:    "Test hybrid without mask",
+			attack:  Attack{Type: AttackTypeHybridWordlistMask, Wordlists: []string{"a"}},
+			wantErr: true,
+		},
+		{
+			name:   "Test markov",
+			attack: Attack{Type: AttackTypeMarkov, Model: "model", Mask: "?l?l?l", Threshold: 5},
+		},
+		{
+			name:    "Test markov without model",
+			attack:  Attack{Type: AttackTypeMarkov, Mask: "?l?l?l"},
+			wantErr: true,
+		},
+		{
+			name:    "Test markov with negative threshold",
+			attack:  Attack{Type: AttackTypeMarkov, Model: "model", Mask: "?l?l?l", Threshold: -1},
+			wantErr: true,
+		},
+		{
+			name:   "Test prince",
+			attack: Attack{Type: AttackTypePrince, Wordlists: []string{"a"}, MinLength: 6, MaxLength: 12},
+		},
+		{
+			name:    "Test prince without length range",
+			attack:  Attack{Type: AttackTypePrince, Wordlists: []string{"a"}},
+			wantErr: true,
+		},
+		{
+			name:    "Test prince with too long max length",
+			attack:  Attack{Type: AttackTypePrince, Wordlists: []string{"a"}, MinLength: 6, MaxLength: MaxPrinceLength + 1},
+			wantErr: true,
+		},
+		{
+			name:   "Test dictionary",
+			attack: Attack{Type: AttackTypeDictionary, Wordlists: []string{"a"}},
+		},
+		{
+			name:    "Test dictionary with two wordlists",
+			attack:  Attack{Type: AttackTypeDictionary, Wordlists: []string{"a", "b"}},
+			wantErr: true,
+		},
+		{
+			name:   "Test dictionary with rules",
+			attack: Attack{Type: AttackTypeDictionary, Wordlists: []string{"a"}, Rules: "r"},
+		},
+		{
+			name:    "Test hybrid with rules",
+			attack:  Attack{Type: AttackTypeHybridWordlistMask, Wordlists: []string{"a"}, Mask: "?d", Rules: "r"},
+			wantErr: true,
+		},
+		{
+			name:   "Test mask",
+			attack: Attack{Type: AttackTypeMask, Mask: "?u?l?d"},
+		},
+		{
+			name:    "Test mask with wordlist",
+			attack:  Attack{Type: AttackTypeMask, Wordlists: []string{"a"}, Mask: "?u?l?d"},
+			wantErr: true,
+		},
+		{
+			name:    "Test unknown type",
+			attack:  Attack{Type: "bogus"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.attack.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttack_Keyspace(t *testing.T) {
+	tests := []struct {
+		name    string
+		attack  Attack
+		lines   []uint64
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name:   "Test dictionary",
+			attack: Attack{Type: AttackTypeDictionary, Wordlists: []string{"a"}},
+			lines:  []uint64{1000},
+			want:   1000,
+		},
+		{
+			name:   "Test combinator",
+			attack: Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+			lines:  []uint64{1000, 250},
+			want:   250000,
+		},
+		{
+			name:   "Test hybrid",
+			attack: Attack{Type: AttackTypeHybridWordlistMask, Wordlists: []string{"a"}, Mask: "?d?d?d?d"},
+			lines:  []uint64{300},
+			want:   3000000,
+		},
+		{
+			name:   "Test markov with threshold",
+			attack: Attack{Type: AttackTypeMarkov, Model: "model", Mask: "?l?l?d", Threshold: 4},
+			lines:  []uint64{},
+			want:   4 * 4 * 4,
+		},
+		{
+			name:   "Test markov without threshold",
+			attack: Attack{Type: AttackTypeMarkov, Model: "model", Mask: "?l?l?d"},
+			lines:  []uint64{},
+			want:   26 * 26 * 10,
+		},
+		{
+			name:    "Test missing line counts",
+			attack:  Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+			lines:   []uint64{1000},
+			wantErr: true,
+		},
+		{
+			name:    "Test overflow",
+			attack:  Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+			lines:   []uint64{1 << 40, 1 << 40},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.attack.Keyspace(tt.lines)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Keyspace() got = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Keyspace() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Keyspace() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttack_Generator(t *testing.T) {
+	tests := []struct {
+		name      string
+		attack    Attack
+		wordlists [][]string
+		want      []string
+	}{
+		{
+			name:      "Test combinator",
+			attack:    Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+			wordlists: [][]string{{"pass", "love"}, {"word", "123"}},
+			want:      []string{"password", "pass123", "loveword", "love123"},
+		},
+		{
+			name:      "Test hybrid wordlist mask",
+			attack:    Attack{Type: AttackTypeHybridWordlistMask, Wordlists: []string{"a"}, Mask: "?d"},
+			wordlists: [][]string{{"a", "b"}},
+			want:      []string{"a0", "a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9", "b0", "b1", "b2", "b3", "b4", "b5", "b6", "b7", "b8", "b9"},
+		},
+		{
+			name:      "Test hybrid mask wordlist",
+			attack:    Attack{Type: AttackTypeHybridMaskWordlist, Wordlists: []string{"a"}, Mask: "?h"},
+			wordlists: [][]string{{"x", "y"}},
+			want:      []string{"0x", "0y", "1x", "1y", "2x", "2y", "3x", "3y", "4x", "4y", "5x", "5y", "6x", "6y", "7x", "7y", "8x", "8y", "9x", "9y", "ax", "ay", "bx", "by", "cx", "cy", "dx", "dy", "ex", "ey", "fx", "fy"},
+		},
+		{
+			name:      "Test prince",
+			attack:    Attack{Type: AttackTypePrince, Wordlists: []string{"a"}, MinLength: 2, MaxLength: 2},
+			wordlists: [][]string{{"a", "b"}},
+			want:      []string{"aa", "ab", "ba", "bb"},
+		},
+		{
+			name:      "Test combinator with empty wordlist",
+			attack:    Attack{Type: AttackTypeCombinator, Wordlists: []string{"a", "b"}},
+			wordlists: [][]string{{"pass"}, {}},
+			want:      []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := tt.attack.Generator(Resources{Wordlists: tt.wordlists})
+			if err != nil {
+				t.Fatalf("Generator() error = %v", err)
+			}
+
+			if g.Keyspace() != uint64(len(tt.want)) {
+				t.Errorf("Keyspace() got = %v, want %v", g.Keyspace(), len(tt.want))
+			}
+
+			checkCandidates(t, drain(t, g), tt.want)
+		})
+	}
+}
+
+func TestGenerator_Seek(t *testing.T) {
+	g, err := NewCombinator([]string{"a", "b", "c"}, []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("NewCombinator() error = %v", err)
+	}
+
+	err = g.Seek(3)
+	if err != nil {
+		t.Errorf("Seek() error = %v", err)
+	}
+	checkCandidates(t, drain(t, g), []string{"b2", "c1", "c2"})
+
+	err = g.Seek(7)
+	if err == nil {
+		t.Errorf("Seek() past keyspace got = nil, want error")
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyspace  uint64
+		chunkSize uint64
+		want      []Chunk
+	}{
+		{
+			name:      "Test even split",
+			keyspace:  30,
+			chunkSize: 10,
+			want:      []Chunk{{0, 10}, {10, 20}, {20, 30}},
+		},
+		{
+			name:      "Test uneven split",
+			keyspace:  25,
+			chunkSize: 10,
+			want:      []Chunk{{0, 10}, {10, 20}, {20, 25}},
+		},
+		{
+			name:      "Test chunk larger than keyspace",
+			keyspace:  5,
+			chunkSize: 10,
+			want:      []Chunk{{0, 5}},
+		},
+		{
+			name:      "Test empty keyspace",
+			keyspace:  0,
+			chunkSize: 10,
+			want:      nil,
+		},
+		{
+			name:      "Test chunk size near max",
+			keyspace:  ^uint64(0),
+			chunkSize: ^uint64(0) - 1,
+			want:      []Chunk{{0, ^uint64(0) - 1}, {^uint64(0) - 1, ^uint64(0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Partition(tt.keyspace, tt.chunkSize)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Partition() got = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Partition() chunk %d got = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package attack
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+// dictionary streams its candidates straight from a wordlist file, so it
+// never holds the wordlist in memory.
+type dictionary struct {
+	r        *wordlist.Reader
+	keyspace uint64
+}
+
+// NewDictionary returns a generator that tries every line of r, which is
+// expected to hold lines lines.
+func NewDictionary(r *wordlist.Reader, lines uint64) Generator {
+	return &dictionary{r: r, keyspace: lines}
+}
+
+func (d *dictionary) Keyspace() uint64 {
+	return d.keyspace
+}
+
+func (d *dictionary) Position() uint64 {
+	return d.r.Line()
+}
+
+func (d *dictionary) Seek(pos uint64) error {
+	if pos > d.keyspace {
+		return fmt.Errorf("position %d is outside of keyspace %d", pos, d.keyspace)
+	}
+	return d.r.Seek(pos)
+}
+
+func (d *dictionary) Offset() uint64 {
+	return d.r.Offset()
+}
+
+func (d *dictionary) SeekOffset(pos, offset uint64) error {
+	if pos > d.keyspace {
+		return fmt.Errorf("position %d is outside of keyspace %d", pos, d.keyspace)
+	}
+	return d.r.SeekOffset(pos, offset)
+}
+
+func (d *dictionary) Next() (string, error) {
+	if d.r.Line() >= d.keyspace {
+		return "", io.EOF
+	}
+	return d.r.Next()
+}
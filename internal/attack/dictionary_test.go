@@ -0,0 +1,121 @@
+package attack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+func TestAttack_Generator_Dictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordlist")
+	err := os.WriteFile(path, []byte("alpha\nbravo\ncharlie\ndelta\n"), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	r, err := wordlist.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	a := Attack{Type: AttackTypeDictionary, Wordlists: []string{"a"}}
+	g, err := a.Generator(Resources{Streams: []*wordlist.Reader{r}, Lines: []uint64{4}})
+	if err != nil {
+		t.Fatalf("Generator() error = %v", err)
+	}
+
+	if g.Keyspace() != 4 {
+		t.Errorf("Keyspace() got = %v, want 4", g.Keyspace())
+	}
+
+	err = g.Seek(1)
+	if err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if g.Position() != 1 {
+		t.Errorf("Position() got = %v, want 1", g.Position())
+	}
+	checkCandidates(t, drain(t, g), []string{"bravo", "charlie", "delta"})
+
+	err = g.Seek(5)
+	if err == nil {
+		t.Errorf("Seek() past keyspace got = nil, want error")
+	}
+
+	_, err = a.Generator(Resources{Wordlists: [][]string{{"alpha"}}})
+	if err == nil {
+		t.Errorf("Generator() without stream got = nil, want error")
+	}
+}
+
+func TestDictionary_SeekOffset(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("alpha\r\nbravo\ncharlie\ndelta\n"))
+	zw.Close()
+
+	path := filepath.Join(t.TempDir(), "wordlist.gz")
+	err := os.WriteFile(path, buf.Bytes(), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	rules, err := ParseRules([]string{":", "u", "$1"})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{"dictionary", nil},
+		{"dictionary with rules", rules},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open := func() OffsetSeeker {
+				r, err := wordlist.Open(path)
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				t.Cleanup(func() { r.Close() })
+
+				var g Generator = NewDictionary(r, 4)
+				if tt.rules != nil {
+					g, err = NewDictionaryRules(r, 4, tt.rules)
+					if err != nil {
+						t.Fatalf("NewDictionaryRules() error = %v", err)
+					}
+				}
+				return g.(OffsetSeeker)
+			}
+
+			want := drain(t, open())
+			for pos := range want {
+				checkpointed := open()
+				for i := 0; i < pos; i++ {
+					_, err := checkpointed.Next()
+					if err != nil {
+						t.Fatalf("Next() error = %v", err)
+					}
+				}
+
+				resumed := open()
+				err := resumed.SeekOffset(checkpointed.Position(), checkpointed.Offset())
+				if err != nil {
+					t.Fatalf("SeekOffset() error = %v", err)
+				}
+				if resumed.Position() != uint64(pos) {
+					t.Errorf("Position() after SeekOffset() got = %v, want %v", resumed.Position(), pos)
+				}
+				checkCandidates(t, drain(t, resumed), want[pos:])
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package attack
+
+import (
+	"fmt"
+	"io"
+)
+
+// indexedGenerator is a Generator whose candidates can be computed directly
+// from their position in the keyspace.
+type indexedGenerator struct {
+	keyspace  uint64
+	pos       uint64
+	candidate func(i uint64) string
+}
+
+func (g *indexedGenerator) Keyspace() uint64 {
+	return g.keyspace
+}
+
+func (g *indexedGenerator) Position() uint64 {
+	return g.pos
+}
+
+func (g *indexedGenerator) Seek(pos uint64) error {
+	if pos > g.keyspace {
+		return fmt.Errorf("position %d is outside of keyspace %d", pos, g.keyspace)
+	}
+	g.pos = pos
+	return nil
+}
+
+func (g *indexedGenerator) Next() (string, error) {
+	if g.pos >= g.keyspace {
+		return "", io.EOF
+	}
+	c := g.candidate(g.pos)
+	g.pos++
+	return c, nil
+}
+
+// NewMask returns a generator that tries every candidate of mask.
+func NewMask(mask *Mask) (Generator, error) {
+	keyspace, err := mask.Keyspace()
+	if err != nil {
+		return nil, err
+	}
+
+	return &indexedGenerator{keyspace: keyspace, candidate: mask.Candidate}, nil
+}
+
+// NewCombinator returns a generator that appends every word of right to every
+// word of left.
+func NewCombinator(left, right []string) (Generator, error) {
+	keyspace, err := mulKeyspace(uint64(len(left)), uint64(len(right)))
+	if err != nil {
+		return nil, err
+	}
+
+	n := uint64(len(right))
+	return &indexedGenerator{
+		keyspace: keyspace,
+		candidate: func(i uint64) string {
+			return left[i/n] + right[i%n]
+		},
+	}, nil
+}
+
+// NewHybrid returns a generator that appends every candidate of mask to every
+// word, or prepends it when maskFirst is set.
+func NewHybrid(words []string, mask *Mask, maskFirst bool) (Generator, error) {
+	maskKeyspace, err := mask.Keyspace()
+	if err != nil {
+		return nil, err
+	}
+	keyspace, err := mulKeyspace(uint64(len(words)), maskKeyspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &indexedGenerator{
+		keyspace: keyspace,
+		candidate: func(i uint64) string {
+			if maskFirst {
+				return mask.Candidate(i/uint64(len(words))) + words[i%uint64(len(words))]
+			}
+			return words[i/maskKeyspace] + mask.Candidate(i%maskKeyspace)
+		},
+	}, nil
+}
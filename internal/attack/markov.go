@@ -0,0 +1,227 @@
+package attack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+const (
+	markovMagic   = "UHMK"
+	markovVersion = 1
+	// markovStart is the virtual previous character of the first position.
+	markovStart   = 256
+	markovPrevs   = 257
+	markovSymbols = 256
+	markovHeader  = 12
+)
+
+// MaxMarkovLength bounds the positions a model keeps statistics for, so a
+// model never takes more than 64*257*256 counts.
+const MaxMarkovLength = 64
+
+// MarkovModel holds per-position character transition counts, i.e. how often
+// a character followed another at a given position of the training plaintexts.
+// Positions past MaxLength share the statistics of the last position.
+type MarkovModel struct {
+	maxLength int
+	counts    []uint32
+}
+
+func NewMarkovModel(maxLength int) (*MarkovModel, error) {
+	if maxLength <= 0 || maxLength > MaxMarkovLength {
+		return nil, fmt.Errorf("max length must be between 1 and %d, got %d", MaxMarkovLength, maxLength)
+	}
+
+	return &MarkovModel{
+		maxLength: maxLength,
+		counts:    make([]uint32, maxLength*markovPrevs*markovSymbols),
+	}, nil
+}
+
+func TrainMarkovModel(plaintexts []string, maxLength int) (*MarkovModel, error) {
+	m, err := NewMarkovModel(maxLength)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plaintexts {
+		m.Add(p)
+	}
+
+	return m, nil
+}
+
+func (m *MarkovModel) MaxLength() int {
+	return m.maxLength
+}
+
+func (m *MarkovModel) Add(plaintext string) {
+	prev := markovStart
+	for pos := 0; pos < len(plaintext); pos++ {
+		i := m.index(pos, prev, int(plaintext[pos]))
+		if m.counts[i] < ^uint32(0) {
+			m.counts[i]++
+		}
+		prev = int(plaintext[pos])
+	}
+}
+
+func (m *MarkovModel) Count(pos, prev int, next byte) uint32 {
+	return m.counts[m.index(pos, prev, int(next))]
+}
+
+func (m *MarkovModel) index(pos, prev, next int) int {
+	if pos >= m.maxLength {
+		pos = m.maxLength - 1
+	}
+	return (pos*markovPrevs+prev)*markovSymbols + next
+}
+
+// successors orders charset by how likely each character is to follow prev at
+// pos, keeping at most threshold characters. Ties keep the charset order.
+func (m *MarkovModel) successors(pos, prev int, charset string, threshold int) string {
+	ordered := []byte(charset)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return m.Count(pos, prev, ordered[i]) > m.Count(pos, prev, ordered[j])
+	})
+
+	if threshold > 0 && threshold < len(ordered) {
+		ordered = ordered[:threshold]
+	}
+
+	return string(ordered)
+}
+
+func (m *MarkovModel) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, markovHeader)
+	header = append(header, markovMagic...)
+	header = binary.LittleEndian.AppendUint32(header, markovVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(m.maxLength))
+	n, err := bw.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	err = binary.Write(bw, binary.LittleEndian, m.counts)
+	if err != nil {
+		return int64(n), err
+	}
+
+	return int64(n) + int64(len(m.counts)*4), bw.Flush()
+}
+
+func ReadMarkovModel(r io.Reader) (*MarkovModel, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, markovHeader)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, fmt.Errorf("error reading markov model header: %w", err)
+	}
+	if string(header[:4]) != markovMagic {
+		return nil, errors.New("not a markov model")
+	}
+	if v := binary.LittleEndian.Uint32(header[4:8]); v != markovVersion {
+		return nil, fmt.Errorf("unsupported markov model version %d", v)
+	}
+
+	maxLength := binary.LittleEndian.Uint32(header[8:12])
+	if maxLength == 0 || maxLength > MaxMarkovLength {
+		return nil, fmt.Errorf("markov model max length must be between 1 and %d, got %d", MaxMarkovLength, maxLength)
+	}
+
+	// Files know their size, so a truncated model is rejected before its
+	// counts are allocated.
+	if f, ok := r.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("error reading markov model size: %w", err)
+		}
+		want := int64(markovHeader) + int64(maxLength)*markovPrevs*markovSymbols*4
+		if info.Size() != want {
+			return nil, fmt.Errorf("markov model is %d bytes, want %d for max length %d", info.Size(), want, maxLength)
+		}
+	}
+
+	m, err := NewMarkovModel(int(maxLength))
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Read(br, binary.LittleEndian, m.counts)
+	if err != nil {
+		return nil, fmt.Errorf("error reading markov model counts: %w", err)
+	}
+
+	return m, nil
+}
+
+// NewMarkov returns a generator over the positions of mask where, at every
+// position, the characters of its charset are tried most-likely-first given
+// the previous character. A positive threshold keeps only the most likely
+// characters per position, shrinking the keyspace.
+func NewMarkov(model *MarkovModel, mask *Mask, threshold int) (Generator, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold cannot be negative, got %d", threshold)
+	}
+
+	keyspace, err := markovKeyspace(mask, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// tables[pos][prev] is the ordered successor list for that position.
+	tables := make([][]string, mask.Len())
+	for pos, charset := range mask.positions {
+		tables[pos] = make([]string, markovPrevs)
+		for prev := range tables[pos] {
+			tables[pos][prev] = model.successors(pos, prev, charset, threshold)
+		}
+	}
+
+	return &indexedGenerator{
+		keyspace: keyspace,
+		candidate: func(i uint64) string {
+			digits := make([]uint64, len(tables))
+			for pos := len(tables) - 1; pos >= 0; pos-- {
+				n := uint64(len(tables[pos][0]))
+				digits[pos] = i % n
+				i /= n
+			}
+
+			buf := make([]byte, len(tables))
+			prev := markovStart
+			for pos := range tables {
+				buf[pos] = tables[pos][prev][digits[pos]]
+				prev = int(buf[pos])
+			}
+
+			return string(buf)
+		},
+	}, nil
+}
+
+func markovKeyspace(mask *Mask, threshold int) (uint64, error) {
+	keyspace := uint64(1)
+	for _, charset := range mask.positions {
+		n := len(charset)
+		if threshold > 0 && threshold < n {
+			n = threshold
+		}
+
+		var err error
+		keyspace, err = mulKeyspace(keyspace, uint64(n))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return keyspace, nil
+}
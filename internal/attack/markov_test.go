@@ -0,0 +1,158 @@
+package attack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkovModel_Count(t *testing.T) {
+	m, err := TrainMarkovModel([]string{"abc", "abd", "xbd"}, 2)
+	if err != nil {
+		t.Fatalf("TrainMarkovModel() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pos  int
+		prev int
+		next byte
+		want uint32
+	}{
+		{name: "Test first position", pos: 0, prev: markovStart, next: 'a', want: 2},
+		{name: "Test transition", pos: 1, prev: 'a', next: 'b', want: 2},
+		{name: "Test unseen transition", pos: 1, prev: 'a', next: 'c', want: 0},
+		{name: "Test position past max length", pos: 5, prev: 'b', next: 'd', want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Count(tt.pos, tt.prev, tt.next); got != tt.want {
+				t.Errorf("Count() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMarkovModel_InvalidLength(t *testing.T) {
+	_, err := NewMarkovModel(0)
+	if err == nil {
+		t.Errorf("NewMarkovModel() got = nil, want error")
+	}
+}
+
+func TestNewMarkov(t *testing.T) {
+	m, err := TrainMarkovModel([]string{"ba", "ba", "bb", "ab"}, 4)
+	if err != nil {
+		t.Fatalf("TrainMarkovModel() error = %v", err)
+	}
+	mask, err := ParseMask("?l?l")
+	if err != nil {
+		t.Fatalf("ParseMask() error = %v", err)
+	}
+
+	t.Run("Test most likely candidates first", func(t *testing.T) {
+		g, err := NewMarkov(m, mask, 2)
+		if err != nil {
+			t.Fatalf("NewMarkov() error = %v", err)
+		}
+		if g.Keyspace() != 4 {
+			t.Errorf("Keyspace() got = %v, want 4", g.Keyspace())
+		}
+
+		checkCandidates(t, drain(t, g), []string{"ba", "bb", "ab", "aa"})
+	})
+
+	t.Run("Test without threshold covers the full mask", func(t *testing.T) {
+		g, err := NewMarkov(m, mask, 0)
+		if err != nil {
+			t.Fatalf("NewMarkov() error = %v", err)
+		}
+		if g.Keyspace() != 26*26 {
+			t.Errorf("Keyspace() got = %v, want %v", g.Keyspace(), 26*26)
+		}
+
+		seen := make(map[string]bool)
+		for _, c := range drain(t, g) {
+			seen[c] = true
+		}
+		if len(seen) != 26*26 {
+			t.Errorf("got %d distinct candidates, want %d", len(seen), 26*26)
+		}
+	})
+
+	t.Run("Test negative threshold", func(t *testing.T) {
+		_, err := NewMarkov(m, mask, -1)
+		if err == nil {
+			t.Errorf("NewMarkov() got = nil, want error")
+		}
+	})
+}
+
+func TestMarkovModel_WriteTo(t *testing.T) {
+	m, err := TrainMarkovModel([]string{"password", "letmein"}, 8)
+	if err != nil {
+		t.Fatalf("TrainMarkovModel() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, err = m.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	got, err := ReadMarkovModel(&buf)
+	if err != nil {
+		t.Fatalf("ReadMarkovModel() error = %v", err)
+	}
+
+	if got.MaxLength() != m.MaxLength() {
+		t.Errorf("MaxLength() got = %v, want %v", got.MaxLength(), m.MaxLength())
+	}
+	for i := range m.counts {
+		if got.counts[i] != m.counts[i] {
+			t.Fatalf("counts[%d] got = %v, want %v", i, got.counts[i], m.counts[i])
+		}
+	}
+
+	_, err = ReadMarkovModel(bytes.NewReader([]byte("not a model")))
+	if err == nil {
+		t.Errorf("ReadMarkovModel() got = nil, want error")
+	}
+}
+
+func TestReadMarkovModel_Invalid(t *testing.T) {
+	header := func(maxLength uint32) []byte {
+		h := append([]byte(markovMagic), 1, 0, 0, 0)
+		return binary.LittleEndian.AppendUint32(h, maxLength)
+	}
+
+	t.Run("max length too large", func(t *testing.T) {
+		_, err := ReadMarkovModel(bytes.NewReader(header(math.MaxUint32)))
+		if err == nil {
+			t.Errorf("ReadMarkovModel() got = nil, want error")
+		}
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "model.uhmk")
+		err := os.WriteFile(path, header(MaxMarkovLength), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		_, err = ReadMarkovModel(f)
+		if err == nil || !strings.Contains(err.Error(), "bytes") {
+			t.Errorf("ReadMarkovModel() error = %v, want size mismatch", err)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package attack
+
+import "fmt"
+
+const (
+	charsetLower   = "abcdefghijklmnopqrstuvwxyz"
+	charsetUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	charsetDigits  = "0123456789"
+	charsetSpecial = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	charsetHex     = "0123456789abcdef"
+	charsetHexUp   = "0123456789ABCDEF"
+)
+
+var maskCharsets = map[byte]string{
+	'l': charsetLower,
+	'u': charsetUpper,
+	'd': charsetDigits,
+	's': charsetSpecial,
+	'a': charsetLower + charsetUpper + charsetDigits + charsetSpecial,
+	'h': charsetHex,
+	'H': charsetHexUp,
+	'?': "?",
+}
+
+// Mask is a hashcat-style mask such as `?u?l?l?l?d?d`, where every position is
+// either a built-in charset or a literal character.
+type Mask struct {
+	positions []string
+}
+
+func ParseMask(s string) (*Mask, error) {
+	if s == "" {
+		return nil, fmt.Errorf("mask cannot be empty")
+	}
+
+	m := &Mask{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '?' {
+			m.positions = append(m.positions, s[i:i+1])
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return nil, fmt.Errorf("mask `%s` ends with an incomplete charset", s)
+		}
+		charset, ok := maskCharsets[s[i+1]]
+		if !ok {
+			return nil, fmt.Errorf("mask `%s` has unknown charset `?%c`", s, s[i+1])
+		}
+		m.positions = append(m.positions, charset)
+		i++
+	}
+
+	return m, nil
+}
+
+func (m *Mask) Len() int {
+	return len(m.positions)
+}
+
+func (m *Mask) Keyspace() (uint64, error) {
+	keyspace := uint64(1)
+	for _, charset := range m.positions {
+		var err error
+		keyspace, err = mulKeyspace(keyspace, uint64(len(charset)))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return keyspace, nil
+}
+
+// Candidate returns the i-th candidate of the mask. The last position varies
+// fastest.
+func (m *Mask) Candidate(i uint64) string {
+	buf := make([]byte, len(m.positions))
+	for p := len(m.positions) - 1; p >= 0; p-- {
+		charset := m.positions[p]
+		n := uint64(len(charset))
+		buf[p] = charset[i%n]
+		i /= n
+	}
+	return string(buf)
+}
@@ -0,0 +1,111 @@
+package attack
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// princeChain is an ordered sequence of element lengths whose words are
+// concatenated into a candidate.
+type princeChain struct {
+	lengths     []int
+	keyspace    uint64
+	probability float64
+}
+
+// MaxPrinceLength bounds the candidate length of PRINCE attacks. Every
+// composition of every length is a chain, so there are about 2^maxLength of
+// them to build and keep in memory.
+const MaxPrinceLength = 16
+
+// NewPrince returns a PRINCE generator, which builds candidates between
+// minLength and maxLength long by chaining words of the wordlist. Chains are
+// ordered by the probability of their element lengths occurring in the
+// wordlist, so chains of common word lengths are tried first. Within a length,
+// words keep their wordlist order.
+func NewPrince(words []string, minLength, maxLength int) (Generator, error) {
+	if minLength <= 0 || maxLength < minLength || maxLength > MaxPrinceLength {
+		return nil, fmt.Errorf("invalid prince length range %d-%d, must be within 1-%d", minLength, maxLength, MaxPrinceLength)
+	}
+
+	elements := make(map[int][]string)
+	seen := make(map[string]bool)
+	total := 0
+	for _, w := range words {
+		if w == "" || len(w) > maxLength || seen[w] {
+			continue
+		}
+		seen[w] = true
+		elements[len(w)] = append(elements[len(w)], w)
+		total++
+	}
+
+	chains := make([]princeChain, 0)
+	var err error
+	var build func(remaining int, lengths []int)
+	build = func(remaining int, lengths []int) {
+		if err != nil {
+			return
+		}
+		if remaining == 0 {
+			c := princeChain{lengths: append([]int(nil), lengths...), keyspace: 1}
+			for _, l := range c.lengths {
+				c.keyspace, err = mulKeyspace(c.keyspace, uint64(len(elements[l])))
+				if err != nil {
+					return
+				}
+			}
+			// Computed from the keyspace so that permutations of the same
+			// lengths compare exactly equal and keep their build order.
+			c.probability = float64(c.keyspace) / math.Pow(float64(total), float64(len(c.lengths)))
+			chains = append(chains, c)
+			return
+		}
+		for l := 1; l <= remaining; l++ {
+			if len(elements[l]) > 0 {
+				build(remaining-l, append(lengths, l))
+			}
+		}
+	}
+	for length := minLength; length <= maxLength; length++ {
+		build(length, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].probability > chains[j].probability
+	})
+
+	// offsets[i] is the position of the first candidate of chains[i].
+	offsets := make([]uint64, len(chains))
+	keyspace := uint64(0)
+	for i, c := range chains {
+		offsets[i] = keyspace
+		keyspace += c.keyspace
+		if keyspace < offsets[i] {
+			return nil, ErrKeyspaceOverflow
+		}
+	}
+
+	return &indexedGenerator{
+		keyspace: keyspace,
+		candidate: func(i uint64) string {
+			c := sort.Search(len(offsets), func(n int) bool { return offsets[n] > i }) - 1
+			i -= offsets[c]
+
+			chain := chains[c].lengths
+			parts := make([]string, len(chain))
+			for e := len(chain) - 1; e >= 0; e-- {
+				n := uint64(len(elements[chain[e]]))
+				parts[e] = elements[chain[e]][i%n]
+				i /= n
+			}
+
+			return strings.Join(parts, "")
+		},
+	}, nil
+}
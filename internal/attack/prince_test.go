@@ -0,0 +1,117 @@
+package attack
+
+import (
+	"testing"
+)
+
+func TestNewPrince(t *testing.T) {
+	tests := []struct {
+		name      string
+		words     []string
+		minLength int
+		maxLength int
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "Test chains ordered by probability",
+			words:     []string{"a", "b", "cd"},
+			minLength: 2,
+			maxLength: 3,
+			want: []string{
+				"aa", "ab", "ba", "bb",
+				"cd",
+				"aaa", "aab", "aba", "abb", "baa", "bab", "bba", "bbb",
+				"acd", "bcd",
+				"cda", "cdb",
+			},
+		},
+		{
+			name:      "Test duplicate and oversized words are skipped",
+			words:     []string{"x", "x", "", "toolong"},
+			minLength: 1,
+			maxLength: 2,
+			want:      []string{"x", "xx"},
+		},
+		{
+			name:      "Test wordlist order is kept within a length",
+			words:     []string{"z", "a"},
+			minLength: 1,
+			maxLength: 1,
+			want:      []string{"z", "a"},
+		},
+		{
+			name:      "Test no words",
+			words:     []string{},
+			minLength: 1,
+			maxLength: 4,
+			want:      []string{},
+		},
+		{
+			name:      "Test invalid length range",
+			words:     []string{"a"},
+			minLength: 3,
+			maxLength: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "Test max length above the limit",
+			words:     []string{"a"},
+			minLength: 1,
+			maxLength: MaxPrinceLength + 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewPrince(tt.words, tt.minLength, tt.maxLength)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewPrince() got = %v, want error", g)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewPrince() error = %v", err)
+			}
+
+			if g.Keyspace() != uint64(len(tt.want)) {
+				t.Errorf("Keyspace() got = %v, want %v", g.Keyspace(), len(tt.want))
+			}
+			checkCandidates(t, drain(t, g), tt.want)
+		})
+	}
+}
+
+func TestNewPrince_Resume(t *testing.T) {
+	words := []string{"pass", "word", "1", "2", "!"}
+
+	g, err := NewPrince(words, 1, 9)
+	if err != nil {
+		t.Fatalf("NewPrince() error = %v", err)
+	}
+	all := drain(t, g)
+
+	resumed, err := NewPrince(words, 1, 9)
+	if err != nil {
+		t.Fatalf("NewPrince() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err = resumed.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+	}
+
+	checkpoint := resumed.Position()
+	if checkpoint != 10 {
+		t.Errorf("Position() got = %v, want 10", checkpoint)
+	}
+
+	err = resumed.Seek(checkpoint)
+	if err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	checkCandidates(t, drain(t, resumed), all[checkpoint:])
+}
@@ -0,0 +1,369 @@
+package attack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+// ruleArgs is how many argument bytes each supported hashcat rule function
+// takes.
+var ruleArgs = map[byte]int{
+	':': 0, 'l': 0, 'u': 0, 'c': 0, 'C': 0, 't': 0, 'r': 0, 'd': 0, 'f': 0,
+	'{': 0, '}': 0, '[': 0, ']': 0, 'q': 0,
+	'T': 1, 'p': 1, 'D': 1, '\'': 1, 'z': 1, 'Z': 1, '$': 1, '^': 1, '@': 1,
+	'x': 2, 'O': 2, 'i': 2, 'o': 2, 's': 2,
+}
+
+type ruleFunc struct {
+	op   byte
+	args [2]byte
+}
+
+// Rule is a parsed hashcat rule, a sequence of functions applied to a word in
+// order. Functions whose position argument is outside the word leave it
+// unchanged.
+type Rule []ruleFunc
+
+func ParseRule(s string) (Rule, error) {
+	rule := make(Rule, 0)
+	for i := 0; i < len(s); i++ {
+		if s[i] == ' ' {
+			continue
+		}
+
+		n, ok := ruleArgs[s[i]]
+		if !ok {
+			return nil, fmt.Errorf("rule `%s` has unknown function `%c`", s, s[i])
+		}
+		if i+n >= len(s) {
+			return nil, fmt.Errorf("rule `%s` has incomplete function `%c`", s, s[i])
+		}
+
+		f := ruleFunc{op: s[i]}
+		copy(f.args[:], s[i+1:i+1+n])
+		for a := 0; a < n; a++ {
+			if rulePositionArg(f.op, a) && rulePosition(f.args[a]) < 0 {
+				return nil, fmt.Errorf("rule `%s` has invalid position `%c`", s, f.args[a])
+			}
+		}
+		rule = append(rule, f)
+		i += n
+	}
+
+	return rule, nil
+}
+
+// ParseRules parses a rule file, skipping blank lines and `#` comments.
+func ParseRules(lines []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (r Rule) Apply(word string) string {
+	w := []byte(word)
+	for _, f := range r {
+		w = f.apply(w)
+	}
+
+	return string(w)
+}
+
+func (f ruleFunc) apply(w []byte) []byte {
+	n := rulePosition(f.args[0])
+	m := rulePosition(f.args[1])
+
+	switch f.op {
+	case 'l':
+		return bytes.ToLower(w)
+	case 'u':
+		return bytes.ToUpper(w)
+	case 'c':
+		w = bytes.ToLower(w)
+		if len(w) > 0 {
+			w[0] = toUpper(w[0])
+		}
+	case 'C':
+		w = bytes.ToUpper(w)
+		if len(w) > 0 {
+			w[0] = toLower(w[0])
+		}
+	case 't':
+		for i := range w {
+			w[i] = toggle(w[i])
+		}
+	case 'T':
+		if n < len(w) {
+			w[n] = toggle(w[n])
+		}
+	case 'r':
+		for i, j := 0, len(w)-1; i < j; i, j = i+1, j-1 {
+			w[i], w[j] = w[j], w[i]
+		}
+	case 'd':
+		return append(w, w...)
+	case 'p':
+		out := make([]byte, 0, len(w)*(n+1))
+		for i := 0; i <= n; i++ {
+			out = append(out, w...)
+		}
+		return out
+	case 'f':
+		out := append([]byte{}, w...)
+		for i := len(w) - 1; i >= 0; i-- {
+			out = append(out, w[i])
+		}
+		return out
+	case '{':
+		if len(w) > 0 {
+			return append(w[1:], w[0])
+		}
+	case '}':
+		if len(w) > 0 {
+			return append([]byte{w[len(w)-1]}, w[:len(w)-1]...)
+		}
+	case '$':
+		return append(w, f.args[0])
+	case '^':
+		return append([]byte{f.args[0]}, w...)
+	case '[':
+		if len(w) > 0 {
+			return w[1:]
+		}
+	case ']':
+		if len(w) > 0 {
+			return w[:len(w)-1]
+		}
+	case 'D':
+		if n < len(w) {
+			return append(w[:n], w[n+1:]...)
+		}
+	case 'x':
+		if n < len(w) && n+m <= len(w) {
+			return w[n : n+m]
+		}
+	case 'O':
+		if n < len(w) && n+m <= len(w) {
+			return append(w[:n], w[n+m:]...)
+		}
+	case 'i':
+		if n <= len(w) {
+			out := append([]byte{}, w[:n]...)
+			out = append(out, f.args[1])
+			return append(out, w[n:]...)
+		}
+	case 'o':
+		if n < len(w) {
+			w[n] = f.args[1]
+		}
+	case '\'':
+		if n < len(w) {
+			return w[:n]
+		}
+	case 's':
+		for i := range w {
+			if w[i] == f.args[0] {
+				w[i] = f.args[1]
+			}
+		}
+	case '@':
+		out := w[:0]
+		for _, c := range w {
+			if c != f.args[0] {
+				out = append(out, c)
+			}
+		}
+		return out
+	case 'z':
+		if len(w) > 0 {
+			return append(bytes.Repeat(w[:1], n), w...)
+		}
+	case 'Z':
+		if len(w) > 0 {
+			return append(w, bytes.Repeat(w[len(w)-1:], n)...)
+		}
+	case 'q':
+		out := make([]byte, 0, 2*len(w))
+		for _, c := range w {
+			out = append(out, c, c)
+		}
+		return out
+	}
+
+	return w
+}
+
+// rulePositionArg reports whether argument a of op is a position or count,
+// as opposed to a character.
+func rulePositionArg(op byte, a int) bool {
+	switch op {
+	case 'T', 'p', 'D', '\'', 'z', 'Z':
+		return true
+	case 'x', 'O':
+		return true
+	case 'i', 'o':
+		return a == 0
+	default:
+		return false
+	}
+}
+
+// rulePosition decodes hashcat's position notation, 0-9 then A-Z for 10-35.
+func rulePosition(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	default:
+		return -1
+	}
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+func toggle(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return toUpper(c)
+	}
+	return toLower(c)
+}
+
+// rulesDictionary streams a wordlist like dictionary, trying every rule on
+// each word before moving to the next one.
+type rulesDictionary struct {
+	r        *wordlist.Reader
+	rules    []Rule
+	keyspace uint64
+	pos      uint64
+	word     string
+	// wordOffset is where the line of word starts.
+	wordOffset uint64
+}
+
+// NewDictionaryRules returns a generator that applies every rule to every
+// line of r, which is expected to hold lines lines.
+func NewDictionaryRules(r *wordlist.Reader, lines uint64, rules []Rule) (Generator, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rules cannot be empty")
+	}
+
+	keyspace, err := mulKeyspace(lines, uint64(len(rules)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rulesDictionary{r: r, rules: rules, keyspace: keyspace}, nil
+}
+
+func (d *rulesDictionary) Keyspace() uint64 {
+	return d.keyspace
+}
+
+func (d *rulesDictionary) Position() uint64 {
+	return d.pos
+}
+
+func (d *rulesDictionary) Seek(pos uint64) error {
+	if pos > d.keyspace {
+		return fmt.Errorf("position %d is outside of keyspace %d", pos, d.keyspace)
+	}
+
+	err := d.r.Seek(pos / uint64(len(d.rules)))
+	if err != nil {
+		return err
+	}
+
+	return d.resume(pos)
+}
+
+func (d *rulesDictionary) Offset() uint64 {
+	if d.pos%uint64(len(d.rules)) != 0 {
+		return d.wordOffset
+	}
+	return d.r.Offset()
+}
+
+func (d *rulesDictionary) SeekOffset(pos, offset uint64) error {
+	if pos > d.keyspace {
+		return fmt.Errorf("position %d is outside of keyspace %d", pos, d.keyspace)
+	}
+
+	err := d.r.SeekOffset(pos/uint64(len(d.rules)), offset)
+	if err != nil {
+		return err
+	}
+
+	return d.resume(pos)
+}
+
+// resume moves to pos once the reader is before the line of its word.
+func (d *rulesDictionary) resume(pos uint64) error {
+	if pos%uint64(len(d.rules)) != 0 {
+		err := d.readWord()
+		if err != nil {
+			return err
+		}
+	}
+	d.pos = pos
+
+	return nil
+}
+
+func (d *rulesDictionary) readWord() error {
+	offset := d.r.Offset()
+	word, err := d.r.Next()
+	if err != nil {
+		return err
+	}
+	d.word = word
+	d.wordOffset = offset
+
+	return nil
+}
+
+func (d *rulesDictionary) Next() (string, error) {
+	if d.pos >= d.keyspace {
+		return "", io.EOF
+	}
+
+	n := uint64(len(d.rules))
+	if d.pos%n == 0 {
+		err := d.readWord()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	c := d.rules[d.pos%n].Apply(d.word)
+	d.pos++
+
+	return c, nil
+}
@@ -0,0 +1,139 @@
+package attack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+func TestRule_Apply(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		word string
+		want string
+	}{
+		{name: "Test noop", rule: ":", word: "Pass", want: "Pass"},
+		{name: "Test lower", rule: "l", word: "PaSS", want: "pass"},
+		{name: "Test upper", rule: "u", word: "PaSS", want: "PASS"},
+		{name: "Test capitalize", rule: "c", word: "pASS", want: "Pass"},
+		{name: "Test invert capitalize", rule: "C", word: "pass", want: "pASS"},
+		{name: "Test toggle case", rule: "t", word: "PaSs1", want: "pAsS1"},
+		{name: "Test toggle at", rule: "T1", word: "pass", want: "pAss"},
+		{name: "Test reverse", rule: "r", word: "pass", want: "ssap"},
+		{name: "Test duplicate", rule: "d", word: "ab", want: "abab"},
+		{name: "Test duplicate n", rule: "p2", word: "ab", want: "ababab"},
+		{name: "Test reflect", rule: "f", word: "ab", want: "abba"},
+		{name: "Test rotate left", rule: "{", word: "abc", want: "bca"},
+		{name: "Test rotate right", rule: "}", word: "abc", want: "cab"},
+		{name: "Test append", rule: "$1 $2", word: "pass", want: "pass12"},
+		{name: "Test prepend", rule: "^1", word: "pass", want: "1pass"},
+		{name: "Test truncate left and right", rule: "[]", word: "pass", want: "as"},
+		{name: "Test delete at", rule: "D1", word: "pass", want: "pss"},
+		{name: "Test extract", rule: "x12", word: "password", want: "as"},
+		{name: "Test omit", rule: "O12", word: "password", want: "psword"},
+		{name: "Test insert", rule: "i2!", word: "pass", want: "pa!ss"},
+		{name: "Test overwrite", rule: "o0P", word: "pass", want: "Pass"},
+		{name: "Test truncate at", rule: "'3", word: "password", want: "pas"},
+		{name: "Test replace", rule: "sa@ss$", word: "pass", want: "p@$$"},
+		{name: "Test purge", rule: "@s", word: "pass", want: "pa"},
+		{name: "Test duplicate first", rule: "z2", word: "ab", want: "aaab"},
+		{name: "Test duplicate last", rule: "Z2", word: "ab", want: "abbb"},
+		{name: "Test duplicate all", rule: "q", word: "ab", want: "aabb"},
+		{name: "Test position out of range", rule: "D9", word: "pass", want: "pass"},
+		{name: "Test chained", rule: "c $2 $0 $2 $4", word: "summer", want: "Summer2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule() error = %v", err)
+			}
+			if got := r.Apply(tt.word); got != tt.want {
+				t.Errorf("Apply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]string{"# comment", "", ":", "c", "$1"})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(rules) != 3 {
+		t.Errorf("ParseRules() got %d rules, want 3", len(rules))
+	}
+
+	for _, bad := range []string{"$", "K", "Ta", "i!a"} {
+		if _, err := ParseRules([]string{bad}); err == nil {
+			t.Errorf("ParseRules(%q) got = nil, want error", bad)
+		}
+	}
+}
+
+func TestAttack_Generator_DictionaryRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wordlist")
+	err := os.WriteFile(path, []byte("alpha\nbravo\ncharlie\n"), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	r, err := wordlist.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	rules, err := ParseRules([]string{":", "u", "$1"})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+
+	a := Attack{Type: AttackTypeDictionary, Wordlists: []string{"a"}, Rules: "r"}
+	g, err := a.Generator(Resources{Streams: []*wordlist.Reader{r}, Lines: []uint64{3}, Rules: rules})
+	if err != nil {
+		t.Fatalf("Generator() error = %v", err)
+	}
+
+	if g.Keyspace() != 9 {
+		t.Errorf("Keyspace() got = %v, want 9", g.Keyspace())
+	}
+
+	err = g.Seek(4)
+	if err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	checkCandidates(t, drain(t, g), []string{"BRAVO", "bravo1", "charlie", "CHARLIE", "charlie1"})
+
+	err = g.Seek(3)
+	if err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	checkCandidates(t, drain(t, g), []string{"bravo", "BRAVO", "bravo1", "charlie", "CHARLIE", "charlie1"})
+}
+
+func TestAttack_Generator_Mask(t *testing.T) {
+	a := Attack{Type: AttackTypeMask, Mask: "?d?d"}
+	g, err := a.Generator(Resources{})
+	if err != nil {
+		t.Fatalf("Generator() error = %v", err)
+	}
+
+	keyspace, err := a.Keyspace(nil)
+	if err != nil {
+		t.Fatalf("Keyspace() error = %v", err)
+	}
+	if g.Keyspace() != 100 || keyspace != 100 {
+		t.Errorf("Keyspace() got = %v and %v, want 100", g.Keyspace(), keyspace)
+	}
+
+	err = g.Seek(98)
+	if err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	checkCandidates(t, drain(t, g), []string{"98", "99"})
+}
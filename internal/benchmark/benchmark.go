@@ -0,0 +1,171 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/crack"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+const (
+	DefaultDuration = time.Second
+
+	// benchmarkMask has a keyspace far larger than anything hashed within a
+	// benchmark, so the generator never runs dry.
+	benchmarkMask = "?a?a?a?a?a?a?a?a"
+)
+
+type Result struct {
+	HashType            hashtype.HashType `json:"hashType"`
+	CandidatesPerSecond float64           `json:"candidatesPerSecond"`
+	MeasuredAt          time.Time         `json:"measuredAt"`
+}
+
+type BenchmarkStore interface {
+	SetBenchmark(r Result) error
+	GetBenchmark(t hashtype.HashType) (*Result, error)
+	ListBenchmarks() ([]Result, error)
+}
+
+type BenchmarkService struct {
+	store BenchmarkStore
+}
+
+func NewBenchmarkService(s BenchmarkStore) *BenchmarkService {
+	return &BenchmarkService{store: s}
+}
+
+func Unmarshal(data []byte) (*Result, error) {
+	var r Result
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling benchmark: %w", err)
+	}
+
+	return &r, nil
+}
+
+// Measure runs the cracking loop for t against a target that is never found
+// for roughly d and reports how many candidates it tried per second.
+func Measure(t hashtype.HashType, d time.Duration) (Result, error) {
+	mask, err := attack.ParseMask(benchmarkMask)
+	if err != nil {
+		return Result{}, err
+	}
+	gen, err := attack.NewHybrid([]string{""}, mask, false)
+	if err != nil {
+		return Result{}, err
+	}
+
+	unreachable := make([]byte, 2*t.Size())
+	for i := range unreachable {
+		unreachable[i] = 'f'
+	}
+	targets, err := crack.NewTargets(t, []string{string(unreachable)})
+	if err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	stats, err := crack.Run(ctx, gen, targets, 0, func(hash, plain string) {})
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return Result{}, err
+	}
+	if stats.Elapsed <= 0 {
+		return Result{}, errors.New("benchmark finished without measurable time")
+	}
+
+	return Result{
+		HashType:            t,
+		CandidatesPerSecond: float64(stats.Candidates) / stats.Elapsed.Seconds(),
+		MeasuredAt:          time.Now().UTC(),
+	}, nil
+}
+
+// RunAll measures every registered hash type for d each and stores the
+// results.
+func (b *BenchmarkService) RunAll(d time.Duration) ([]Result, error) {
+	results := make([]Result, 0)
+	for _, t := range hashtype.Registered() {
+		r, err := Measure(t, d)
+		if err != nil {
+			return nil, fmt.Errorf("error benchmarking %v: %w", t, err)
+		}
+
+		err = b.store.SetBenchmark(r)
+		if err != nil {
+			return nil, fmt.Errorf("error storing %v benchmark: %w", t, err)
+		}
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+func (b *BenchmarkService) ListResults() ([]Result, error) {
+	results, err := b.store.ListBenchmarks()
+	if err != nil {
+		return nil, fmt.Errorf("error listing benchmarks: %w", err)
+	}
+
+	return results, nil
+}
+
+func (b *BenchmarkService) rate(t hashtype.HashType) (float64, error) {
+	r, err := b.store.GetBenchmark(t)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return 0, fmt.Errorf("no benchmark for %v: %w", t, err)
+		}
+		return 0, err
+	}
+	if r.CandidatesPerSecond <= 0 {
+		return 0, fmt.Errorf("no benchmark for %v: %w", t, &uerr.ErrorNotFound{Err: errors.New("benchmark rate is zero")})
+	}
+
+	return r.CandidatesPerSecond, nil
+}
+
+// ETA estimates how long trying keyspace candidates of t takes on this
+// machine.
+func (b *BenchmarkService) ETA(t hashtype.HashType, keyspace uint64) (time.Duration, error) {
+	rate, err := b.rate(t)
+	if err != nil {
+		return 0, err
+	}
+
+	seconds := float64(keyspace) / rate
+	if seconds >= math.MaxInt64/float64(time.Second) {
+		return time.Duration(math.MaxInt64), nil
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ChunkSize returns how many candidates of t take about d to try, so work
+// can be split into chunks of similar duration.
+func (b *BenchmarkService) ChunkSize(t hashtype.HashType, d time.Duration) (uint64, error) {
+	rate, err := b.rate(t)
+	if err != nil {
+		return 0, err
+	}
+
+	size := rate * d.Seconds()
+	if size < 1 {
+		return 1, nil
+	}
+	if size >= math.MaxUint64 {
+		return math.MaxUint64, nil
+	}
+
+	return uint64(size), nil
+}
@@ -0,0 +1,149 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockBenchmarkStore struct {
+	results map[hashtype.HashType]Result
+}
+
+func (m *MockBenchmarkStore) SetBenchmark(r Result) error {
+	if m.results == nil {
+		m.results = make(map[hashtype.HashType]Result)
+	}
+	m.results[r.HashType] = r
+	return nil
+}
+
+func (m *MockBenchmarkStore) GetBenchmark(t hashtype.HashType) (*Result, error) {
+	r, ok := m.results[t]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("benchmark not found")}
+	}
+	return &r, nil
+}
+
+func (m *MockBenchmarkStore) ListBenchmarks() ([]Result, error) {
+	results := make([]Result, 0, len(m.results))
+	for _, r := range m.results {
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func TestMeasure(t *testing.T) {
+	for _, ht := range hashtype.Registered() {
+		t.Run("Test Measure "+string(ht), func(t *testing.T) {
+			got, err := Measure(ht, 20*time.Millisecond)
+			if err != nil {
+				t.Fatalf("Measure() error = %v", err)
+			}
+			if got.HashType != ht {
+				t.Errorf("Measure() got = %v, want %v", got.HashType, ht)
+			}
+			if got.CandidatesPerSecond <= 0 {
+				t.Errorf("Measure() got = %v candidates/s, want > 0", got.CandidatesPerSecond)
+			}
+		})
+	}
+}
+
+func TestBenchmarkService_RunAll(t *testing.T) {
+	store := &MockBenchmarkStore{}
+	b := NewBenchmarkService(store)
+
+	results, err := b.RunAll(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("RunAll() error = %v", err)
+	}
+	if len(results) != len(hashtype.Registered()) {
+		t.Errorf("RunAll() got %v results, want %v", len(results), len(hashtype.Registered()))
+	}
+	if len(store.results) != len(hashtype.Registered()) {
+		t.Errorf("RunAll() stored %v results, want %v", len(store.results), len(hashtype.Registered()))
+	}
+}
+
+func TestBenchmarkService_ETA(t *testing.T) {
+	store := &MockBenchmarkStore{}
+	store.SetBenchmark(Result{HashType: hashtype.HashTypeMD5, CandidatesPerSecond: 1000})
+	b := NewBenchmarkService(store)
+
+	tests := []struct {
+		name     string
+		hashType hashtype.HashType
+		keyspace uint64
+		want     time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "Test ETA",
+			hashType: hashtype.HashTypeMD5,
+			keyspace: 5000,
+			want:     5 * time.Second,
+		},
+		{
+			name:     "Test ETA saturates",
+			hashType: hashtype.HashTypeMD5,
+			keyspace: ^uint64(0),
+			want:     time.Duration(1<<63 - 1),
+		},
+		{
+			name:     "Test ETA without benchmark",
+			hashType: hashtype.HashTypeSHA1,
+			keyspace: 5000,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ETA(tt.hashType, tt.keyspace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ETA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, &uerr.ErrorNotFound{}) {
+					t.Errorf("ETA() error = %v, want not found", err)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ETA() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBenchmarkService_ChunkSize(t *testing.T) {
+	store := &MockBenchmarkStore{}
+	store.SetBenchmark(Result{HashType: hashtype.HashTypeMD5, CandidatesPerSecond: 1000})
+	b := NewBenchmarkService(store)
+
+	tests := []struct {
+		name string
+		d    time.Duration
+		want uint64
+	}{
+		{name: "Test ChunkSize", d: 30 * time.Second, want: 30000},
+		{name: "Test ChunkSize minimum", d: time.Microsecond, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ChunkSize(hashtype.HashTypeMD5, tt.d)
+			if err != nil {
+				t.Fatalf("ChunkSize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ChunkSize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
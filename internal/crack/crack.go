@@ -0,0 +1,100 @@
+package crack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+// checkInterval is how many candidates are tried between checks for
+// cancellation, keeping the check out of the hot loop.
+const checkInterval = 4096
+
+// ErrCandidateLimit is returned by Run when it stops because it tried as many
+// candidates as it was allowed to, before the generator ran out.
+var ErrCandidateLimit = errors.New("candidate limit reached")
+
+// Targets are the digests still to be cracked, keyed by raw digest and
+// mapping back to the hash as it was submitted.
+type Targets struct {
+	hashType hashtype.HashType
+	digests  map[string]string
+}
+
+type Stats struct {
+	Candidates uint64        `json:"candidates"`
+	Cracked    int           `json:"cracked"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+func NewTargets(t hashtype.HashType, hashes []string) (*Targets, error) {
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := &Targets{hashType: t, digests: make(map[string]string, len(hashes))}
+	for _, h := range hashes {
+		digest, err := t.Decode(h)
+		if err != nil {
+			return nil, err
+		}
+		targets.digests[string(digest)] = h
+	}
+
+	return targets, nil
+}
+
+func (t *Targets) Len() int {
+	return len(t.digests)
+}
+
+// Run hashes candidates from gen until it is exhausted, every target is
+// cracked, ctx is done or limit candidates were tried, calling found for each
+// cracked target. A limit of 0 means no limit. Cracked targets are removed
+// from targets.
+func Run(ctx context.Context, gen attack.Generator, targets *Targets, limit uint64, found func(hash, plain string)) (stats Stats, err error) {
+	start := time.Now()
+	defer func() { stats.Elapsed = time.Since(start) }()
+
+	for targets.Len() > 0 {
+		if limit > 0 && stats.Candidates >= limit {
+			if gen.Position() < gen.Keyspace() {
+				return stats, ErrCandidateLimit
+			}
+			break
+		}
+		if stats.Candidates%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+
+		candidate, err := gen.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("error generating candidate: %w", err)
+		}
+		stats.Candidates++
+
+		digest, err := targets.hashType.Hash(candidate)
+		if err != nil {
+			return stats, err
+		}
+
+		if hash, ok := targets.digests[string(digest)]; ok {
+			delete(targets.digests, string(digest))
+			stats.Cracked++
+			found(hash, candidate)
+		}
+	}
+
+	return stats, nil
+}
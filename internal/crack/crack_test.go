@@ -0,0 +1,173 @@
+package crack
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+func md5Hex(plain string) string {
+	digest, _ := hashtype.HashTypeMD5.Hash(plain)
+	return hex.EncodeToString(digest)
+}
+
+func TestNewTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType hashtype.HashType
+		hashes   []string
+		wantLen  int
+		wantErr  bool
+	}{
+		{name: "Test NewTargets", hashType: hashtype.HashTypeMD5, hashes: []string{md5Hex("a"), md5Hex("b")}, wantLen: 2},
+		{name: "Test NewTargets uppercase", hashType: hashtype.HashTypeMD5, hashes: []string{"5F4DCC3B5AA765D61D8327DEB882CF99"}, wantLen: 1},
+		{name: "Test NewTargets wrong length", hashType: hashtype.HashTypeSHA1, hashes: []string{md5Hex("a")}, wantErr: true},
+		{name: "Test NewTargets not hex", hashType: hashtype.HashTypeMD5, hashes: []string{"zz"}, wantErr: true},
+		{name: "Test NewTargets unknown hash type", hashType: "bogus", hashes: []string{md5Hex("a")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTargets(tt.hashType, tt.hashes)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewTargets() got = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTargets() error = %v", err)
+			}
+			if got.Len() != tt.wantLen {
+				t.Errorf("Len() got = %v, want %v", got.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	mask, err := attack.ParseMask("?d?d?d")
+	if err != nil {
+		t.Fatalf("ParseMask() error = %v", err)
+	}
+
+	t.Run("Test Run cracks targets", func(t *testing.T) {
+		gen, err := attack.NewHybrid([]string{"pin", "code"}, mask, false)
+		if err != nil {
+			t.Fatalf("NewHybrid() error = %v", err)
+		}
+		targets, err := NewTargets(hashtype.HashTypeMD5, []string{md5Hex("pin042"), md5Hex("code999"), md5Hex("missing")})
+		if err != nil {
+			t.Fatalf("NewTargets() error = %v", err)
+		}
+
+		cracked := make(map[string]string)
+		stats, err := Run(context.Background(), gen, targets, 0, func(hash, plain string) {
+			cracked[hash] = plain
+		})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if stats.Candidates != gen.Keyspace() {
+			t.Errorf("Run() candidates = %v, want %v", stats.Candidates, gen.Keyspace())
+		}
+		if stats.Cracked != 2 || len(cracked) != 2 {
+			t.Errorf("Run() cracked = %v, want 2", cracked)
+		}
+		if cracked[md5Hex("pin042")] != "pin042" || cracked[md5Hex("code999")] != "code999" {
+			t.Errorf("Run() cracked = %v", cracked)
+		}
+		if targets.Len() != 1 {
+			t.Errorf("Len() after Run() got = %v, want 1", targets.Len())
+		}
+	})
+
+	t.Run("Test Run stops once every target is cracked", func(t *testing.T) {
+		gen, err := attack.NewHybrid([]string{"pin"}, mask, false)
+		if err != nil {
+			t.Fatalf("NewHybrid() error = %v", err)
+		}
+		targets, err := NewTargets(hashtype.HashTypeMD5, []string{md5Hex("pin010")})
+		if err != nil {
+			t.Fatalf("NewTargets() error = %v", err)
+		}
+
+		stats, err := Run(context.Background(), gen, targets, 0, func(hash, plain string) {})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if stats.Candidates != 11 {
+			t.Errorf("Run() candidates = %v, want 11", stats.Candidates)
+		}
+		if gen.Position() != 11 {
+			t.Errorf("Position() got = %v, want 11", gen.Position())
+		}
+	})
+
+	t.Run("Test Run stops when the context is done", func(t *testing.T) {
+		gen, err := attack.NewHybrid([]string{"pin"}, mask, false)
+		if err != nil {
+			t.Fatalf("NewHybrid() error = %v", err)
+		}
+		targets, err := NewTargets(hashtype.HashTypeMD5, []string{md5Hex("missing")})
+		if err != nil {
+			t.Fatalf("NewTargets() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		stats, err := Run(ctx, gen, targets, 0, func(hash, plain string) {})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+		}
+		if stats.Candidates != 0 {
+			t.Errorf("Run() candidates = %v, want 0", stats.Candidates)
+		}
+	})
+	t.Run("Test Run stops at the candidate limit", func(t *testing.T) {
+		gen, err := attack.NewHybrid([]string{"pin"}, mask, false)
+		if err != nil {
+			t.Fatalf("NewHybrid() error = %v", err)
+		}
+		targets, err := NewTargets(hashtype.HashTypeMD5, []string{md5Hex("pin005"), md5Hex("pin500")})
+		if err != nil {
+			t.Fatalf("NewTargets() error = %v", err)
+		}
+
+		stats, err := Run(context.Background(), gen, targets, 100, func(hash, plain string) {})
+		if !errors.Is(err, ErrCandidateLimit) {
+			t.Errorf("Run() error = %v, want %v", err, ErrCandidateLimit)
+		}
+		if stats.Candidates != 100 || gen.Position() != 100 {
+			t.Errorf("Run() candidates = %v, position = %v, want 100", stats.Candidates, gen.Position())
+		}
+		if stats.Cracked != 1 {
+			t.Errorf("Run() cracked = %v, want 1", stats.Cracked)
+		}
+	})
+
+	t.Run("Test Run limit covering the whole keyspace", func(t *testing.T) {
+		gen, err := attack.NewHybrid([]string{"pin"}, mask, false)
+		if err != nil {
+			t.Fatalf("NewHybrid() error = %v", err)
+		}
+		targets, err := NewTargets(hashtype.HashTypeMD5, []string{md5Hex("missing")})
+		if err != nil {
+			t.Fatalf("NewTargets() error = %v", err)
+		}
+
+		stats, err := Run(context.Background(), gen, targets, gen.Keyspace(), func(hash, plain string) {})
+		if err != nil {
+			t.Errorf("Run() error = %v, want nil", err)
+		}
+		if stats.Candidates != gen.Keyspace() {
+			t.Errorf("Run() candidates = %v, want %v", stats.Candidates, gen.Keyspace())
+		}
+	})
+}
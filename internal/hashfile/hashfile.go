@@ -0,0 +1,102 @@
+package hashfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+// maxLineSize bounds a single line, so a file without newlines cannot be
+// buffered whole.
+const maxLineSize = 64 << 10
+
+// johnTags are the prefixes John the Ripper puts in front of hashes, by the
+// hash type they mark.
+var johnTags = map[hashtype.HashType][]string{
+	hashtype.HashTypeMD5:  {"$dynamic_0$", "{MD5}"},
+	hashtype.HashTypeSHA1: {"$dynamic_26$", "{SHA}"},
+	hashtype.HashTypeNTLM: {"$NT$", "$3$$"},
+	hashtype.HashTypeLM:   {"$LM$"},
+}
+
+// Entry is a hash read from a hash file with the username or label it was
+// listed under, if any.
+type Entry struct {
+	Label string
+	Hash  string
+}
+
+// Parse reads a hash file of type t line by line, calling fn for every
+// entry. Lines may hold a bare hash as hashcat takes it, `user:hash`, a
+// John-style line with a tagged hash and trailing fields, or a pwdump line
+// `user:rid:lm:nt:::` for LM and NTLM. Blank lines and `#` comments are
+// skipped. Hashes are returned as found; they are not validated.
+func Parse(r io.Reader, t hashtype.HashType, fn func(e Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		err := fn(ParseLine(text, t))
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading hash file line %d: %w", line+1, err)
+	}
+
+	return nil
+}
+
+// ParseLine splits a single hash file line into its label and hash.
+func ParseLine(line string, t hashtype.HashType) Entry {
+	fields := strings.Split(line, ":")
+	if len(fields) == 1 {
+		return Entry{Hash: stripTag(fields[0], t)}
+	}
+
+	if isPwdump(fields) {
+		switch t {
+		case hashtype.HashTypeLM:
+			return Entry{Label: fields[0], Hash: fields[2]}
+		case hashtype.HashTypeNTLM:
+			return Entry{Label: fields[0], Hash: fields[3]}
+		}
+	}
+
+	return Entry{Label: fields[0], Hash: stripTag(fields[1], t)}
+}
+
+// isPwdump reports whether fields look like `user:rid:lm:nt:::`.
+func isPwdump(fields []string) bool {
+	if len(fields) < 4 || fields[1] == "" {
+		return false
+	}
+	for _, c := range fields[1] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stripTag(hash string, t hashtype.HashType) string {
+	for _, tag := range johnTags[t] {
+		if strings.HasPrefix(hash, tag) {
+			return hash[len(tag):]
+		}
+	}
+
+	return hash
+}
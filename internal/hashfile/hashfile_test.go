@@ -0,0 +1,107 @@
+package hashfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		hashType hashtype.HashType
+		want     Entry
+	}{
+		{
+			name:     "Test ParseLine bare hash",
+			line:     "098f6bcd4621d373cade4e832627b4f6",
+			hashType: hashtype.HashTypeMD5,
+			want:     Entry{Hash: "098f6bcd4621d373cade4e832627b4f6"},
+		},
+		{
+			name:     "Test ParseLine user and hash",
+			line:     "alice:098f6bcd4621d373cade4e832627b4f6",
+			hashType: hashtype.HashTypeMD5,
+			want:     Entry{Label: "alice", Hash: "098f6bcd4621d373cade4e832627b4f6"},
+		},
+		{
+			name:     "Test ParseLine john tagged md5",
+			line:     "bob:$dynamic_0$098f6bcd4621d373cade4e832627b4f6",
+			hashType: hashtype.HashTypeMD5,
+			want:     Entry{Label: "bob", Hash: "098f6bcd4621d373cade4e832627b4f6"},
+		},
+		{
+			name:     "Test ParseLine john passwd fields",
+			line:     "carol:$NT$8846f7eaee8fb117ad06bdd830b7586c:1001:1001::/home/carol:/bin/sh",
+			hashType: hashtype.HashTypeNTLM,
+			want:     Entry{Label: "carol", Hash: "8846f7eaee8fb117ad06bdd830b7586c"},
+		},
+		{
+			name:     "Test ParseLine ldap sha",
+			line:     "dave:{SHA}qUqP5cyxm6YcTAhz05Hph5gvu9M=",
+			hashType: hashtype.HashTypeSHA1,
+			want:     Entry{Label: "dave", Hash: "qUqP5cyxm6YcTAhz05Hph5gvu9M="},
+		},
+		{
+			name:     "Test ParseLine pwdump ntlm",
+			line:     "Administrator:500:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::",
+			hashType: hashtype.HashTypeNTLM,
+			want:     Entry{Label: "Administrator", Hash: "8846f7eaee8fb117ad06bdd830b7586c"},
+		},
+		{
+			name:     "Test ParseLine pwdump lm",
+			line:     "Administrator:500:e52cac67419a9a224a3b108f3fa6cb6d:8846f7eaee8fb117ad06bdd830b7586c:::",
+			hashType: hashtype.HashTypeLM,
+			want:     Entry{Label: "Administrator", Hash: "e52cac67419a9a224a3b108f3fa6cb6d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLine(tt.line, tt.hashType)
+			if got != tt.want {
+				t.Errorf("ParseLine() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := "# exported hashes\r\n\r\nalice:098f6bcd4621d373cade4e832627b4f6\r\nad0234829205b9033196ba818f7a872b\n"
+
+	got := make([]Entry, 0)
+	err := Parse(strings.NewReader(input), hashtype.HashTypeMD5, func(e Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []Entry{
+		{Label: "alice", Hash: "098f6bcd4621d373cade4e832627b4f6"},
+		{Hash: "ad0234829205b9033196ba818f7a872b"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parse() got = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Parse() entry %d got = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	stop := errors.New("stop")
+	err = Parse(strings.NewReader(input), hashtype.HashTypeMD5, func(e Entry) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Parse() error = %v, want %v", err, stop)
+	}
+
+	err = Parse(strings.NewReader(strings.Repeat("a", maxLineSize+1)), hashtype.HashTypeMD5, func(e Entry) error { return nil })
+	if err == nil {
+		t.Errorf("Parse() of an overlong line got = nil, want error")
+	}
+}
@@ -0,0 +1,191 @@
+package hashjob
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// DeletionPolicy says what happens to the jobs of a user being deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRefuse refuses to delete users with pending or running
+	// jobs, and refuses hard deletions of users owning any job, so no job is
+	// left owned by a user that no longer exists. Soft deletions leave the
+	// finished jobs in place.
+	DeletionPolicyRefuse DeletionPolicy = "refuse"
+	// DeletionPolicyCascade deletes the user's jobs along with it.
+	DeletionPolicyCascade DeletionPolicy = "cascade"
+	// DeletionPolicyReassign hands the user's jobs to another user or team.
+	DeletionPolicyReassign DeletionPolicy = "reassign"
+)
+
+var (
+	ErrInvalidDeletion    = errors.New("invalid user deletion")
+	ErrOwnerHasActiveJobs = errors.New("user has pending or running hashjobs")
+	ErrOwnerHasJobs       = errors.New("user owns hashjobs; cascade or reassign them to delete it")
+)
+
+// UserDeletion describes how to delete a user. With the reassign policy, the
+// user's jobs go to NewOwner and, if Team is set, are shared with it; without
+// a NewOwner the first other admin of Team takes them over. Soft deletions
+// only mark the user deleted, so it can be restored, and cannot cascade.
+type UserDeletion struct {
+	Policy   DeletionPolicy
+	NewOwner *user.User
+	Team     *user.Team
+	Soft     bool
+}
+
+// UserDeletionStore deletes or soft-deletes a user together with what
+// happens to its jobs, all or nothing. u is written back if soft is set and
+// removed otherwise, along with its API keys, password and team memberships;
+// jobs with ids in deleted are removed and reassigned jobs are written back.
+// Hard deletions that would leave a team with members but no admin fail with
+// user.ErrLastTeamAdmin.
+type UserDeletionStore interface {
+	DeleteUserAndHashJobs(u *user.User, soft bool, deleted []string, reassigned []HashJob) error
+}
+
+// SessionRevoker ends every session of a user, implemented by
+// user.AuthService.
+type SessionRevoker interface {
+	EndSessions(u *user.User) error
+}
+
+// UserDeletionService deletes users along with their jobs according to a
+// DeletionPolicy, keeping the job and user caches in step with the store and
+// ending the sessions of deleted users.
+type UserDeletionService struct {
+	store    UserDeletionStore
+	jobs     *HashJobService
+	users    *user.UserService
+	sessions SessionRevoker
+}
+
+func NewUserDeletionService(s UserDeletionStore, jobs *HashJobService, users *user.UserService, sessions SessionRevoker) *UserDeletionService {
+	return &UserDeletionService{store: s, jobs: jobs, users: users, sessions: sessions}
+}
+
+func (p DeletionPolicy) Validate() error {
+	switch p {
+	case DeletionPolicyRefuse, DeletionPolicyCascade, DeletionPolicyReassign:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown policy %q", ErrInvalidDeletion, p)
+	}
+}
+
+func (d *UserDeletion) Validate(u *user.User) error {
+	if err := d.Policy.Validate(); err != nil {
+		return err
+	}
+	if d.Soft && d.Policy == DeletionPolicyCascade {
+		return fmt.Errorf("%w: soft deletions cannot cascade", ErrInvalidDeletion)
+	}
+	if d.Policy != DeletionPolicyReassign {
+		if d.NewOwner != nil || d.Team != nil {
+			return fmt.Errorf("%w: only the reassign policy takes a new owner or team", ErrInvalidDeletion)
+		}
+		return nil
+	}
+
+	if d.NewOwner == nil && d.Team == nil {
+		return fmt.Errorf("%w: reassigning requires a new owner or team", ErrInvalidDeletion)
+	}
+	if d.NewOwner != nil && (d.NewOwner.ID == u.ID || d.NewOwner.Deleted()) {
+		return fmt.Errorf("%w: jobs cannot be reassigned to a deleted user", ErrInvalidDeletion)
+	}
+
+	return nil
+}
+
+// newOwner returns the id of the user the reassign policy hands jobs to.
+func (d *UserDeletion) newOwner(u *user.User) (string, error) {
+	if d.NewOwner != nil {
+		return d.NewOwner.ID, nil
+	}
+
+	for _, m := range d.Team.Members {
+		if m.Admin && m.UserID != u.ID {
+			return m.UserID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: team %v has no other admin to take the jobs over", ErrInvalidDeletion, d.Team.ID)
+}
+
+// DeleteUser deletes u as d describes.
+func (s *UserDeletionService) DeleteUser(u *user.User, d UserDeletion) error {
+	if err := d.Validate(u); err != nil {
+		return err
+	}
+
+	// Claims and updates of the user's jobs wait until they are dealt with.
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	jobs, err := s.jobs.store.ListHashJobsByOwner(u.ID, nil)
+	if err != nil {
+		return fmt.Errorf("error listing hashjobs: %w", err)
+	}
+
+	var deleted []string
+	var reassigned []HashJob
+	switch d.Policy {
+	case DeletionPolicyRefuse:
+		for _, hj := range jobs {
+			if !hj.Status.Terminal() {
+				return ErrOwnerHasActiveJobs
+			}
+		}
+		if !d.Soft && len(jobs) > 0 {
+			return ErrOwnerHasJobs
+		}
+	case DeletionPolicyCascade:
+		for _, hj := range jobs {
+			deleted = append(deleted, hj.ID)
+		}
+	case DeletionPolicyReassign:
+		ownerId, err := d.newOwner(u)
+		if err != nil {
+			return err
+		}
+		for _, hj := range jobs {
+			hj.OwnerId = ownerId
+			if d.Team != nil {
+				hj.TeamId = d.Team.ID
+			}
+			reassigned = append(reassigned, hj)
+		}
+	}
+
+	deletedUser := *u
+	if d.Soft {
+		now := time.Now().UTC()
+		deletedUser.DeletedAt = &now
+	}
+
+	err = s.store.DeleteUserAndHashJobs(&deletedUser, d.Soft, deleted, reassigned)
+	if err != nil {
+		return err
+	}
+
+	// The store is consistent from here on; stale cache entries and sessions
+	// would only hide that, so they are dropped rather than failing the
+	// deletion.
+	var cacheErrs []error
+	cacheErrs = append(cacheErrs, s.users.ClearCache(u), s.sessions.EndSessions(u))
+	for _, hj := range jobs {
+		cacheErrs = append(cacheErrs, s.jobs.cache.ClearHashJob(hj))
+	}
+
+	if err := errors.Join(cacheErrs...); err != nil {
+		return fmt.Errorf("user deleted, but clearing caches or sessions failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package hashjob
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// MockUserStore implements user.UserStore
+
+type MockUserStore struct {
+	Users map[string]user.User
+}
+
+func (m *MockUserStore) InsertUser(u *user.User) error {
+	m.Users[u.ID] = *u
+	return nil
+}
+
+func (m *MockUserStore) GetUser(id string) (*user.User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{}
+	}
+	return &u, nil
+}
+
+func (m *MockUserStore) GetUserByEmail(email string) (*user.User, error) {
+	return nil, &uerr.ErrorNotFound{}
+}
+
+func (m *MockUserStore) GetUserByUsername(username string) (*user.User, error) {
+	return nil, &uerr.ErrorNotFound{}
+}
+
+func (m *MockUserStore) UpdateUser(u *user.User) error {
+	m.Users[u.ID] = *u
+	return nil
+}
+
+func (m *MockUserStore) DeleteUser(id string) error {
+	delete(m.Users, id)
+	return nil
+}
+
+func (m *MockUserStore) ListUsers(q user.UserQuery) (*user.UserPage, error) {
+	return &user.UserPage{}, nil
+}
+
+// MockUserCache implements user.UserCache
+
+type MockUserCache struct {
+	Users map[string]user.User
+}
+
+func (m *MockUserCache) GetUser(id string) (*user.User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{}
+	}
+	return &u, nil
+}
+
+func (m *MockUserCache) GetUserByEmail(email string) (*user.User, error) {
+	return nil, &uerr.ErrorNotFound{}
+}
+
+func (m *MockUserCache) SetUser(u *user.User) error {
+	m.Users[u.ID] = *u
+	return nil
+}
+
+func (m *MockUserCache) ClearUser(u *user.User) error {
+	delete(m.Users, u.ID)
+	return nil
+}
+
+// MockUserDeletionStore implements UserDeletionStore over the user and job
+// mocks.
+
+type MockUserDeletionStore struct {
+	Users *MockUserStore
+	Jobs  *MockHashJobStore
+}
+
+func (m *MockUserDeletionStore) DeleteUserAndHashJobs(u *user.User, soft bool, deleted []string, reassigned []HashJob) error {
+	if soft {
+		m.Users.Users[u.ID] = *u
+	} else {
+		delete(m.Users.Users, u.ID)
+	}
+	for _, id := range deleted {
+		delete(m.Jobs.HashJobs, id)
+	}
+	for _, hj := range reassigned {
+		m.Jobs.HashJobs[hj.ID] = hj
+	}
+	return nil
+}
+
+// MockSessionRevoker implements SessionRevoker
+
+type MockSessionRevoker struct {
+	Ended []string
+}
+
+func (m *MockSessionRevoker) EndSessions(u *user.User) error {
+	m.Ended = append(m.Ended, u.ID)
+	return nil
+}
+
+func TestUserDeletionService_DeleteUser(t *testing.T) {
+	alice := user.User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: user.RoleAnalyst}
+	bob := user.User{ID: "bob", Username: "bob", Email: "bob@example.com", Role: user.RoleAnalyst}
+	red := user.Team{ID: "red", Members: []user.TeamMember{{UserID: "alice", Admin: true}, {UserID: "carol", Admin: true}}}
+	blue := user.Team{ID: "blue", Members: []user.TeamMember{{UserID: "alice", Admin: true}, {UserID: "bob"}}}
+
+	tests := []struct {
+		name       string
+		running    bool
+		deletion   UserDeletion
+		wantErr    error
+		wantJobs   map[string]string
+		wantTeam   string
+		wantUser   bool
+		wantCached bool
+	}{
+		{
+			name:       "refuse with only finished jobs",
+			deletion:   UserDeletion{Policy: DeletionPolicyRefuse},
+			wantErr:    ErrOwnerHasJobs,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:       "refuse with running jobs",
+			running:    true,
+			deletion:   UserDeletion{Policy: DeletionPolicyRefuse},
+			wantErr:    ErrOwnerHasActiveJobs,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:     "cascade",
+			running:  true,
+			deletion: UserDeletion{Policy: DeletionPolicyCascade},
+			wantJobs: map[string]string{"c": "bob"},
+		},
+		{
+			name:     "reassign to user and team",
+			running:  true,
+			deletion: UserDeletion{Policy: DeletionPolicyReassign, NewOwner: &bob, Team: &blue},
+			wantJobs: map[string]string{"a": "bob", "b": "bob", "c": "bob"},
+			wantTeam: "blue",
+		},
+		{
+			name:     "reassign to team admin",
+			deletion: UserDeletion{Policy: DeletionPolicyReassign, Team: &red},
+			wantJobs: map[string]string{"a": "carol", "b": "carol", "c": "bob"},
+			wantTeam: "red",
+		},
+		{
+			name:       "reassign to team without other admin",
+			deletion:   UserDeletion{Policy: DeletionPolicyReassign, Team: &blue},
+			wantErr:    ErrInvalidDeletion,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:       "reassign without target",
+			deletion:   UserDeletion{Policy: DeletionPolicyReassign},
+			wantErr:    ErrInvalidDeletion,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:       "reassign to self",
+			deletion:   UserDeletion{Policy: DeletionPolicyReassign, NewOwner: &alice},
+			wantErr:    ErrInvalidDeletion,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:       "soft cascade",
+			deletion:   UserDeletion{Policy: DeletionPolicyCascade, Soft: true},
+			wantErr:    ErrInvalidDeletion,
+			wantJobs:   map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser:   true,
+			wantCached: true,
+		},
+		{
+			name:     "soft refuse",
+			deletion: UserDeletion{Policy: DeletionPolicyRefuse, Soft: true},
+			wantJobs: map[string]string{"a": "alice", "b": "alice", "c": "bob"},
+			wantUser: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := HashJobStatusDone
+			if tt.running {
+				status = HashJobStatusRunning
+			}
+			jobs := map[string]HashJob{
+				"a": {ID: "a", OwnerId: "alice", Status: HashJobStatusDone},
+				"b": {ID: "b", OwnerId: "alice", Status: status},
+				"c": {ID: "c", OwnerId: "bob", Status: HashJobStatusPending},
+			}
+			cached := make(map[string]HashJob)
+			for id, hj := range jobs {
+				cached[id] = hj
+			}
+			jobStore := &MockHashJobStore{HashJobs: jobs}
+			jobCache := &MockHashJobCache{HashJobs: cached}
+			userStore := &MockUserStore{Users: map[string]user.User{"alice": alice, "bob": bob}}
+			userCache := &MockUserCache{Users: map[string]user.User{"alice": alice}}
+			sessions := &MockSessionRevoker{}
+
+			s := NewUserDeletionService(
+				&MockUserDeletionStore{Users: userStore, Jobs: jobStore},
+				NewHashJobService(jobStore, jobCache, nil),
+				user.NewUserService(userStore, userCache),
+				sessions,
+			)
+
+			err := s.DeleteUser(&alice, tt.deletion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteUser() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(jobs) != len(tt.wantJobs) {
+				t.Errorf("DeleteUser() left jobs %v, want %v", jobs, tt.wantJobs)
+			}
+			for id, owner := range tt.wantJobs {
+				if jobs[id].OwnerId != owner {
+					t.Errorf("DeleteUser() job %v owned by %q, want %q", id, jobs[id].OwnerId, owner)
+				}
+				if id != "c" && jobs[id].TeamId != tt.wantTeam {
+					t.Errorf("DeleteUser() job %v shared with %q, want %q", id, jobs[id].TeamId, tt.wantTeam)
+				}
+			}
+
+			u, ok := userStore.Users["alice"]
+			if ok != tt.wantUser {
+				t.Errorf("DeleteUser() user stored = %v, want %v", ok, tt.wantUser)
+			}
+			if ok && u.Deleted() != (tt.deletion.Soft && tt.wantErr == nil) {
+				t.Errorf("DeleteUser() user deleted at %v, want soft deletion %v", u.DeletedAt, tt.deletion.Soft)
+			}
+			if _, ok := userCache.Users["alice"]; ok != tt.wantCached {
+				t.Errorf("DeleteUser() user cached = %v, want %v", ok, tt.wantCached)
+			}
+			if tt.wantErr == nil && len(cached) != 1 {
+				t.Errorf("DeleteUser() left jobs %v cached, want only bob's", cached)
+			}
+			if wantEnded := tt.wantErr == nil; (len(sessions.Ended) == 1 && sessions.Ended[0] == "alice") != wantEnded {
+				t.Errorf("DeleteUser() ended sessions of %v, want alice's ended %v", sessions.Ended, wantEnded)
+			}
+		})
+	}
+}
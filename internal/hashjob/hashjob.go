@@ -1,11 +1,20 @@
 package hashjob
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/crack"
+	"github.com/fmdunlap/unhash/internal/hashtype"
 	"github.com/fmdunlap/unhash/internal/uerr"
 	"github.com/fmdunlap/unhash/internal/user"
 	"github.com/google/uuid"
@@ -18,18 +27,92 @@ const (
 	HashJobStatusRunning HashJobStatus = "running"
 	HashJobStatusDone    HashJobStatus = "done"
 	HashJobStatusError   HashJobStatus = "error"
+	// HashJobStatusTimedOut and HashJobStatusExhausted mark jobs stopped by
+	// their runtime and candidate budgets respectively.
+	HashJobStatusTimedOut  HashJobStatus = "timed_out"
+	HashJobStatusExhausted HashJobStatus = "exhausted"
+	// HashJobStatusQuotaExceeded marks jobs stopped because their owner or
+	// team used up its daily CPU time.
+	HashJobStatusQuotaExceeded HashJobStatus = "quota_exceeded"
 )
 
+// Budget bounds the work spent on a job across all of its runs. Zero values
+// are unlimited.
+type Budget struct {
+	MaxRuntimeSeconds int64  `json:"maxRuntimeSeconds,omitempty"`
+	MaxCandidates     uint64 `json:"maxCandidates,omitempty"`
+}
+
+type StageType string
+
+const (
+	StageTypePotfile StageType = "potfile"
+	StageTypeAttack  StageType = "attack"
+	// StageTypeRainbow looks hashes up in the rainbow tables the worker has
+	// for the job's hash type.
+	StageTypeRainbow StageType = "rainbow"
+)
+
+type StageStatus string
+
+const (
+	StageStatusPending StageStatus = "pending"
+	StageStatusRunning StageStatus = "running"
+	StageStatusDone    StageStatus = "done"
+	// StageStatusSkipped marks stages not run because every hash was already
+	// cracked.
+	StageStatusSkipped StageStatus = "skipped"
+)
+
+// Stage is one step of a job's pipeline. Each stage only runs against the
+// hashes the stages before it left uncracked. The Position of an attack stage
+// is that of its generator; that of a rainbow stage is the index of the next
+// of the job's hashes to look up. Dictionary attacks also record the Offset
+// into their decompressed wordlist that Position is at.
+type Stage struct {
+	Type     StageType      `json:"type"`
+	Attack   *attack.Attack `json:"attack,omitempty"`
+	Status   StageStatus    `json:"status"`
+	Keyspace uint64         `json:"keyspace,omitempty"`
+	Position uint64         `json:"position,omitempty"`
+	Offset   uint64         `json:"offset,omitempty"`
+	Stats    crack.Stats    `json:"stats"`
+}
+
+// Target is a submitted hash with the username or label it belongs to, if
+// known.
+type Target struct {
+	Hash  string `json:"hash"`
+	Label string `json:"label,omitempty"`
+}
+
+// HashJob is a set of hashes and the pipeline of stages run to crack them.
+// Labels lists the usernames or labels each hash was submitted under.
+// CrackedBy maps each hash cracked by a stage to that stage's index; hashes
+// resolved by lookups on creation have no entry.
 type HashJob struct {
-	ID      string        `json:"id"`
-	OwnerId string        `json:"ownerId"`
-	Status  HashJobStatus `json:"status"`
-	Hashes  []string      `json:"hash"`
+	ID        string              `json:"id"`
+	OwnerId   string              `json:"ownerId"`
+	TeamId    string              `json:"teamId,omitempty"`
+	Status    HashJobStatus       `json:"status"`
+	HashType  hashtype.HashType   `json:"hashType"`
+	Hashes    []string            `json:"hash"`
+	Labels    map[string][]string `json:"labels,omitempty"`
+	Stages    []Stage             `json:"stages,omitempty"`
+	Stage     int                 `json:"stage"`
+	Budget    *Budget             `json:"budget,omitempty"`
+	Stats     crack.Stats         `json:"stats"`
+	Cracked   map[string]string   `json:"cracked,omitempty"`
+	CrackedBy map[string]int      `json:"crackedBy,omitempty"`
+	Error     string              `json:"error,omitempty"`
 }
 
 type HashJobStore interface {
 	InsertHashJob(h HashJob) error
 	GetHashJob(id string) (*HashJob, error)
+	UpdateHashJob(h HashJob) error
+	ListHashJobsByStatus(status HashJobStatus) ([]HashJob, error)
+	ListHashJobsByOwner(ownerId string, teamIds []string) ([]HashJob, error)
 	DeleteHashJob(id string) error
 }
 
@@ -39,13 +122,60 @@ type HashJobCache interface {
 	ClearHashJob(h HashJob) error
 }
 
+// PlaintextLookup resolves digests of a single hash type without running an
+// attack, e.g. from a precomputed table.
+type PlaintextLookup interface {
+	HashType() hashtype.HashType
+	Lookup(digest []byte) (string, bool, error)
+}
+
+// QuotaChecker decides whether a user, and the team a job is shared with,
+// may submit more work, implemented by usage.UsageService.
+type QuotaChecker interface {
+	CheckHashes(userId, teamId string, n int) error
+	CheckConcurrency(userId, teamId string, activeUser, activeTeam int) error
+}
+
+var (
+	ErrHashJobNotAppendable = errors.New("hashes can only be added to pending or running hashjobs")
+	ErrNoValidHashes        = errors.New("no valid hashes submitted")
+	// ErrInvalidHashJob wraps the reasons a submitted job is malformed.
+	ErrInvalidHashJob = errors.New("invalid hashjob")
+)
+
+// SubmissionReport describes what became of each submitted hash. Accepted
+// hashes are in their normalized form; duplicates and rejected hashes are
+// as submitted.
+type SubmissionReport struct {
+	ID         string         `json:"id,omitempty"`
+	Accepted   []string       `json:"accepted"`
+	Duplicates []string       `json:"duplicates"`
+	Rejected   []RejectedHash `json:"rejected"`
+
+	labels map[string][]string
+}
+
+type RejectedHash struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
 type HashJobService struct {
-	store HashJobStore
-	cache HashJobCache
+	store   HashJobStore
+	cache   HashJobCache
+	quotas  QuotaChecker
+	lookups []PlaintextLookup
+
+	// mu serializes read-modify-write updates of jobs, which the API and the
+	// worker make concurrently, and quota checks with the submissions they
+	// admit.
+	mu sync.Mutex
 }
 
-func NewHashJobService(s HashJobStore, c HashJobCache) *HashJobService {
-	return &HashJobService{store: s, cache: c}
+// NewHashJobService returns a service keeping jobs in s and c. q may be nil,
+// in which case jobs are not subject to quotas.
+func NewHashJobService(s HashJobStore, c HashJobCache, q QuotaChecker, lookups ...PlaintextLookup) *HashJobService {
+	return &HashJobService{store: s, cache: c, quotas: q, lookups: lookups}
 }
 
 func Unmarshal(data []byte) (*HashJob, error) {
@@ -58,38 +188,246 @@ func Unmarshal(data []byte) (*HashJob, error) {
 	return &hj, nil
 }
 
-func (h *HashJobService) CreateHashJob(hashes []string, owner *user.User) (string, error) {
-	if len(hashes) == 0 {
-		return "", errors.New("hashes cannot be empty")
+// AccessibleBy reports whether u, a member of teams, may read hj: admins
+// may read every job, everyone else their own and their teams'.
+func (hj *HashJob) AccessibleBy(u *user.User, teams []user.Team) bool {
+	if u.IsAdmin() || hj.OwnerId == u.ID {
+		return true
+	}
+
+	return slices.ContainsFunc(teams, func(t user.Team) bool {
+		return hj.TeamId != "" && t.ID == hj.TeamId && t.IsMember(u.ID)
+	})
+}
+
+// DeletableBy reports whether u, a member of teams, may delete hj: admins
+// and the job's owner may, as may admins of the team it belongs to.
+func (hj *HashJob) DeletableBy(u *user.User, teams []user.Team) bool {
+	if u.IsAdmin() || hj.OwnerId == u.ID {
+		return true
+	}
+
+	return slices.ContainsFunc(teams, func(t user.Team) bool {
+		return hj.TeamId != "" && t.ID == hj.TeamId && t.ManageableBy(u)
+	})
+}
+
+func (s *Stage) Validate() error {
+	switch s.Type {
+	case StageTypePotfile, StageTypeRainbow:
+		if s.Attack != nil {
+			return fmt.Errorf("%v stage does not take an attack", s.Type)
+		}
+	case StageTypeAttack:
+		if s.Attack == nil {
+			return errors.New("attack stage requires an attack")
+		}
+		if err := s.Attack.Validate(); err != nil {
+			return fmt.Errorf("invalid attack: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown stage type `%v`", s.Type)
+	}
+
+	return nil
+}
+
+func (b *Budget) Validate() error {
+	if b.MaxRuntimeSeconds < 0 {
+		return fmt.Errorf("max runtime cannot be negative, got %d", b.MaxRuntimeSeconds)
+	}
+
+	return nil
+}
+
+// MaxRuntime returns the runtime budget, or 0 if it is unlimited.
+func (b *Budget) MaxRuntime() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return time.Duration(b.MaxRuntimeSeconds) * time.Second
+}
+
+// Terminal reports whether a job in status s will not run again.
+func (s HashJobStatus) Terminal() bool {
+	switch s {
+	case HashJobStatusDone, HashJobStatusError, HashJobStatusTimedOut, HashJobStatusExhausted, HashJobStatusQuotaExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// RemainingKeyspace returns how many candidates the job's unfinished attack
+// stages have left to try, bounded by its candidate budget. lines returns the
+// line count of a wordlist, needed for stages that have not started yet. It
+// fails if the keyspace of such a stage depends on the contents of its files.
+func (hj *HashJob) RemainingKeyspace(lines func(id string) (uint64, error)) (uint64, error) {
+	var total uint64
+	for i := hj.Stage; i < len(hj.Stages); i++ {
+		stage := hj.Stages[i]
+		if stage.Attack == nil || stage.Status == StageStatusDone || stage.Status == StageStatusSkipped {
+			continue
+		}
+
+		keyspace := stage.Keyspace
+		if keyspace == 0 {
+			wordlistLines := make([]uint64, len(stage.Attack.Wordlists))
+			for j, id := range stage.Attack.Wordlists {
+				n, err := lines(id)
+				if err != nil {
+					return 0, err
+				}
+				wordlistLines[j] = n
+			}
+
+			var err error
+			keyspace, err = stage.Attack.Keyspace(wordlistLines)
+			if err != nil {
+				return 0, fmt.Errorf("error computing keyspace of stage %d: %w", i, err)
+			}
+		}
+
+		if keyspace > stage.Position {
+			left := keyspace - stage.Position
+			if total > math.MaxUint64-left {
+				total = math.MaxUint64
+			} else {
+				total += left
+			}
+		}
+	}
+
+	if hj.Budget != nil && hj.Budget.MaxCandidates > 0 {
+		if hj.Stats.Candidates >= hj.Budget.MaxCandidates {
+			return 0, nil
+		}
+		total = min(total, hj.Budget.MaxCandidates-hj.Stats.Candidates)
+	}
+
+	return total, nil
+}
+
+// CreateHashJob creates a pending job running stages in order against the
+// targets, shared with the team with teamId if it is set. Hashes are
+// normalized and deduplicated first; the report lists what happened to each
+// of them. If none is accepted no job is created and ErrNoValidHashes is
+// returned along with the report. Jobs beyond the owner's or team's quotas
+// are refused with an error wrapping usage.ErrQuotaExceeded.
+func (h *HashJobService) CreateHashJob(hashType hashtype.HashType, targets []Target, owner *user.User, teamId string, stages []Stage, budget *Budget) (*SubmissionReport, error) {
+	if err := hashType.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHashJob, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: hashes cannot be empty", ErrInvalidHashJob)
 	}
 	if owner == nil {
-		return "", errors.New("owner cannot be nil")
+		return nil, errors.New("owner cannot be nil")
 	}
 	if owner.Validate() != nil {
-		return "", fmt.Errorf("invalid owner: %w", owner.Validate())
+		return nil, fmt.Errorf("invalid owner: %w", owner.Validate())
+	}
+	for i := range stages {
+		if err := stages[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%w: invalid stage %d: %w", ErrInvalidHashJob, i, err)
+		}
+		stages[i].Status = StageStatusPending
+	}
+	if budget != nil {
+		if err := budget.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: invalid budget: %w", ErrInvalidHashJob, err)
+		}
+	}
+
+	report := newSubmissionReport(hashType, targets, nil)
+	if len(report.Accepted) == 0 {
+		return report, ErrNoValidHashes
 	}
 
 	hj := HashJob{
-		ID:      uuid.New().String(),
-		OwnerId: owner.ID,
-		Status:  HashJobStatusPending,
-		Hashes:  hashes,
+		ID:       uuid.New().String(),
+		OwnerId:  owner.ID,
+		TeamId:   teamId,
+		Status:   HashJobStatusPending,
+		HashType: hashType,
+		Hashes:   report.Accepted,
+		Stages:   stages,
+		Budget:   budget,
+	}
+	hj.AddLabels(report.labels)
+
+	h.lookupPlaintexts(&hj)
+	if len(hj.Cracked) == len(hj.Hashes) {
+		hj.Status = HashJobStatusDone
+	}
+
+	// The quotas are checked and the job inserted under the lock, so
+	// concurrent submissions cannot all pass the check.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.quotas != nil {
+		if err := h.checkQuotas(owner.ID, teamId, len(report.Accepted)); err != nil {
+			return nil, err
+		}
 	}
 
 	err := h.store.InsertHashJob(hj)
 	if err != nil {
 		if errors.Is(err, &uerr.ErrorCannotInsert{}) {
-			return "", fmt.Errorf("hashjob already exists: %w", err)
+			return nil, fmt.Errorf("hashjob already exists: %w", err)
 		}
-		return "", err
+		return nil, err
 	}
 
 	err = h.cache.SetHashJob(hj)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return hj.ID, nil
+	report.ID = hj.ID
+	return report, nil
+}
+
+// checkQuotas checks a new job of n hashes owned by ownerId and shared with
+// teamId against their quotas.
+func (h *HashJobService) checkQuotas(ownerId, teamId string, n int) error {
+	err := h.quotas.CheckHashes(ownerId, teamId, n)
+	if err != nil {
+		return err
+	}
+
+	activeUser, err := h.activeHashJobs(ownerId, nil)
+	if err != nil {
+		return err
+	}
+	activeTeam := 0
+	if teamId != "" {
+		activeTeam, err = h.activeHashJobs("", []string{teamId})
+		if err != nil {
+			return err
+		}
+	}
+
+	return h.quotas.CheckConcurrency(ownerId, teamId, activeUser, activeTeam)
+}
+
+// activeHashJobs counts the pending and running jobs owned by ownerId or
+// shared with one of teamIds.
+func (h *HashJobService) activeHashJobs(ownerId string, teamIds []string) (int, error) {
+	jobs, err := h.store.ListHashJobsByOwner(ownerId, teamIds)
+	if err != nil {
+		return 0, fmt.Errorf("error listing hashjobs: %w", err)
+	}
+
+	active := 0
+	for _, hj := range jobs {
+		if !hj.Status.Terminal() {
+			active++
+		}
+	}
+
+	return active, nil
 }
 
 func (h *HashJobService) GetHashJob(id string) (*HashJob, error) {
@@ -116,14 +454,302 @@ func (h *HashJobService) GetHashJob(id string) (*HashJob, error) {
 	return hj, nil
 }
 
-func (h *HashJobService) DeleteHashJob(id string) error {
-	err := h.store.DeleteHashJob(id)
+// ListHashJobs lists the jobs u owns or that belong to one of teams, oldest
+// first.
+func (h *HashJobService) ListHashJobs(u *user.User, teams []user.Team) ([]HashJob, error) {
+	teamIds := make([]string, 0, len(teams))
+	for _, t := range teams {
+		teamIds = append(teamIds, t.ID)
+	}
+
+	jobs, err := h.store.ListHashJobsByOwner(u.ID, teamIds)
+	if err != nil {
+		return nil, fmt.Errorf("error listing hashjobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// UpdateHashJob writes hj to the store and refreshes its cached copy. Hashes
+// and cracked plaintexts are only ever added, so any the stored job has that
+// hj lacks, e.g. hashes appended while the worker held hj, are kept. So is
+// the stored job's ownership, which only user deletions change.
+func (h *HashJobService) UpdateHashJob(hj HashJob) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.updateHashJob(hj)
+}
+
+func (h *HashJobService) updateHashJob(hj HashJob) error {
+	stored, err := h.store.GetHashJob(hj.ID)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return fmt.Errorf("hashjob not found: %w", err)
+		}
+		return err
+	}
+
+	if len(stored.Hashes) > len(hj.Hashes) {
+		hj.Hashes = stored.Hashes
+	}
+	for hash, plain := range stored.Cracked {
+		if _, ok := hj.Cracked[hash]; ok {
+			continue
+		}
+		if hj.Cracked == nil {
+			hj.Cracked = make(map[string]string)
+		}
+		hj.Cracked[hash] = plain
+	}
+	hj.AddLabels(stored.Labels)
+	hj.OwnerId = stored.OwnerId
+	hj.TeamId = stored.TeamId
+
+	err = h.store.UpdateHashJob(hj)
 	if err != nil {
-		if errors.Is(err, &uerr.ErrorCannotDelete{}) {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
 			return fmt.Errorf("hashjob not found: %w", err)
 		}
-		return nil
+		return err
+	}
+
+	return h.cache.SetHashJob(hj)
+}
+
+// ClaimHashJob marks the oldest pending job as running and returns it. It
+// returns ErrorNotFound when no job is pending.
+func (h *HashJobService) ClaimHashJob() (*HashJob, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	jobs, err := h.store.ListHashJobsByStatus(HashJobStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pending hashjobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("no pending hashjob")}
+	}
+
+	hj := jobs[0]
+	hj.Status = HashJobStatusRunning
+	err = h.updateHashJob(hj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hj, nil
+}
+
+// ResourceInUse reports whether a pending or running job has a stage left to
+// run whose attack uses the library resource with id.
+func (h *HashJobService) ResourceInUse(id string) (bool, error) {
+	for _, status := range []HashJobStatus{HashJobStatusPending, HashJobStatusRunning} {
+		jobs, err := h.store.ListHashJobsByStatus(status)
+		if err != nil {
+			return false, fmt.Errorf("error listing %v hashjobs: %w", status, err)
+		}
+
+		for _, hj := range jobs {
+			for i := hj.Stage; i < len(hj.Stages); i++ {
+				a := hj.Stages[i].Attack
+				if a != nil && (a.Rules == id || slices.Contains(a.Wordlists, id)) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// RequeueHashJobs returns jobs left running by a previous process to pending,
+// so they are resumed.
+func (h *HashJobService) RequeueHashJobs() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	jobs, err := h.store.ListHashJobsByStatus(HashJobStatusRunning)
+	if err != nil {
+		return fmt.Errorf("error listing running hashjobs: %w", err)
+	}
+
+	for _, hj := range jobs {
+		hj.Status = HashJobStatusPending
+		err = h.updateHashJob(hj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddHashes normalizes the targets' hashes and appends those the pending or
+// running job does not have yet. Labels of duplicate hashes are still
+// recorded. A running job picks new hashes up from its next stage on; stages
+// it already ran are not repeated for them.
+func (h *HashJobService) AddHashes(id string, targets []Target) (*SubmissionReport, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: hashes cannot be empty", ErrInvalidHashJob)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hj, err := h.store.GetHashJob(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("hashjob not found: %w", err)
+		}
+		return nil, err
+	}
+	if hj.Status != HashJobStatusPending && hj.Status != HashJobStatusRunning {
+		return nil, ErrHashJobNotAppendable
+	}
+
+	report := newSubmissionReport(hj.HashType, targets, hj.Hashes)
+	report.ID = hj.ID
+	if len(report.Accepted) == 0 && len(report.labels) == 0 {
+		return report, nil
+	}
+
+	if h.quotas != nil {
+		err = h.quotas.CheckHashes(hj.OwnerId, hj.TeamId, len(hj.Hashes)+len(report.Accepted))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hj.Hashes = append(hj.Hashes, report.Accepted...)
+	hj.AddLabels(report.labels)
+	h.lookupPlaintexts(hj)
+
+	err = h.store.UpdateHashJob(*hj)
+	if err != nil {
+		return nil, err
+	}
+	err = h.cache.SetHashJob(*hj)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// DeleteHashJob removes the job with id from the store and evicts it from
+// the cache, so it is not served from there afterwards.
+func (h *HashJobService) DeleteHashJob(id string) error {
+	// Updates of the job wait, so they cannot cache it again.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.store.DeleteHashJob(id)
+	if err != nil {
+		return fmt.Errorf("error deleting hashjob: %w", err)
+	}
+
+	err = h.cache.ClearHashJob(HashJob{ID: id})
+	if err != nil {
+		return fmt.Errorf("hashjob deleted, but clearing cache failed: %w", err)
 	}
 
 	return nil
 }
+
+// newSubmissionReport normalizes the hashes of targets for t, sorting out
+// those that are invalid or duplicate each other or one of existing, and
+// collects the labels of every valid one.
+func newSubmissionReport(t hashtype.HashType, targets []Target, existing []string) *SubmissionReport {
+	report := &SubmissionReport{
+		Accepted:   make([]string, 0, len(targets)),
+		Duplicates: make([]string, 0),
+		Rejected:   make([]RejectedHash, 0),
+		labels:     make(map[string][]string),
+	}
+
+	seen := make(map[string]bool, len(existing)+len(targets))
+	for _, hash := range existing {
+		seen[hash] = true
+	}
+
+	for _, target := range targets {
+		normalized, err := t.Normalize(target.Hash)
+		if err != nil {
+			report.Rejected = append(report.Rejected, RejectedHash{Hash: target.Hash, Reason: err.Error()})
+			continue
+		}
+		if target.Label != "" {
+			report.labels[normalized] = append(report.labels[normalized], target.Label)
+		}
+		if seen[normalized] {
+			report.Duplicates = append(report.Duplicates, target.Hash)
+			continue
+		}
+
+		seen[normalized] = true
+		report.Accepted = append(report.Accepted, normalized)
+	}
+
+	return report
+}
+
+// AddLabels records labels for the job's hashes, skipping ones it already
+// has for a hash.
+func (hj *HashJob) AddLabels(labels map[string][]string) {
+	for hash, ls := range labels {
+		if hj.Labels == nil {
+			hj.Labels = make(map[string][]string)
+		}
+		for _, l := range ls {
+			if !slices.Contains(hj.Labels[hash], l) {
+				hj.Labels[hash] = append(hj.Labels[hash], l)
+			}
+		}
+	}
+}
+
+// Targets converts bare hashes into unlabeled targets.
+func Targets(hashes []string) []Target {
+	targets := make([]Target, 0, len(hashes))
+	for _, hash := range hashes {
+		targets = append(targets, Target{Hash: hash})
+	}
+
+	return targets
+}
+
+// lookupPlaintexts resolves whatever hashes of the job the configured lookups
+// already know, so no attack has to run for them.
+func (h *HashJobService) lookupPlaintexts(hj *HashJob) {
+	for _, l := range h.lookups {
+		if l.HashType() != hj.HashType {
+			continue
+		}
+
+		for _, hash := range hj.Hashes {
+			if _, ok := hj.Cracked[hash]; ok {
+				continue
+			}
+
+			digest, err := hex.DecodeString(strings.ToLower(hash))
+			if err != nil || len(digest) != hj.HashType.Size() {
+				continue
+			}
+
+			plain, found, err := l.Lookup(digest)
+			if err != nil {
+				log.Printf("Error looking up hash %v for hashjob %v: %v", hash, hj.ID, err)
+				continue
+			}
+			if !found {
+				continue
+			}
+
+			if hj.Cracked == nil {
+				hj.Cracked = make(map[string]string)
+			}
+			hj.Cracked[hash] = plain
+		}
+	}
+}
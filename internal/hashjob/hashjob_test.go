@@ -2,9 +2,18 @@ package hashjob
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/crack"
+	"github.com/fmdunlap/unhash/internal/hashtype"
 	"github.com/fmdunlap/unhash/internal/uerr"
 	"github.com/fmdunlap/unhash/internal/user"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Helpers
@@ -52,6 +61,47 @@ func (m *MockHashJobStore) GetHashJob(id string) (*HashJob, error) {
 	return &h, nil
 }
 
+func (m *MockHashJobStore) UpdateHashJob(h HashJob) error {
+	_, ok := m.HashJobs[h.ID]
+	if !ok {
+		return &uerr.ErrorNotFound{}
+	}
+	m.HashJobs[h.ID] = h
+	return nil
+}
+
+func (m *MockHashJobStore) ListHashJobsByStatus(status HashJobStatus) ([]HashJob, error) {
+	ids := make([]string, 0)
+	for id, h := range m.HashJobs {
+		if h.Status == status {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	jobs := make([]HashJob, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, m.HashJobs[id])
+	}
+	return jobs, nil
+}
+
+func (m *MockHashJobStore) ListHashJobsByOwner(ownerId string, teamIds []string) ([]HashJob, error) {
+	ids := make([]string, 0)
+	for id, h := range m.HashJobs {
+		if h.OwnerId == ownerId || h.TeamId != "" && slices.Contains(teamIds, h.TeamId) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	jobs := make([]HashJob, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, m.HashJobs[id])
+	}
+	return jobs, nil
+}
+
 func (m *MockHashJobStore) DeleteHashJob(id string) error {
 	_, ok := m.HashJobs[id]
 	if !ok {
@@ -81,14 +131,26 @@ func (m *MockHashJobCache) SetHashJob(h HashJob) error {
 }
 
 func (m *MockHashJobCache) ClearHashJob(h HashJob) error {
-	_, ok := m.HashJobs[h.ID]
-	if !ok {
-		return &uerr.ErrorCannotDelete{}
-	}
 	delete(m.HashJobs, h.ID)
 	return nil
 }
 
+// MockPlaintextLookup implements PlaintextLookup
+
+type MockPlaintextLookup struct {
+	Type       hashtype.HashType
+	Plaintexts map[string]string
+}
+
+func (m *MockPlaintextLookup) HashType() hashtype.HashType {
+	return m.Type
+}
+
+func (m *MockPlaintextLookup) Lookup(digest []byte) (string, bool, error) {
+	plain, ok := m.Plaintexts[string(digest)]
+	return plain, ok, nil
+}
+
 // HashJob Tests
 
 func TestUnmarshal(t *testing.T) {
@@ -136,59 +198,159 @@ func TestUnmarshal(t *testing.T) {
 
 // HashJobService Tests
 
+const (
+	testHash  = "098f6bcd4621d373cade4e832627b4f6"
+	testHash2 = "ad0234829205b9033196ba818f7a872b"
+)
+
 func TestHashJobService_CreateHashJob(t *testing.T) {
 
 	testOwner := &user.User{
 		ID:       "test",
 		Username: "test",
-		Email:    "test",
+		Email:    "test@example.com",
+		Role:     user.RoleAnalyst,
 	}
 
 	type args struct {
-		hashes []string
-		owner  *user.User
+		hashType hashtype.HashType
+		hashes   []string
+		owner    *user.User
+		stages   []Stage
 	}
 	tests := []struct {
 		name    string
 		args    args
 		wantErr bool
+		// wantInvalid is set for errors in the submission rather than the
+		// caller, which the API reports with 400 Bad Request.
+		wantInvalid bool
 	}{
 		{
 			name: "Test CreateHashJob",
 			args: args{
-				hashes: []string{"test"},
-				owner:  testOwner,
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
 			},
 			wantErr: false,
 		},
 		{
 			name: "Test CreateHashJob with multiple hashes",
 			args: args{
-				hashes: []string{"test", "test2"},
-				owner:  testOwner,
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash, testHash2},
+				owner:    testOwner,
 			},
 			wantErr: false,
 		},
 		{
 			name: "Test CreateHashJob with no hashes",
 			args: args{
-				hashes: []string{},
-				owner:  testOwner,
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{},
+				owner:    testOwner,
 			},
-			wantErr: true,
+			wantErr:     true,
+			wantInvalid: true,
 		},
 		{
 			name: "Test CreateHashJob with no owner",
 			args: args{
-				hashes: []string{"test"},
-				owner:  nil,
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    nil,
 			},
 			wantErr: true,
 		},
+		{
+			name: "Test CreateHashJob with unknown hash type",
+			args: args{
+				hashType: "bogus",
+				hashes:   []string{testHash},
+				owner:    testOwner,
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Test CreateHashJob with hybrid attack",
+			args: args{
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
+				stages: []Stage{{
+					Type: StageTypeAttack,
+					Attack: &attack.Attack{
+						Type:      attack.AttackTypeHybridWordlistMask,
+						Wordlists: []string{"rockyou.txt"},
+						Mask:      "?d?d?d?d",
+					},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test CreateHashJob with pipeline",
+			args: args{
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
+				stages: []Stage{
+					{Type: StageTypePotfile},
+					{Type: StageTypeRainbow},
+					{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"rockyou.txt"}}},
+					{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"rockyou.txt"}, Rules: "best64.rule"}},
+					{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeHybridWordlistMask, Wordlists: []string{"rockyou.txt"}, Mask: "?d?d"}},
+					{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeMask, Mask: "?a?a?a?a"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test CreateHashJob with invalid attack",
+			args: args{
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
+				stages: []Stage{{
+					Type: StageTypeAttack,
+					Attack: &attack.Attack{
+						Type:      attack.AttackTypeCombinator,
+						Wordlists: []string{"rockyou.txt"},
+					},
+				}},
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Test CreateHashJob with attack stage without attack",
+			args: args{
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
+				stages:   []Stage{{Type: StageTypeAttack}},
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "Test CreateHashJob with rainbow stage with attack",
+			args: args{
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
+				owner:    testOwner,
+				stages:   []Stage{{Type: StageTypeRainbow, Attack: &attack.Attack{Type: attack.AttackTypeMask, Mask: "?d"}}},
+			},
+			wantErr:     true,
+			wantInvalid: true,
+		},
 		{
 			name: "Test CreateHashJob with invalid owner",
 			args: args{
-				hashes: []string{"test"},
+				hashType: hashtype.HashTypeMD5,
+				hashes:   []string{testHash},
 				owner: &user.User{
 					ID:       "",
 					Username: "test",
@@ -207,20 +369,24 @@ func TestHashJobService_CreateHashJob(t *testing.T) {
 				store: &MockHashJobStore{HashJobs: hashJobStoreMap},
 				cache: &MockHashJobCache{HashJobs: hashJobCacheMap},
 			}
-			got, err := h.CreateHashJob(tt.args.hashes, tt.args.owner)
+			report, err := h.CreateHashJob(tt.args.hashType, Targets(tt.args.hashes), tt.args.owner, "", tt.args.stages, nil)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("CreateHashJob() got = %v, want error", got)
+					t.Errorf("CreateHashJob() got = %v, want error", report)
+				}
+				if errors.Is(err, ErrInvalidHashJob) != tt.wantInvalid {
+					t.Errorf("CreateHashJob() error = %v, want ErrInvalidHashJob %v", err, tt.wantInvalid)
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("CreateHashJob() error = %v", err)
+				t.Fatalf("CreateHashJob() error = %v", err)
 			}
 
-			if got == "" && !tt.wantErr {
+			got := report.ID
+			if got == "" {
 				t.Errorf("CreateHashJob() got = %v, want not empty", got)
 			}
 
@@ -231,6 +397,14 @@ func TestHashJobService_CreateHashJob(t *testing.T) {
 				Status:  HashJobStatusPending,
 				Hashes:  tt.args.hashes,
 			})
+			if len(hashJobStoreMap[got].Stages) != len(tt.args.stages) {
+				t.Errorf("CreateHashJob() stages = %v, want %v", hashJobStoreMap[got].Stages, tt.args.stages)
+			}
+			for i, stage := range hashJobStoreMap[got].Stages {
+				if stage.Status != StageStatusPending {
+					t.Errorf("CreateHashJob() stage %d status = %v, want %v", i, stage.Status, StageStatusPending)
+				}
+			}
 
 			// Check Cache
 			checkJobInMap(t, hashJobCacheMap, got, hashJobStoreMap[got])
@@ -238,11 +412,96 @@ func TestHashJobService_CreateHashJob(t *testing.T) {
 	}
 }
 
+func TestHashJobService_CreateHashJob_Lookup(t *testing.T) {
+	testOwner := &user.User{
+		ID:       "test",
+		Username: "test",
+		Email:    "test@example.com",
+		Role:     user.RoleAnalyst,
+	}
+
+	passwordDigest, _ := hashtype.HashTypeMD5.Hash("password")
+	dragonDigest, _ := hashtype.HashTypeMD5.Hash("dragon")
+	md5Lookup := &MockPlaintextLookup{
+		Type: hashtype.HashTypeMD5,
+		Plaintexts: map[string]string{
+			string(passwordDigest): "password",
+			string(dragonDigest):   "dragon",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		hashType    hashtype.HashType
+		hashes      []string
+		wantCracked map[string]string
+		wantStatus  HashJobStatus
+	}{
+		{
+			name:     "Test CreateHashJob resolves every hash",
+			hashType: hashtype.HashTypeMD5,
+			hashes:   []string{"5f4dcc3b5aa765d61d8327deb882cf99", "8621FFDBC5698829397D97767AC13DB3"},
+			wantCracked: map[string]string{
+				"5f4dcc3b5aa765d61d8327deb882cf99": "password",
+				"8621ffdbc5698829397d97767ac13db3": "dragon",
+			},
+			wantStatus: HashJobStatusDone,
+		},
+		{
+			name:     "Test CreateHashJob resolves some hashes",
+			hashType: hashtype.HashTypeMD5,
+			hashes:   []string{"5f4dcc3b5aa765d61d8327deb882cf99", "00000000000000000000000000000000", "not hex"},
+			wantCracked: map[string]string{
+				"5f4dcc3b5aa765d61d8327deb882cf99": "password",
+			},
+			wantStatus: HashJobStatusPending,
+		},
+		{
+			name:        "Test CreateHashJob skips lookups of other hash types",
+			hashType:    hashtype.HashTypeNTLM,
+			hashes:      []string{"5f4dcc3b5aa765d61d8327deb882cf99"},
+			wantCracked: map[string]string{},
+			wantStatus:  HashJobStatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeMap := make(map[string]HashJob)
+			h := NewHashJobService(
+				&MockHashJobStore{HashJobs: storeMap},
+				&MockHashJobCache{HashJobs: make(map[string]HashJob)},
+				nil,
+				md5Lookup,
+			)
+
+			report, err := h.CreateHashJob(tt.hashType, Targets(tt.hashes), testOwner, "", nil, nil)
+			if err != nil {
+				t.Fatalf("CreateHashJob() error = %v", err)
+			}
+
+			hj := storeMap[report.ID]
+			if hj.Status != tt.wantStatus {
+				t.Errorf("CreateHashJob() status = %v, want %v", hj.Status, tt.wantStatus)
+			}
+			if len(hj.Cracked) != len(tt.wantCracked) {
+				t.Errorf("CreateHashJob() cracked = %v, want %v", hj.Cracked, tt.wantCracked)
+			}
+			for hash, plain := range tt.wantCracked {
+				if hj.Cracked[hash] != plain {
+					t.Errorf("CreateHashJob() cracked[%v] = %v, want %v", hash, hj.Cracked[hash], plain)
+				}
+			}
+		})
+	}
+}
+
 func TestHashJobService_GetHashJob(t *testing.T) {
 	testOwner := &user.User{
 		ID:       "test",
 		Username: "test",
-		Email:    "test",
+		Email:    "test@example.com",
+		Role:     user.RoleAnalyst,
 	}
 
 	type args struct {
@@ -362,7 +621,8 @@ func TestHashJobService_DeleteHashJob(t *testing.T) {
 	testOwner := &user.User{
 		ID:       "test",
 		Username: "test",
-		Email:    "test",
+		Email:    "test@example.com",
+		Role:     user.RoleAnalyst,
 	}
 
 	type args struct {
@@ -390,6 +650,23 @@ func TestHashJobService_DeleteHashJob(t *testing.T) {
 				})
 			},
 		},
+		{
+			name: "Test DeleteHashJob cached",
+			args: args{
+				id: "test",
+			},
+			wantErr: false,
+			before: func(h *HashJobService) {
+				hj := HashJob{
+					ID:      "test",
+					OwnerId: testOwner.ID,
+					Status:  HashJobStatusPending,
+					Hashes:  []string{"test"},
+				}
+				h.store.InsertHashJob(hj)
+				h.cache.SetHashJob(hj)
+			},
+		},
 		{
 			name: "Test DeleteHashJob with no job",
 			args: args{
@@ -435,11 +712,537 @@ func TestHashJobService_DeleteHashJob(t *testing.T) {
 				t.Errorf("DeleteHashJob() cacheMap = %v, want empty", cacheMap)
 			}
 
-			_, err = h.store.GetHashJob(tt.args.id)
-			if err == nil {
-				t.Errorf("DeleteHashJob() got = %v, want error", err)
+			_, err = h.GetHashJob(tt.args.id)
+			if !errors.Is(err, &uerr.ErrorNotFound{}) {
+				t.Errorf("GetHashJob() after DeleteHashJob() error = %v, want not found", err)
+			}
+
+		})
+	}
+}
+
+func TestHashJobService_ClaimHashJob(t *testing.T) {
+	storeMap := map[string]HashJob{
+		"a": {ID: "a", OwnerId: "test", Status: HashJobStatusDone},
+		"b": {ID: "b", OwnerId: "test", Status: HashJobStatusPending},
+		"c": {ID: "c", OwnerId: "test", Status: HashJobStatusPending},
+	}
+	cacheMap := make(map[string]HashJob)
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: cacheMap}, nil)
+
+	for _, want := range []string{"b", "c"} {
+		got, err := h.ClaimHashJob()
+		if err != nil {
+			t.Fatalf("ClaimHashJob() error = %v", err)
+		}
+		if got.ID != want || got.Status != HashJobStatusRunning {
+			t.Errorf("ClaimHashJob() got = %v, want running job %v", got, want)
+		}
+		if storeMap[want].Status != HashJobStatusRunning || cacheMap[want].Status != HashJobStatusRunning {
+			t.Errorf("ClaimHashJob() did not persist running status for %v", want)
+		}
+	}
+
+	_, err := h.ClaimHashJob()
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("ClaimHashJob() error = %v, want not found", err)
+	}
+
+	err = h.RequeueHashJobs()
+	if err != nil {
+		t.Fatalf("RequeueHashJobs() error = %v", err)
+	}
+	if storeMap["b"].Status != HashJobStatusPending || storeMap["c"].Status != HashJobStatusPending {
+		t.Errorf("RequeueHashJobs() got = %v, want running jobs pending", storeMap)
+	}
+	if storeMap["a"].Status != HashJobStatusDone {
+		t.Errorf("RequeueHashJobs() changed done job to %v", storeMap["a"].Status)
+	}
+}
+
+func TestHashJobService_ResourceInUse(t *testing.T) {
+	dictionary := func(wordlist string) Stage {
+		return Stage{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{wordlist}, Rules: "rules"}}
+	}
+	storeMap := map[string]HashJob{
+		"pending": {ID: "pending", Status: HashJobStatusPending, Stages: []Stage{{Type: StageTypePotfile}, dictionary("queued")}},
+		"running": {ID: "running", Status: HashJobStatusRunning, Stage: 1, Stages: []Stage{dictionary("ran"), dictionary("running")}},
+		"done":    {ID: "done", Status: HashJobStatusDone, Stage: 1, Stages: []Stage{dictionary("finished")}},
+	}
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"queued", true},
+		{"running", true},
+		{"rules", true},
+		{"ran", false},
+		{"finished", false},
+		{"unused", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := h.ResourceInUse(tt.id)
+			if err != nil {
+				t.Fatalf("ResourceInUse() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResourceInUse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashJobService_CreateHashJob_Budget(t *testing.T) {
+	testOwner := &user.User{ID: "test", Username: "test", Email: "test@example.com", Role: user.RoleAnalyst}
+	storeMap := make(map[string]HashJob)
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	report, err := h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash}), testOwner, "", nil, &Budget{MaxRuntimeSeconds: 60, MaxCandidates: 1000})
+	if err != nil {
+		t.Fatalf("CreateHashJob() error = %v", err)
+	}
+	id := report.ID
+	if storeMap[id].Budget.MaxRuntime() != time.Minute || storeMap[id].Budget.MaxCandidates != 1000 {
+		t.Errorf("CreateHashJob() budget = %v, want 60s and 1000 candidates", storeMap[id].Budget)
+	}
+
+	_, err = h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash}), testOwner, "", nil, &Budget{MaxRuntimeSeconds: -1})
+	if !errors.Is(err, ErrInvalidHashJob) {
+		t.Errorf("CreateHashJob() with negative runtime error = %v, want %v", err, ErrInvalidHashJob)
+	}
+}
+
+func TestHashJobService_AddHashes(t *testing.T) {
+	md5Empty := "d41d8cd98f00b204e9800998ecf8427e"
+	md5A := "0cc175b9c0f1b6a831c399e269772661"
+	md5B := "92eb5ffee6ae2fec3ad71c777531578f"
+
+	tests := []struct {
+		name         string
+		status       HashJobStatus
+		hashes       []string
+		wantAccepted []string
+		wantDup      int
+		wantRejected int
+		wantErr      bool
+	}{
+		{
+			name:         "Test AddHashes",
+			status:       HashJobStatusPending,
+			hashes:       []string{md5A, md5B},
+			wantAccepted: []string{md5A, md5B},
+		},
+		{
+			name:         "Test AddHashes skips duplicates",
+			status:       HashJobStatusRunning,
+			hashes:       []string{strings.ToUpper(md5Empty), md5A, "0x" + md5A},
+			wantAccepted: []string{md5A},
+			wantDup:      2,
+		},
+		{
+			name:         "Test AddHashes rejects invalid hashes",
+			status:       HashJobStatusPending,
+			hashes:       []string{md5A, "test"},
+			wantAccepted: []string{md5A},
+			wantRejected: 1,
+		},
+		{
+			name:    "Test AddHashes to finished job",
+			status:  HashJobStatusDone,
+			hashes:  []string{md5A},
+			wantErr: true,
+		},
+		{
+			name:    "Test AddHashes with no hashes",
+			status:  HashJobStatusPending,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storeMap := map[string]HashJob{
+				"test": {ID: "test", OwnerId: "test", Status: tt.status, HashType: hashtype.HashTypeMD5, Hashes: []string{md5Empty}},
+			}
+			cacheMap := make(map[string]HashJob)
+			h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: cacheMap}, nil)
+
+			report, err := h.AddHashes("test", Targets(tt.hashes))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddHashes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(storeMap["test"].Hashes) != 1 {
+					t.Errorf("AddHashes() changed hashes to %v on error", storeMap["test"].Hashes)
+				}
+				return
+			}
+
+			if len(report.Accepted) != len(tt.wantAccepted) || len(report.Duplicates) != tt.wantDup || len(report.Rejected) != tt.wantRejected {
+				t.Errorf("AddHashes() got = %+v, want %v accepted, %v duplicates, %v rejected", report, tt.wantAccepted, tt.wantDup, tt.wantRejected)
 			}
+			wantHashes := append([]string{md5Empty}, tt.wantAccepted...)
+			checkJobInMap(t, storeMap, "test", HashJob{ID: "test", OwnerId: "test", Status: tt.status, Hashes: wantHashes})
+			checkJobInMap(t, cacheMap, "test", HashJob{ID: "test", OwnerId: "test", Status: tt.status, Hashes: wantHashes})
+		})
+	}
+}
+
+func TestHashJobService_CreateHashJob_Report(t *testing.T) {
+	testOwner := &user.User{ID: "test", Username: "test", Email: "test@example.com", Role: user.RoleAnalyst}
+	storeMap := make(map[string]HashJob)
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	hashes := []string{
+		" " + strings.ToUpper(testHash) + " ",
+		testHash,
+		"0x" + testHash2,
+		"CY9rzUYh03PK3k6DJie09g==",
+		"",
+		"not a hash",
+	}
+	report, err := h.CreateHashJob(hashtype.HashTypeMD5, Targets(hashes), testOwner, "", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateHashJob() error = %v", err)
+	}
+
+	wantAccepted := []string{testHash, testHash2}
+	if len(report.Accepted) != len(wantAccepted) {
+		t.Fatalf("CreateHashJob() accepted = %v, want %v", report.Accepted, wantAccepted)
+	}
+	for i := range wantAccepted {
+		if report.Accepted[i] != wantAccepted[i] {
+			t.Errorf("CreateHashJob() accepted[%d] = %v, want %v", i, report.Accepted[i], wantAccepted[i])
+		}
+	}
+	if len(report.Duplicates) != 2 || report.Duplicates[0] != testHash || report.Duplicates[1] != "CY9rzUYh03PK3k6DJie09g==" {
+		t.Errorf("CreateHashJob() duplicates = %v, want the plain and base64 repeats", report.Duplicates)
+	}
+	if len(report.Rejected) != 2 || report.Rejected[0].Reason == "" {
+		t.Errorf("CreateHashJob() rejected = %v, want 2 with reasons", report.Rejected)
+	}
+	checkJobInMap(t, storeMap, report.ID, HashJob{ID: report.ID, OwnerId: "test", Status: HashJobStatusPending, Hashes: wantAccepted})
+
+	report, err = h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{"nope"}), testOwner, "", nil, nil)
+	if !errors.Is(err, ErrNoValidHashes) {
+		t.Errorf("CreateHashJob() error = %v, want %v", err, ErrNoValidHashes)
+	}
+	if report == nil || len(report.Rejected) != 1 || report.ID != "" {
+		t.Errorf("CreateHashJob() report = %v, want one rejected hash and no job", report)
+	}
+}
+
+func TestHashJob_AccessibleBy(t *testing.T) {
+	red := user.Team{ID: "red", Members: []user.TeamMember{{UserID: "alice", Admin: true}, {UserID: "bob"}, {UserID: "dave", Admin: true}}}
+	blue := user.Team{ID: "blue", Members: []user.TeamMember{{UserID: "bob", Admin: true}}}
+
+	tests := []struct {
+		name          string
+		hj            HashJob
+		u             user.User
+		teams         []user.Team
+		wantAccess    bool
+		wantDeletable bool
+	}{
+		{
+			name:          "Test AccessibleBy owner",
+			hj:            HashJob{OwnerId: "alice"},
+			u:             user.User{ID: "alice", Role: user.RoleViewer},
+			wantAccess:    true,
+			wantDeletable: true,
+		},
+		{
+			name: "Test AccessibleBy other analyst",
+			hj:   HashJob{OwnerId: "alice"},
+			u:    user.User{ID: "bob", Role: user.RoleAnalyst},
+		},
+		{
+			name:          "Test AccessibleBy admin",
+			hj:            HashJob{OwnerId: "alice"},
+			u:             user.User{ID: "carol", Role: user.RoleAdmin},
+			wantAccess:    true,
+			wantDeletable: true,
+		},
+		{
+			name:       "Test AccessibleBy team member",
+			hj:         HashJob{OwnerId: "alice", TeamId: "red"},
+			u:          user.User{ID: "bob", Role: user.RoleAnalyst},
+			teams:      []user.Team{red, blue},
+			wantAccess: true,
+		},
+		{
+			name:          "Test AccessibleBy team admin",
+			hj:            HashJob{OwnerId: "alice", TeamId: "red"},
+			u:             user.User{ID: "dave", Role: user.RoleViewer},
+			teams:         []user.Team{red},
+			wantAccess:    true,
+			wantDeletable: true,
+		},
+		{
+			name:  "Test AccessibleBy admin of another team",
+			hj:    HashJob{OwnerId: "alice", TeamId: "red"},
+			u:     user.User{ID: "bob", Role: user.RoleAnalyst},
+			teams: []user.Team{blue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hj.AccessibleBy(&tt.u, tt.teams); got != tt.wantAccess {
+				t.Errorf("AccessibleBy() got = %v, want %v", got, tt.wantAccess)
+			}
+			if got := tt.hj.DeletableBy(&tt.u, tt.teams); got != tt.wantDeletable {
+				t.Errorf("DeletableBy() got = %v, want %v", got, tt.wantDeletable)
+			}
+		})
+	}
+}
+
+func TestHashJob_RemainingKeyspace(t *testing.T) {
+	lines := func(id string) (uint64, error) {
+		if id != "words" {
+			return 0, &uerr.ErrorNotFound{}
+		}
+		return 10, nil
+	}
+	mask := Stage{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeMask, Mask: "?d?d"}}
+	dictionary := Stage{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"words"}}}
+	running := Stage{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypePrince, Wordlists: []string{"words"}, MinLength: 1, MaxLength: 2}, Status: StageStatusRunning, Keyspace: 500, Position: 200}
+
+	tests := []struct {
+		name    string
+		hj      HashJob
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name: "Test RemainingKeyspace pending",
+			hj:   HashJob{Stages: []Stage{{Type: StageTypePotfile}, mask, dictionary}},
+			want: 110,
+		},
+		{
+			name: "Test RemainingKeyspace running",
+			hj:   HashJob{Stages: []Stage{{Type: StageTypePotfile, Status: StageStatusDone}, running, mask}, Stage: 1},
+			want: 400,
+		},
+		{
+			name: "Test RemainingKeyspace budget",
+			hj:   HashJob{Stages: []Stage{running, mask}, Budget: &Budget{MaxCandidates: 250}, Stats: crack.Stats{Candidates: 200}},
+			want: 50,
+		},
+		{
+			name:    "Test RemainingKeyspace unknown",
+			hj:      HashJob{Stages: []Stage{{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypePrince, Wordlists: []string{"words"}, MinLength: 1, MaxLength: 2}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Test RemainingKeyspace missing wordlist",
+			hj:      HashJob{Stages: []Stage{{Type: StageTypeAttack, Attack: &attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"gone"}}}}},
+			wantErr: true,
+		},
+	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.hj.RemainingKeyspace(lines)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RemainingKeyspace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RemainingKeyspace() got = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
+
+func TestHashJobService_ListHashJobs(t *testing.T) {
+	storeMap := map[string]HashJob{
+		"a": {ID: "a", OwnerId: "alice"},
+		"b": {ID: "b", OwnerId: "bob", TeamId: "red"},
+		"c": {ID: "c", OwnerId: "bob"},
+		"d": {ID: "d", OwnerId: "carol", TeamId: "blue"},
+	}
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	jobs, err := h.ListHashJobs(&user.User{ID: "alice"}, []user.Team{{ID: "red"}})
+	if err != nil {
+		t.Fatalf("ListHashJobs() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "b" {
+		t.Errorf("ListHashJobs() got = %v, want jobs a and b", jobs)
+	}
+}
+
+func TestHashJobService_Labels(t *testing.T) {
+	testOwner := &user.User{ID: "test", Username: "test", Email: "test@example.com", Role: user.RoleAnalyst}
+	storeMap := make(map[string]HashJob)
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	report, err := h.CreateHashJob(hashtype.HashTypeMD5, []Target{
+		{Hash: testHash, Label: "alice"},
+		{Hash: strings.ToUpper(testHash), Label: "bob"},
+		{Hash: testHash2},
+	}, testOwner, "", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateHashJob() error = %v", err)
+	}
+
+	_, err = h.AddHashes(report.ID, []Target{{Hash: testHash, Label: "alice"}, {Hash: testHash2, Label: "carol"}})
+	if err != nil {
+		t.Fatalf("AddHashes() error = %v", err)
+	}
+
+	labels := storeMap[report.ID].Labels
+	if len(labels) != 2 || !slices.Equal(labels[testHash], []string{"alice", "bob"}) || !slices.Equal(labels[testHash2], []string{"carol"}) {
+		t.Errorf("Labels got = %v, want alice and bob on %v and carol on %v", labels, testHash, testHash2)
+	}
+}
+
+func TestHashJobService_UpdateHashJob_KeepsAppendedHashes(t *testing.T) {
+	storeMap := map[string]HashJob{
+		"test": {ID: "test", OwnerId: "test", Status: HashJobStatusRunning, HashType: hashtype.HashTypeMD5, Hashes: []string{"a"}},
+	}
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, nil)
+
+	held := storeMap["test"]
+	stored := storeMap["test"]
+	stored.Hashes = []string{"a", "b"}
+	stored.Cracked = map[string]string{"b": "plain"}
+	stored.Labels = map[string][]string{"b": {"bob"}}
+	storeMap["test"] = stored
+
+	held.Status = HashJobStatusDone
+	err := h.UpdateHashJob(held)
+	if err != nil {
+		t.Fatalf("UpdateHashJob() error = %v", err)
+	}
+
+	got := storeMap["test"]
+	if got.Status != HashJobStatusDone {
+		t.Errorf("UpdateHashJob() status = %v, want %v", got.Status, HashJobStatusDone)
+	}
+	if len(got.Hashes) != 2 || got.Cracked["b"] != "plain" || !slices.Equal(got.Labels["b"], []string{"bob"}) {
+		t.Errorf("UpdateHashJob() got = %v, want appended hash, label and plaintext kept", got)
+	}
+
+	err = h.UpdateHashJob(HashJob{ID: "missing"})
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("UpdateHashJob() error = %v, want not found", err)
+	}
+}
+
+var errTestQuota = errors.New("quota exceeded")
+
+type MockQuotaChecker struct {
+	MaxHashes int
+	MaxJobs   int
+}
+
+func (m *MockQuotaChecker) CheckHashes(userId, teamId string, n int) error {
+	if n > m.MaxHashes {
+		return errTestQuota
+	}
+	return nil
+}
+
+func (m *MockQuotaChecker) CheckConcurrency(userId, teamId string, activeUser, activeTeam int) error {
+	if activeUser >= m.MaxJobs || teamId != "" && activeTeam >= m.MaxJobs {
+		return errTestQuota
+	}
+	return nil
+}
+
+func TestHashJobService_Quotas(t *testing.T) {
+	testOwner := &user.User{ID: "test", Username: "test", Email: "test@example.com", Role: user.RoleAnalyst}
+	storeMap := map[string]HashJob{
+		"done":    {ID: "done", OwnerId: "test", Status: HashJobStatusDone},
+		"running": {ID: "running", OwnerId: "other", TeamId: "red", Status: HashJobStatusRunning},
+	}
+	h := NewHashJobService(&MockHashJobStore{HashJobs: storeMap}, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, &MockQuotaChecker{MaxHashes: 2, MaxJobs: 1})
+
+	_, err := h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash, testHash2, strings.Repeat("0", 32)}), testOwner, "", nil, nil)
+	if !errors.Is(err, errTestQuota) {
+		t.Errorf("CreateHashJob() over hash quota error = %v, want %v", err, errTestQuota)
+	}
+
+	_, err = h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash}), testOwner, "red", nil, nil)
+	if !errors.Is(err, errTestQuota) {
+		t.Errorf("CreateHashJob() over team job quota error = %v, want %v", err, errTestQuota)
+	}
+
+	report, err := h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash}), testOwner, "", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateHashJob() error = %v", err)
+	}
+
+	_, err = h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash2}), testOwner, "", nil, nil)
+	if !errors.Is(err, errTestQuota) {
+		t.Errorf("CreateHashJob() over job quota error = %v, want %v", err, errTestQuota)
+	}
+
+	_, err = h.AddHashes(report.ID, Targets([]string{testHash2, strings.Repeat("0", 32)}))
+	if !errors.Is(err, errTestQuota) {
+		t.Errorf("AddHashes() over hash quota error = %v, want %v", err, errTestQuota)
+	}
+	if _, err = h.AddHashes(report.ID, Targets([]string{testHash2})); err != nil {
+		t.Errorf("AddHashes() within hash quota error = %v", err)
+	}
+}
+
+// MockLockedHashJobStore serializes access to a MockHashJobStore and slows
+// down listing, so quota checks of concurrent submissions would overlap.
+
+type MockLockedHashJobStore struct {
+	*MockHashJobStore
+	mu sync.Mutex
+}
+
+func (m *MockLockedHashJobStore) InsertHashJob(h HashJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.MockHashJobStore.InsertHashJob(h)
+}
+
+func (m *MockLockedHashJobStore) ListHashJobsByOwner(ownerId string, teamIds []string) ([]HashJob, error) {
+	m.mu.Lock()
+	jobs, err := m.MockHashJobStore.ListHashJobsByOwner(ownerId, teamIds)
+	m.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	return jobs, err
+}
+
+func TestHashJobService_Quotas_Concurrent(t *testing.T) {
+	testOwner := &user.User{ID: "test", Username: "test", Email: "test@example.com", Role: user.RoleAnalyst}
+	storeMap := make(map[string]HashJob)
+	store := &MockLockedHashJobStore{MockHashJobStore: &MockHashJobStore{HashJobs: storeMap}}
+	h := NewHashJobService(store, &MockHashJobCache{HashJobs: make(map[string]HashJob)}, &MockQuotaChecker{MaxHashes: 1, MaxJobs: 3})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.CreateHashJob(hashtype.HashTypeMD5, Targets([]string{testHash}), testOwner, "", nil, nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, errTestQuota):
+			t.Errorf("CreateHashJob() error = %v, want nil or %v", err, errTestQuota)
+		}
+	}
+	if created != 3 || len(storeMap) != 3 {
+		t.Errorf("CreateHashJob() created %d jobs, stored %d, want 3", created, len(storeMap))
+	}
+}
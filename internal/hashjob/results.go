@@ -0,0 +1,136 @@
+package hashjob
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/fmdunlap/unhash/internal/potfile"
+)
+
+type ResultFormat string
+
+const (
+	ResultFormatPot   ResultFormat = "pot"
+	ResultFormatCSV   ResultFormat = "csv"
+	ResultFormatJSONL ResultFormat = "jsonl"
+	ResultFormatJohn  ResultFormat = "john"
+)
+
+func (f ResultFormat) Validate() error {
+	switch f {
+	case ResultFormatPot, ResultFormatCSV, ResultFormatJSONL, ResultFormatJohn:
+		return nil
+	default:
+		return fmt.Errorf("unknown result format: %v", f)
+	}
+}
+
+// ContentType is the media type results in format f are served as.
+func (f ResultFormat) ContentType() string {
+	switch f {
+	case ResultFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ResultFormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Result is a cracked target. A hash submitted under several labels has a
+// result for each of them.
+type Result struct {
+	Label string `json:"label,omitempty"`
+	Hash  string `json:"hash"`
+	Plain string `json:"plain"`
+}
+
+// Results lists the job's cracked targets in the order their hashes were
+// submitted.
+func (hj *HashJob) Results() []Result {
+	results := make([]Result, 0, len(hj.Cracked))
+	for _, hash := range hj.Hashes {
+		plain, ok := hj.Cracked[hash]
+		if !ok {
+			continue
+		}
+
+		labels := hj.Labels[hash]
+		if len(labels) == 0 {
+			results = append(results, Result{Hash: hash, Plain: plain})
+			continue
+		}
+		for _, label := range labels {
+			results = append(results, Result{Label: label, Hash: hash, Plain: plain})
+		}
+	}
+
+	return results
+}
+
+// WriteResults writes results to w in format f. Potfiles hold `hash:plain`
+// once per hash; John's `--show` format holds `label:plain`, falling back to
+// the hash for unlabeled targets. Plaintexts hashcat and John cannot read
+// verbatim are written in `$HEX[...]` notation in both.
+func WriteResults(w io.Writer, results []Result, f ResultFormat) error {
+	switch f {
+	case ResultFormatPot:
+		bw := bufio.NewWriter(w)
+		seen := make(map[string]bool, len(results))
+		for _, r := range results {
+			if seen[r.Hash] {
+				continue
+			}
+			seen[r.Hash] = true
+
+			_, err := fmt.Fprintf(bw, "%s:%s\n", r.Hash, potfile.EncodePlain(r.Plain))
+			if err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	case ResultFormatJohn:
+		bw := bufio.NewWriter(w)
+		for _, r := range results {
+			user := r.Label
+			if user == "" {
+				user = r.Hash
+			}
+
+			_, err := fmt.Fprintf(bw, "%s:%s\n", user, potfile.EncodePlain(r.Plain))
+			if err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	case ResultFormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.Write([]string{"label", "hash", "plain"})
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			err = cw.Write([]string{r.Label, r.Hash, r.Plain})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case ResultFormatJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		for _, r := range results {
+			err := enc.Encode(r)
+			if err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	default:
+		return f.Validate()
+	}
+}
@@ -0,0 +1,62 @@
+package hashjob
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHashJob_Results(t *testing.T) {
+	hj := HashJob{
+		Hashes:  []string{testHash, testHash2, "d41d8cd98f00b204e9800998ecf8427e"},
+		Labels:  map[string][]string{testHash: {"alice", "bob"}},
+		Cracked: map[string]string{testHash: "test", testHash2: "café"},
+	}
+	results := hj.Results()
+
+	tests := []struct {
+		name   string
+		format ResultFormat
+		want   string
+	}{
+		{
+			name:   "Test WriteResults pot",
+			format: ResultFormatPot,
+			want:   testHash + ":test\n" + testHash2 + ":$HEX[636166c3a9]\n",
+		},
+		{
+			name:   "Test WriteResults john",
+			format: ResultFormatJohn,
+			want:   "alice:test\nbob:test\n" + testHash2 + ":$HEX[636166c3a9]\n",
+		},
+		{
+			name:   "Test WriteResults csv",
+			format: ResultFormatCSV,
+			want:   "label,hash,plain\nalice," + testHash + ",test\nbob," + testHash + ",test\n," + testHash2 + ",café\n",
+		},
+		{
+			name:   "Test WriteResults jsonl",
+			format: ResultFormatJSONL,
+			want: `{"label":"alice","hash":"` + testHash + `","plain":"test"}` + "\n" +
+				`{"label":"bob","hash":"` + testHash + `","plain":"test"}` + "\n" +
+				`{"hash":"` + testHash2 + `","plain":"caf` + "é" + `"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteResults(&buf, results, tt.format)
+			if err != nil {
+				t.Fatalf("WriteResults() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteResults() got = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	err := WriteResults(&bytes.Buffer{}, results, ResultFormat("xml"))
+	if err == nil {
+		t.Errorf("WriteResults() with unknown format got nil error")
+	}
+}
@@ -0,0 +1,188 @@
+package hashtype
+
+import (
+	"crypto/des"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+type HashType string
+
+const (
+	HashTypeMD5  HashType = "md5"
+	HashTypeSHA1 HashType = "sha1"
+	HashTypeNTLM HashType = "ntlm"
+	HashTypeLM   HashType = "lm"
+)
+
+const lmMagic = "KGS!@#$%"
+
+var ErrInvalidHash = errors.New("invalid hash")
+
+type algorithm struct {
+	size int
+	hash func(plain string) []byte
+}
+
+var algorithms = map[HashType]algorithm{
+	HashTypeMD5: {
+		size: md5.Size,
+		hash: func(plain string) []byte {
+			sum := md5.Sum([]byte(plain))
+			return sum[:]
+		},
+	},
+	HashTypeSHA1: {
+		size: sha1.Size,
+		hash: func(plain string) []byte {
+			sum := sha1.Sum([]byte(plain))
+			return sum[:]
+		},
+	},
+	HashTypeNTLM: {
+		size: md4.Size,
+		hash: ntlm,
+	},
+	HashTypeLM: {
+		size: 2 * des.BlockSize,
+		hash: lm,
+	},
+}
+
+// Registered returns every supported hash type in a stable order.
+func Registered() []HashType {
+	types := make([]HashType, 0, len(algorithms))
+	for t := range algorithms {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
+
+func (t HashType) Validate() error {
+	if _, ok := algorithms[t]; !ok {
+		return fmt.Errorf("unknown hash type `%v`", t)
+	}
+	return nil
+}
+
+// Size returns the length of a raw digest of this type in bytes.
+func (t HashType) Size() int {
+	return algorithms[t].size
+}
+
+// Decode returns the raw digest of a hex encoded hash of this type.
+func (t HashType) Decode(hash string) ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	digest, err := hex.DecodeString(strings.ToLower(hash))
+	if err != nil || len(digest) != t.Size() {
+		return nil, fmt.Errorf("%w: `%v` is not a %v hash", ErrInvalidHash, hash, t)
+	}
+
+	return digest, nil
+}
+
+// Normalize returns the canonical lowercase hex form of a hash of this type.
+// Surrounding whitespace and a `0x` prefix are ignored, and digests encoded
+// as base64 are converted to hex.
+func (t HashType) Normalize(hash string) (string, error) {
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+
+	h := strings.TrimSpace(hash)
+	if h == "" {
+		return "", fmt.Errorf("%w: hash is empty", ErrInvalidHash)
+	}
+
+	hexHash := h
+	if len(hexHash) > 2 && (hexHash[:2] == "0x" || hexHash[:2] == "0X") {
+		hexHash = hexHash[2:]
+	}
+	if digest, err := hex.DecodeString(hexHash); err == nil && len(digest) == t.Size() {
+		return strings.ToLower(hexHash), nil
+	}
+
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if digest, err := enc.DecodeString(h); err == nil && len(digest) == t.Size() {
+			return hex.EncodeToString(digest), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: expected %d hex characters or base64 of %d bytes for %v", ErrInvalidHash, 2*t.Size(), t.Size(), t)
+}
+
+func (t HashType) Hash(plain string) ([]byte, error) {
+	a, ok := algorithms[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash type `%v`", t)
+	}
+
+	return a.hash(plain), nil
+}
+
+func ntlm(plain string) []byte {
+	encoded := utf16.Encode([]rune(plain))
+
+	buf := make([]byte, 0, len(encoded)*2)
+	for _, c := range encoded {
+		buf = append(buf, byte(c), byte(c>>8))
+	}
+
+	h := md4.New()
+	h.Write(buf)
+	return h.Sum(nil)
+}
+
+// lm computes the LAN Manager hash, which uppercases the password, pads or
+// truncates it to 14 bytes and uses each 7 byte half as a DES key.
+func lm(plain string) []byte {
+	padded := make([]byte, 14)
+	copy(padded, strings.ToUpper(plain))
+
+	sum := make([]byte, 0, 2*des.BlockSize)
+	for _, half := range [][]byte{padded[:7], padded[7:]} {
+		block, err := des.NewCipher(lmKey(half))
+		if err != nil {
+			panic(err)
+		}
+
+		out := make([]byte, des.BlockSize)
+		block.Encrypt(out, []byte(lmMagic))
+		sum = append(sum, out...)
+	}
+
+	return sum
+}
+
+// lmKey spreads 56 key bits over 8 bytes, leaving the DES parity bits unset.
+func lmKey(half []byte) []byte {
+	key := []byte{
+		half[0] >> 1,
+		(half[0]&0x01)<<6 | half[1]>>2,
+		(half[1]&0x03)<<5 | half[2]>>3,
+		(half[2]&0x07)<<4 | half[3]>>4,
+		(half[3]&0x0f)<<3 | half[4]>>5,
+		(half[4]&0x1f)<<2 | half[5]>>6,
+		(half[5]&0x3f)<<1 | half[6]>>7,
+		half[6] & 0x7f,
+	}
+	for i := range key {
+		key[i] <<= 1
+	}
+
+	return key
+}
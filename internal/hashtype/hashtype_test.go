@@ -0,0 +1,122 @@
+package hashtype
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashType_Hash(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType HashType
+		plain    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Test md5", hashType: HashTypeMD5, plain: "password", want: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{name: "Test sha1", hashType: HashTypeSHA1, plain: "password", want: "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"},
+		{name: "Test ntlm", hashType: HashTypeNTLM, plain: "password", want: "8846f7eaee8fb117ad06bdd830b7586c"},
+		{name: "Test ntlm empty", hashType: HashTypeNTLM, plain: "", want: "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{name: "Test lm", hashType: HashTypeLM, plain: "password", want: "e52cac67419a9a224a3b108f3fa6cb6d"},
+		{name: "Test lm empty", hashType: HashTypeLM, plain: "", want: "aad3b435b51404eeaad3b435b51404ee"},
+		{name: "Test unknown", hashType: "bogus", plain: "password", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.hashType.Hash(tt.plain)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Hash() got = %x, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Hash() got = %x, want %v", got, tt.want)
+			}
+			if len(got) != tt.hashType.Size() {
+				t.Errorf("Size() got = %v, want %v", tt.hashType.Size(), len(got))
+			}
+		})
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	got := Registered()
+	want := []HashType{HashTypeLM, HashTypeMD5, HashTypeNTLM, HashTypeSHA1}
+
+	if len(got) != len(want) {
+		t.Fatalf("Registered() got = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Registered() got = %v, want %v", got, want)
+		}
+		if err := got[i].Validate(); err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	}
+}
+
+func TestHashType_Decode(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType HashType
+		hash     string
+		wantErr  bool
+	}{
+		{name: "Test Decode md5", hashType: HashTypeMD5, hash: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Decode uppercase", hashType: HashTypeMD5, hash: "D41D8CD98F00B204E9800998ECF8427E"},
+		{name: "Test Decode wrong size", hashType: HashTypeSHA1, hash: "d41d8cd98f00b204e9800998ecf8427e", wantErr: true},
+		{name: "Test Decode not hex", hashType: HashTypeMD5, hash: "test", wantErr: true},
+		{name: "Test Decode unknown type", hashType: "bogus", hash: "d41d8cd98f00b204e9800998ecf8427e", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.hashType.Decode(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != tt.hashType.Size() {
+				t.Errorf("Decode() got %d bytes, want %d", len(got), tt.hashType.Size())
+			}
+		})
+	}
+}
+
+func TestHashType_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType HashType
+		hash     string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Test Normalize canonical", hashType: HashTypeMD5, hash: "d41d8cd98f00b204e9800998ecf8427e", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Normalize uppercase and whitespace", hashType: HashTypeMD5, hash: "  D41D8CD98F00B204E9800998ECF8427E\t", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Normalize 0x prefix", hashType: HashTypeMD5, hash: "0xD41D8CD98F00B204E9800998ECF8427E", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Normalize base64", hashType: HashTypeMD5, hash: "1B2M2Y8AsgTpgAmY7PhCfg==", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Normalize unpadded base64", hashType: HashTypeMD5, hash: "1B2M2Y8AsgTpgAmY7PhCfg", want: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "Test Normalize sha1 base64", hashType: HashTypeSHA1, hash: "2jmj7l5rSw0yVb/vlWAYkK/YBwk=", want: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{name: "Test Normalize empty", hashType: HashTypeMD5, hash: "  ", wantErr: true},
+		{name: "Test Normalize wrong size", hashType: HashTypeSHA1, hash: "d41d8cd98f00b204e9800998ecf8427e", wantErr: true},
+		{name: "Test Normalize garbage", hashType: HashTypeMD5, hash: "not a hash", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.hashType.Normalize(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/fmdunlap/unhash/internal/wordlist"
+	"github.com/google/uuid"
+)
+
+type ResourceKind string
+
+const (
+	ResourceKindWordlist ResourceKind = "wordlist"
+	ResourceKindRule     ResourceKind = "rule"
+)
+
+// Resource is a wordlist or rule file in the library. Its contents are stored
+// by SHA-256, so several resources can share the same file on disk.
+type Resource struct {
+	ID          string               `json:"id"`
+	Kind        ResourceKind         `json:"kind"`
+	Name        string               `json:"name"`
+	OwnerId     string               `json:"ownerId"`
+	Shared      bool                 `json:"shared"`
+	SHA256      string               `json:"sha256"`
+	Size        int64                `json:"size"`
+	Lines       uint64               `json:"lines"`
+	Compression wordlist.Compression `json:"compression"`
+	CreatedAt   time.Time            `json:"createdAt"`
+}
+
+type ResourceStore interface {
+	InsertResource(r Resource) error
+	GetResource(id string) (*Resource, error)
+	ListResources(kind ResourceKind, ownerId string) ([]Resource, error)
+	DeleteResource(id string) error
+	CountResourcesBySHA256(sha string) (int, error)
+}
+
+// ResourceUsage reports whether hash jobs that have yet to finish reference
+// a resource, implemented by hashjob.HashJobService.
+type ResourceUsage interface {
+	ResourceInUse(id string) (bool, error)
+}
+
+var (
+	ErrResourceInUse   = errors.New("resource is used by a pending or running hash job")
+	ErrInvalidResource = errors.New("invalid resource")
+)
+
+type LibraryService struct {
+	store   ResourceStore
+	storage *DiskStorage
+	usage   ResourceUsage
+
+	// mu is held while files are stored or removed together with the
+	// resources referring to them, so a file is never removed as another
+	// resource with the same contents is added.
+	mu sync.Mutex
+}
+
+// NewLibraryService returns a service keeping resources in s and d. usage
+// may be nil, in which case resources can be deleted while jobs use them.
+func NewLibraryService(s ResourceStore, d *DiskStorage, usage ResourceUsage) *LibraryService {
+	return &LibraryService{store: s, storage: d, usage: usage}
+}
+
+func Unmarshal(data []byte) (*Resource, error) {
+	var r Resource
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling resource: %w", err)
+	}
+
+	return &r, nil
+}
+
+func (k ResourceKind) Validate() error {
+	switch k {
+	case ResourceKindWordlist, ResourceKindRule:
+		return nil
+	default:
+		return fmt.Errorf("unknown resource kind `%v`", k)
+	}
+}
+
+// VisibleTo reports whether u may use the resource.
+func (r *Resource) VisibleTo(u *user.User) bool {
+	return r.Shared || (u != nil && r.OwnerId == u.ID)
+}
+
+func (l *LibraryService) AddResource(kind ResourceKind, name string, owner *user.User, shared bool, content io.Reader) (*Resource, error) {
+	if err := kind.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResource, err)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidResource)
+	}
+	if owner == nil {
+		return nil, errors.New("owner cannot be nil")
+	}
+	if owner.Validate() != nil {
+		return nil, fmt.Errorf("invalid owner: %w", owner.Validate())
+	}
+
+	b, err := l.storage.Put(content)
+	if err != nil {
+		return nil, err
+	}
+	defer l.storage.Discard(b)
+
+	r := Resource{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		Name:        name,
+		OwnerId:     owner.ID,
+		Shared:      shared,
+		SHA256:      b.SHA256,
+		Size:        b.Size,
+		Lines:       b.Lines,
+		Compression: b.Compression,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = l.storage.Commit(b)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.store.InsertResource(r)
+	if err != nil {
+		l.removeUnreferenced(b.SHA256)
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// GetResource returns the resource if it is visible to u. Resources of other
+// users are reported as not found.
+func (l *LibraryService) GetResource(kind ResourceKind, id string, u *user.User) (*Resource, error) {
+	r, err := l.store.GetResource(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("%v not found: %w", kind, err)
+		}
+		return nil, err
+	}
+
+	if r.Kind != kind || !r.VisibleTo(u) {
+		return nil, fmt.Errorf("%v not found: %w", kind, &uerr.ErrorNotFound{Err: errors.New("resource not visible")})
+	}
+
+	return r, nil
+}
+
+// ListResources returns the resources of kind owned by u plus every shared
+// one. A nil user only sees shared resources.
+func (l *LibraryService) ListResources(kind ResourceKind, u *user.User) ([]Resource, error) {
+	if err := kind.Validate(); err != nil {
+		return nil, err
+	}
+
+	ownerId := ""
+	if u != nil {
+		ownerId = u.ID
+	}
+
+	resources, err := l.store.ListResources(kind, ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("error listing resources: %w", err)
+	}
+
+	return resources, nil
+}
+
+// DeleteResource removes a resource owned by u, and its file once no other
+// resource refers to the same contents. Resources that jobs have yet to use
+// cannot be deleted.
+func (l *LibraryService) DeleteResource(kind ResourceKind, id string, u *user.User) error {
+	r, err := l.GetResource(kind, id, u)
+	if err != nil {
+		return err
+	}
+	if u == nil || r.OwnerId != u.ID {
+		return fmt.Errorf("%v not found: %w", kind, &uerr.ErrorNotFound{Err: errors.New("resource not owned by user")})
+	}
+
+	if l.usage != nil {
+		inUse, err := l.usage.ResourceInUse(id)
+		if err != nil {
+			return fmt.Errorf("error checking whether %v is in use: %w", kind, err)
+		}
+		if inUse {
+			return ErrResourceInUse
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = l.store.DeleteResource(id)
+	if err != nil {
+		return err
+	}
+
+	l.removeUnreferenced(r.SHA256)
+
+	return nil
+}
+
+// Path returns where the contents of a resource visible to u are stored.
+func (l *LibraryService) Path(kind ResourceKind, id string, u *user.User) (string, error) {
+	r, err := l.GetResource(kind, id, u)
+	if err != nil {
+		return "", err
+	}
+
+	return l.storage.Path(r.SHA256), nil
+}
+
+// Locate returns a resource and where its contents are stored regardless of
+// who it is visible to. It is meant for the worker, whose jobs had their
+// resources checked for visibility when they were created.
+func (l *LibraryService) Locate(kind ResourceKind, id string) (*Resource, string, error) {
+	r, err := l.store.GetResource(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, "", fmt.Errorf("%v not found: %w", kind, err)
+		}
+		return nil, "", err
+	}
+	if r.Kind != kind {
+		return nil, "", fmt.Errorf("%v not found: %w", kind, &uerr.ErrorNotFound{Err: errors.New("resource is of another kind")})
+	}
+
+	return r, l.storage.Path(r.SHA256), nil
+}
+
+// removeUnreferenced removes the file with sha once no resource refers to
+// it. It must be called with l.mu held.
+func (l *LibraryService) removeUnreferenced(sha string) {
+	count, err := l.store.CountResourcesBySHA256(sha)
+	if err != nil {
+		log.Printf("Error counting references to %v, keeping file: %v", sha, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	err = l.storage.Remove(sha)
+	if err != nil {
+		log.Printf("Error removing unreferenced file %v: %v", sha, err)
+	}
+}
@@ -0,0 +1,392 @@
+package library
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+// MockResourceStore implements ResourceStore
+
+type MockResourceStore struct {
+	Resources map[string]Resource
+}
+
+func (m *MockResourceStore) InsertResource(r Resource) error {
+	m.Resources[r.ID] = r
+	return nil
+}
+
+func (m *MockResourceStore) GetResource(id string) (*Resource, error) {
+	r, ok := m.Resources[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{}
+	}
+	return &r, nil
+}
+
+func (m *MockResourceStore) ListResources(kind ResourceKind, ownerId string) ([]Resource, error) {
+	resources := make([]Resource, 0)
+	for _, r := range m.Resources {
+		if r.Kind == kind && (r.OwnerId == ownerId || r.Shared) {
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+func (m *MockResourceStore) DeleteResource(id string) error {
+	delete(m.Resources, id)
+	return nil
+}
+
+func (m *MockResourceStore) CountResourcesBySHA256(sha string) (int, error) {
+	count := 0
+	for _, r := range m.Resources {
+		if r.SHA256 == sha {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MockResourceUsage implements ResourceUsage
+
+type MockResourceUsage struct {
+	InUse map[string]bool
+}
+
+func (m *MockResourceUsage) ResourceInUse(id string) (bool, error) {
+	return m.InUse[id], nil
+}
+
+// MockCountHookResourceStore implements ResourceStore, calling OnCount after
+// references are counted
+
+type MockCountHookResourceStore struct {
+	*MockResourceStore
+	OnCount func()
+}
+
+func (m *MockCountHookResourceStore) CountResourcesBySHA256(sha string) (int, error) {
+	count, err := m.MockResourceStore.CountResourcesBySHA256(sha)
+	if m.OnCount != nil {
+		m.OnCount()
+	}
+	return count, err
+}
+
+func newTestService(t *testing.T) (*LibraryService, map[string]Resource) {
+	storage, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStorage() error = %v", err)
+	}
+
+	resources := make(map[string]Resource)
+	return NewLibraryService(&MockResourceStore{Resources: resources}, storage, nil), resources
+}
+
+func gzipString(s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.String()
+}
+
+var (
+	alice = &user.User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: user.RoleAnalyst}
+	bob   = &user.User{ID: "bob", Username: "bob", Email: "bob@example.com", Role: user.RoleAnalyst}
+)
+
+func TestLibraryService_AddResource(t *testing.T) {
+	tests := []struct {
+		name            string
+		kind            ResourceKind
+		resName         string
+		owner           *user.User
+		content         string
+		wantLines       uint64
+		wantCompression wordlist.Compression
+		wantErr         bool
+		wantInvalid     bool
+	}{
+		{
+			name:      "Test AddResource wordlist",
+			kind:      ResourceKindWordlist,
+			resName:   "rockyou.txt",
+			owner:     alice,
+			content:   "password\n123456\nletmein\n",
+			wantLines: 3,
+		},
+		{
+			name:            "Test AddResource gzip wordlist",
+			kind:            ResourceKindWordlist,
+			resName:         "rockyou.txt.gz",
+			owner:           alice,
+			content:         gzipString("password\n123456\nletmein\nqwerty\n"),
+			wantLines:       4,
+			wantCompression: wordlist.CompressionGzip,
+		},
+		{
+			name:      "Test AddResource without trailing newline",
+			kind:      ResourceKindRule,
+			resName:   "best64.rule",
+			owner:     alice,
+			content:   ":\nl\nu",
+			wantLines: 3,
+		},
+		{
+			name:      "Test AddResource empty",
+			kind:      ResourceKindWordlist,
+			resName:   "empty.txt",
+			owner:     alice,
+			content:   "",
+			wantLines: 0,
+		},
+		{
+			name:        "Test AddResource unknown kind",
+			kind:        "bogus",
+			resName:     "x",
+			owner:       alice,
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:        "Test AddResource without name",
+			kind:        ResourceKindWordlist,
+			owner:       alice,
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:    "Test AddResource without owner",
+			kind:    ResourceKindWordlist,
+			resName: "x",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, resources := newTestService(t)
+
+			got, err := l.AddResource(tt.kind, tt.resName, tt.owner, false, strings.NewReader(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("AddResource() got = %v, want error", got)
+				}
+				if tt.wantInvalid && !errors.Is(err, ErrInvalidResource) {
+					t.Errorf("AddResource() error = %v, want %v", err, ErrInvalidResource)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddResource() error = %v", err)
+			}
+
+			wantCompression := tt.wantCompression
+			if wantCompression == "" {
+				wantCompression = wordlist.CompressionNone
+			}
+			if got.Compression != wantCompression {
+				t.Errorf("AddResource() compression = %v, want %v", got.Compression, wantCompression)
+			}
+			if got.Lines != tt.wantLines {
+				t.Errorf("AddResource() lines = %v, want %v", got.Lines, tt.wantLines)
+			}
+			if got.Size != int64(len(tt.content)) {
+				t.Errorf("AddResource() size = %v, want %v", got.Size, len(tt.content))
+			}
+			if _, ok := resources[got.ID]; !ok {
+				t.Errorf("AddResource() resource %v not stored", got.ID)
+			}
+
+			data, err := os.ReadFile(l.storage.Path(got.SHA256))
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(data) != tt.content {
+				t.Errorf("AddResource() stored = %q, want %q", data, tt.content)
+			}
+		})
+	}
+}
+
+func TestLibraryService_Visibility(t *testing.T) {
+	l, _ := newTestService(t)
+
+	private, err := l.AddResource(ResourceKindWordlist, "private.txt", alice, false, strings.NewReader("a\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	shared, err := l.AddResource(ResourceKindWordlist, "shared.txt", alice, true, strings.NewReader("b\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		kind    ResourceKind
+		u       *user.User
+		wantErr bool
+	}{
+		{name: "Test owner sees private", id: private.ID, kind: ResourceKindWordlist, u: alice},
+		{name: "Test other user cannot see private", id: private.ID, kind: ResourceKindWordlist, u: bob, wantErr: true},
+		{name: "Test other user sees shared", id: shared.ID, kind: ResourceKindWordlist, u: bob},
+		{name: "Test anonymous sees shared", id: shared.ID, kind: ResourceKindWordlist, u: nil},
+		{name: "Test wrong kind", id: shared.ID, kind: ResourceKindRule, u: alice, wantErr: true},
+		{name: "Test missing", id: "missing", kind: ResourceKindWordlist, u: alice, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := l.GetResource(tt.kind, tt.id, tt.u)
+			if tt.wantErr {
+				if !errors.Is(err, &uerr.ErrorNotFound{}) {
+					t.Errorf("GetResource() error = %v, want not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("GetResource() error = %v", err)
+			}
+		})
+	}
+
+	bobList, err := l.ListResources(ResourceKindWordlist, bob)
+	if err != nil {
+		t.Fatalf("ListResources() error = %v", err)
+	}
+	if len(bobList) != 1 || bobList[0].ID != shared.ID {
+		t.Errorf("ListResources() got = %v, want only %v", bobList, shared.ID)
+	}
+
+	aliceList, err := l.ListResources(ResourceKindWordlist, alice)
+	if err != nil {
+		t.Fatalf("ListResources() error = %v", err)
+	}
+	if len(aliceList) != 2 {
+		t.Errorf("ListResources() got %d resources, want 2", len(aliceList))
+	}
+}
+
+func TestLibraryService_DeleteResource(t *testing.T) {
+	l, resources := newTestService(t)
+
+	first, err := l.AddResource(ResourceKindWordlist, "first.txt", alice, true, strings.NewReader("same\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	second, err := l.AddResource(ResourceKindWordlist, "second.txt", alice, false, strings.NewReader("same\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	if first.SHA256 != second.SHA256 {
+		t.Fatalf("AddResource() sha256 %v != %v for identical contents", first.SHA256, second.SHA256)
+	}
+	path := l.storage.Path(first.SHA256)
+
+	err = l.DeleteResource(ResourceKindWordlist, first.ID, bob)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("DeleteResource() by non-owner error = %v, want not found", err)
+	}
+
+	err = l.DeleteResource(ResourceKindWordlist, first.ID, alice)
+	if err != nil {
+		t.Fatalf("DeleteResource() error = %v", err)
+	}
+	if _, ok := resources[first.ID]; ok {
+		t.Errorf("DeleteResource() resource %v still stored", first.ID)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("DeleteResource() removed file still referenced by %v", second.ID)
+	}
+
+	err = l.DeleteResource(ResourceKindWordlist, second.ID, alice)
+	if err != nil {
+		t.Fatalf("DeleteResource() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("DeleteResource() left unreferenced file behind, stat error = %v", err)
+	}
+}
+
+func TestLibraryService_DeleteResource_InUse(t *testing.T) {
+	l, resources := newTestService(t)
+
+	r, err := l.AddResource(ResourceKindWordlist, "words.txt", alice, false, strings.NewReader("word\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+
+	usage := &MockResourceUsage{InUse: map[string]bool{r.ID: true}}
+	l.usage = usage
+
+	err = l.DeleteResource(ResourceKindWordlist, r.ID, alice)
+	if !errors.Is(err, ErrResourceInUse) {
+		t.Errorf("DeleteResource() of used resource error = %v, want %v", err, ErrResourceInUse)
+	}
+	if _, ok := resources[r.ID]; !ok {
+		t.Errorf("DeleteResource() deleted resource %v still in use", r.ID)
+	}
+
+	usage.InUse[r.ID] = false
+	err = l.DeleteResource(ResourceKindWordlist, r.ID, alice)
+	if err != nil {
+		t.Errorf("DeleteResource() once unused error = %v", err)
+	}
+}
+
+func TestLibraryService_DeleteResource_ConcurrentAdd(t *testing.T) {
+	storage, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStorage() error = %v", err)
+	}
+	store := &MockCountHookResourceStore{MockResourceStore: &MockResourceStore{Resources: make(map[string]Resource)}}
+	l := NewLibraryService(store, storage, nil)
+
+	deleted, err := l.AddResource(ResourceKindWordlist, "deleted.txt", bob, false, strings.NewReader("same\n"))
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+
+	// Alice adds the same contents once bob's delete has found no other
+	// reference to them, giving the add a moment to finish unless it waits.
+	var kept *Resource
+	var addErr error
+	done := make(chan struct{})
+	store.OnCount = func() {
+		store.OnCount = nil
+		go func() {
+			defer close(done)
+			kept, addErr = l.AddResource(ResourceKindWordlist, "kept.txt", alice, false, strings.NewReader("same\n"))
+		}()
+		select {
+		case <-done:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	err = l.DeleteResource(ResourceKindWordlist, deleted.ID, bob)
+	if err != nil {
+		t.Fatalf("DeleteResource() error = %v", err)
+	}
+	<-done
+	if addErr != nil {
+		t.Fatalf("AddResource() error = %v", addErr)
+	}
+
+	if _, err := os.Stat(l.storage.Path(kept.SHA256)); err != nil {
+		t.Errorf("DeleteResource() removed file still referenced by %v: %v", kept.ID, err)
+	}
+}
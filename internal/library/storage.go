@@ -0,0 +1,145 @@
+package library
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+// DiskStorage keeps resource contents on disk, addressed by their SHA-256 so
+// identical uploads are only stored once.
+type DiskStorage struct {
+	dir string
+}
+
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating library directory: %w", err)
+	}
+
+	return &DiskStorage{dir: dir}, nil
+}
+
+// Blob describes stored contents. Lines counts the lines after
+// decompression, so it can size the keyspace of compressed wordlists.
+type Blob struct {
+	SHA256      string
+	Size        int64
+	Lines       uint64
+	Compression wordlist.Compression
+
+	tmp string
+}
+
+type lineCounter struct {
+	header []byte
+	lines  uint64
+	last   byte
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		if n := 6 - len(c.header); n > 0 {
+			c.header = append(c.header, p[:min(n, len(p))]...)
+		}
+		c.lines += uint64(bytes.Count(p, []byte("\n")))
+		c.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+// Put streams r to a temporary file and describes what was written.
+// Compressed contents are read back once to count their lines. The contents
+// are only stored under their SHA-256 once committed, and the temporary file
+// must be discarded either way.
+func (d *DiskStorage) Put(r io.Reader) (_ *Blob, err error) {
+	tmp, err := os.CreateTemp(d.dir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	hasher := sha256.New()
+	counter := &lineCounter{}
+	bw := bufio.NewWriter(tmp)
+
+	size, err := io.Copy(io.MultiWriter(bw, hasher, counter), r)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error storing upload: %w", err)
+	}
+
+	b := &Blob{
+		SHA256:      hex.EncodeToString(hasher.Sum(nil)),
+		Size:        size,
+		Lines:       counter.lines,
+		Compression: wordlist.Detect(counter.header),
+		tmp:         tmp.Name(),
+	}
+	if size > 0 && counter.last != '\n' {
+		b.Lines++
+	}
+
+	if b.Compression != wordlist.CompressionNone {
+		b.Lines, err = wordlist.CountLines(tmp.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v upload: %w", b.Compression, err)
+		}
+	}
+
+	return b, nil
+}
+
+// Commit moves the contents of b to where they are stored by SHA-256.
+func (d *DiskStorage) Commit(b *Blob) error {
+	path := d.Path(b.SHA256)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(b.tmp, path)
+	if err != nil {
+		return fmt.Errorf("error storing upload: %w", err)
+	}
+
+	return nil
+}
+
+// Discard removes the temporary file of b if it was not committed.
+func (d *DiskStorage) Discard(b *Blob) {
+	os.Remove(b.tmp)
+}
+
+func (d *DiskStorage) Path(sha string) string {
+	return filepath.Join(d.dir, sha[:2], sha)
+}
+
+func (d *DiskStorage) Open(sha string) (*os.File, error) {
+	return os.Open(d.Path(sha))
+}
+
+func (d *DiskStorage) Remove(sha string) error {
+	err := os.Remove(d.Path(sha))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
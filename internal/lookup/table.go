@@ -0,0 +1,256 @@
+package lookup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+// A table file is laid out as a fixed-size header, followed by fixed-width
+// records sorted by hash prefix, followed by the newline-terminated
+// plaintexts the records point into. Everything is at a fixed offset, so the
+// file can be memory-mapped or binary searched in place.
+const (
+	tableMagic      = "UHLT"
+	tableVersion    = 1
+	tableHeaderSize = 32
+	tableRecordSize = 16
+	hashTypeSize    = 16
+	maxPlainLength  = 1024
+)
+
+type record struct {
+	prefix uint64
+	offset uint64
+}
+
+type Table struct {
+	r        io.ReaderAt
+	closer   io.Closer
+	hashType hashtype.HashType
+	count    uint64
+}
+
+// Build hashes every line of words and writes a lookup table for them to w.
+// Records are sorted in memory, so building needs roughly 16 bytes per word
+// plus the size of the wordlist.
+func Build(w io.Writer, t hashtype.HashType, words io.Reader) (uint64, error) {
+	err := t.Validate()
+	if err != nil {
+		return 0, err
+	}
+	if len(t) > hashTypeSize {
+		return 0, fmt.Errorf("hash type `%v` name too long for table header", t)
+	}
+
+	var plaintexts bytes.Buffer
+	records := make([]record, 0)
+
+	// Lines longer than the read buffer are skipped a buffer at a time, like
+	// the wordlist reader does, rather than failing the whole build.
+	br := bufio.NewReader(words)
+	tooLong := false
+	for {
+		raw, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			tooLong = true
+			continue
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && len(raw) > 0) {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("error reading wordlist: %w", err)
+		}
+		if tooLong {
+			tooLong = false
+			continue
+		}
+
+		word := bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+		if len(word) > maxPlainLength {
+			continue
+		}
+
+		digest, err := t.Hash(string(word))
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, record{
+			prefix: binary.BigEndian.Uint64(digest),
+			offset: uint64(plaintexts.Len()),
+		})
+		plaintexts.Write(word)
+		plaintexts.WriteByte('\n')
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].prefix < records[j].prefix })
+
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, tableHeaderSize)
+	copy(header[0:4], tableMagic)
+	binary.LittleEndian.PutUint32(header[4:8], tableVersion)
+	copy(header[8:8+hashTypeSize], t)
+	binary.LittleEndian.PutUint64(header[24:32], uint64(len(records)))
+	_, err = bw.Write(header)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, tableRecordSize)
+	for _, rec := range records {
+		binary.BigEndian.PutUint64(buf[0:8], rec.prefix)
+		binary.LittleEndian.PutUint64(buf[8:16], rec.offset)
+		_, err = bw.Write(buf)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = plaintexts.WriteTo(bw)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(len(records)), bw.Flush()
+}
+
+func Open(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := NewTable(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error opening lookup table %v: %w", path, err)
+	}
+	t.closer = f
+
+	return t, nil
+}
+
+func NewTable(r io.ReaderAt) (*Table, error) {
+	header := make([]byte, tableHeaderSize)
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lookup table header: %w", err)
+	}
+	if string(header[0:4]) != tableMagic {
+		return nil, errors.New("not a lookup table")
+	}
+	if v := binary.LittleEndian.Uint32(header[4:8]); v != tableVersion {
+		return nil, fmt.Errorf("unsupported lookup table version %d", v)
+	}
+
+	t := hashtype.HashType(bytes.TrimRight(header[8:8+hashTypeSize], "\x00"))
+	err = t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Table{
+		r:        r,
+		hashType: t,
+		count:    binary.LittleEndian.Uint64(header[24:32]),
+	}, nil
+}
+
+func (t *Table) HashType() hashtype.HashType {
+	return t.hashType
+}
+
+func (t *Table) Len() uint64 {
+	return t.count
+}
+
+func (t *Table) Close() error {
+	if t.closer == nil {
+		return nil
+	}
+	return t.closer.Close()
+}
+
+// Lookup returns the plaintext of digest if it was in the table's wordlist.
+// Records only hold a prefix of each digest, so every candidate plaintext is
+// rehashed to confirm the match.
+func (t *Table) Lookup(digest []byte) (string, bool, error) {
+	if len(digest) != t.hashType.Size() {
+		return "", false, fmt.Errorf("expected %d byte %v digest, got %d bytes", t.hashType.Size(), t.hashType, len(digest))
+	}
+	prefix := binary.BigEndian.Uint64(digest)
+
+	var readErr error
+	first := sort.Search(int(t.count), func(i int) bool {
+		rec, err := t.record(uint64(i))
+		if err != nil {
+			readErr = err
+			return true
+		}
+		return rec.prefix >= prefix
+	})
+	if readErr != nil {
+		return "", false, readErr
+	}
+
+	for i := uint64(first); i < t.count; i++ {
+		rec, err := t.record(i)
+		if err != nil {
+			return "", false, err
+		}
+		if rec.prefix != prefix {
+			break
+		}
+
+		plain, err := t.plaintext(rec.offset)
+		if err != nil {
+			return "", false, err
+		}
+		candidate, err := t.hashType.Hash(plain)
+		if err != nil {
+			return "", false, err
+		}
+		if bytes.Equal(candidate, digest) {
+			return plain, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (t *Table) record(i uint64) (record, error) {
+	buf := make([]byte, tableRecordSize)
+	_, err := t.r.ReadAt(buf, int64(tableHeaderSize+i*tableRecordSize))
+	if err != nil {
+		return record{}, fmt.Errorf("error reading lookup table record %d: %w", i, err)
+	}
+
+	return record{
+		prefix: binary.BigEndian.Uint64(buf[0:8]),
+		offset: binary.LittleEndian.Uint64(buf[8:16]),
+	}, nil
+}
+
+func (t *Table) plaintext(offset uint64) (string, error) {
+	buf := make([]byte, maxPlainLength+1)
+	n, err := t.r.ReadAt(buf, int64(tableHeaderSize+t.count*tableRecordSize+offset))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("error reading lookup table plaintext: %w", err)
+	}
+
+	end := bytes.IndexByte(buf[:n], '\n')
+	if end < 0 {
+		return "", errors.New("lookup table plaintext is not terminated")
+	}
+
+	return string(buf[:end]), nil
+}
@@ -0,0 +1,158 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+func buildTestTable(t *testing.T, hashType hashtype.HashType, words string) *Table {
+	var buf bytes.Buffer
+	_, err := Build(&buf, hashType, strings.NewReader(words))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	table, err := NewTable(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+
+	return table
+}
+
+func TestTable_Lookup(t *testing.T) {
+	words := "password\r\n123456\nletmein\ndragon\n\nqwerty\n"
+
+	tests := []struct {
+		name      string
+		hashType  hashtype.HashType
+		digest    string
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name:      "Test Lookup md5",
+			hashType:  hashtype.HashTypeMD5,
+			digest:    "5f4dcc3b5aa765d61d8327deb882cf99",
+			want:      "password",
+			wantFound: true,
+		},
+		{
+			name:      "Test Lookup sha1",
+			hashType:  hashtype.HashTypeSHA1,
+			digest:    "7c4a8d09ca3762af61e59520943dc26494f8941b",
+			want:      "123456",
+			wantFound: true,
+		},
+		{
+			name:      "Test Lookup ntlm",
+			hashType:  hashtype.HashTypeNTLM,
+			digest:    "8846f7eaee8fb117ad06bdd830b7586c",
+			want:      "password",
+			wantFound: true,
+		},
+		{
+			name:      "Test Lookup empty plaintext",
+			hashType:  hashtype.HashTypeMD5,
+			digest:    "d41d8cd98f00b204e9800998ecf8427e",
+			want:      "",
+			wantFound: true,
+		},
+		{
+			name:      "Test Lookup missing",
+			hashType:  hashtype.HashTypeMD5,
+			digest:    "00000000000000000000000000000000",
+			wantFound: false,
+		},
+		{
+			name:      "Test Lookup matching prefix only",
+			hashType:  hashtype.HashTypeMD5,
+			digest:    "5f4dcc3b5aa765d60000000000000000",
+			wantFound: false,
+		},
+		{
+			name:     "Test Lookup wrong digest size",
+			hashType: hashtype.HashTypeMD5,
+			digest:   "5f4dcc3b",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := buildTestTable(t, tt.hashType, words)
+			if table.HashType() != tt.hashType {
+				t.Errorf("HashType() got = %v, want %v", table.HashType(), tt.hashType)
+			}
+			if table.Len() != 6 {
+				t.Errorf("Len() got = %v, want 6", table.Len())
+			}
+
+			digest, err := hex.DecodeString(tt.digest)
+			if err != nil {
+				t.Fatalf("DecodeString() error = %v", err)
+			}
+
+			got, found, err := table.Lookup(digest)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Lookup() got = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+
+			if found != tt.wantFound {
+				t.Errorf("Lookup() found = %v, want %v", found, tt.wantFound)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_UnknownHashType(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Build(&buf, "bogus", strings.NewReader("password\n"))
+	if err == nil {
+		t.Errorf("Build() got = nil, want error")
+	}
+}
+
+func TestBuild_LongLines(t *testing.T) {
+	words := "password\n" + strings.Repeat("a", 100*1024) + "\n" + strings.Repeat("b", 2048) + "\nletmein\n" + strings.Repeat("c", 100*1024)
+
+	var buf bytes.Buffer
+	count, err := Build(&buf, hashtype.HashTypeMD5, strings.NewReader(words))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Build() count = %v, want 2", count)
+	}
+
+	table, err := NewTable(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+	digest, _ := hex.DecodeString("0d107d09f5bbe40cade3de5c71e9e9b7")
+	got, found, err := table.Lookup(digest)
+	if err != nil || !found || got != "letmein" {
+		t.Errorf("Lookup() got = %v, %v, %v, want letmein after the long line", got, found, err)
+	}
+}
+
+func TestNewTable_Invalid(t *testing.T) {
+	_, err := NewTable(bytes.NewReader([]byte("this is not a lookup table at all")))
+	if err == nil {
+		t.Errorf("NewTable() got = nil, want error")
+	}
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer logs messages instead of sending them, for development.
+type LogMailer struct {
+	logger *log.Logger
+}
+
+func NewLogMailer(logger *log.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	m.logger.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// FileMailer appends messages to a file instead of sending them, so tests
+// and local setups can read them back.
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+func (m *FileMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening mail file: %w", err)
+	}
+	defer f.Close()
+
+	err = writeMessage(f, to, subject, body, time.Now())
+	if err != nil {
+		return fmt.Errorf("error writing mail file: %w", err)
+	}
+
+	return nil
+}
+
+func writeMessage(w io.Writer, to, subject, body string, date time.Time) error {
+	_, err := fmt.Fprintf(w, "Date: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n\r\n", date.Format(time.RFC1123Z), to, subject, body)
+	return err
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	m := NewFileMailer(path)
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		err := m.Send(to, "Hello", "Hi "+to)
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, want := range []string{"To: alice@example.com\r\nSubject: Hello\r\n\r\nHi alice@example.com", "To: bob@example.com"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Send() wrote %q, want it to contain %q", data, want)
+		}
+	}
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(log.New(&buf, "", 0))
+
+	err := m.Send("alice@example.com", "Hello", "token")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "alice@example.com") || !strings.Contains(buf.String(), "token") {
+		t.Errorf("Send() logged %q, want recipient and body", buf.String())
+	}
+}
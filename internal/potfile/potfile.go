@@ -0,0 +1,98 @@
+package potfile
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Entry is a single recovered plaintext, stored in hashcat's `hash:plain`
+// potfile format.
+type Entry struct {
+	Hash  string `json:"hash"`
+	Plain string `json:"plain"`
+}
+
+func Read(r io.Reader) ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+
+		e, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing potfile line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading potfile: %w", err)
+	}
+
+	return entries, nil
+}
+
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		_, err := fmt.Fprintf(bw, "%s:%s\n", e.Hash, EncodePlain(e.Plain))
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// EncodePlain wraps plaintexts that cannot be written verbatim in hashcat's
+// `$HEX[...]` notation.
+func EncodePlain(plain string) string {
+	for i := 0; i < len(plain); i++ {
+		if plain[i] < 0x20 || plain[i] > 0x7e {
+			return fmt.Sprintf("$HEX[%s]", hex.EncodeToString([]byte(plain)))
+		}
+	}
+	if strings.HasPrefix(plain, "$HEX[") {
+		return fmt.Sprintf("$HEX[%s]", hex.EncodeToString([]byte(plain)))
+	}
+
+	return plain
+}
+
+func DecodePlain(plain string) (string, error) {
+	if !strings.HasPrefix(plain, "$HEX[") || !strings.HasSuffix(plain, "]") {
+		return plain, nil
+	}
+
+	decoded, err := hex.DecodeString(plain[len("$HEX[") : len(plain)-1])
+	if err != nil {
+		return "", fmt.Errorf("invalid $HEX plaintext: %w", err)
+	}
+
+	return string(decoded), nil
+}
+
+func parseLine(line string) (Entry, error) {
+	hash, plain, ok := strings.Cut(line, ":")
+	if !ok {
+		return Entry{}, fmt.Errorf("missing `:` separator")
+	}
+	if hash == "" {
+		return Entry{}, fmt.Errorf("hash cannot be empty")
+	}
+
+	plain, err := DecodePlain(plain)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Hash: hash, Plain: plain}, nil
+}
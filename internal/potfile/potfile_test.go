@@ -0,0 +1,143 @@
+package potfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Entry
+		wantErr bool
+	}{
+		{
+			name:  "Test Read",
+			input: "5f4dcc3b5aa765d61d8327deb882cf99:password\r\n\n8621ffdbc5698829397d97767ac13db3:dragon\n",
+			want: []Entry{
+				{Hash: "5f4dcc3b5aa765d61d8327deb882cf99", Plain: "password"},
+				{Hash: "8621ffdbc5698829397d97767ac13db3", Plain: "dragon"},
+			},
+		},
+		{
+			name:  "Test Read with colon in plaintext",
+			input: "abc:pass:word\n",
+			want:  []Entry{{Hash: "abc", Plain: "pass:word"}},
+		},
+		{
+			name:  "Test Read with hex plaintext",
+			input: "abc:$HEX[70c3a4737321]\n",
+			want:  []Entry{{Hash: "abc", Plain: "päss!"}},
+		},
+		{
+			name:    "Test Read with missing separator",
+			input:   "abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "Test Read with invalid hex plaintext",
+			input:   "abc:$HEX[zz]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Read() got = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Read() got = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Read() entry %d got = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{
+		{Hash: "a", Plain: "password"},
+		{Hash: "b", Plain: "päss"},
+		{Hash: "c", Plain: "$HEX[00]"},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, entries)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := "a:password\nb:$HEX[70c3a47373]\nc:$HEX[244845585b30305d]\n"
+	if buf.String() != want {
+		t.Errorf("Write() got = %q, want %q", buf.String(), want)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for i := range entries {
+		if got[i] != entries[i] {
+			t.Errorf("Read() after Write() entry %d got = %v, want %v", i, got[i], entries[i])
+		}
+	}
+}
+
+func TestPotfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unhash.pot")
+	err := os.WriteFile(path, []byte("ABCDEF:known\n"), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	p, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if plain, ok := p.Lookup("abcdef"); !ok || plain != "known" {
+		t.Errorf("Lookup() got = %v, %v, want known", plain, ok)
+	}
+	if _, ok := p.Lookup("123456"); ok {
+		t.Errorf("Lookup() of unknown hash got = true, want false")
+	}
+
+	for _, e := range []Entry{{Hash: "123456", Plain: "new:plain"}, {Hash: "abcdef", Plain: "ignored"}} {
+		if err := p.Add(e.Hash, e.Plain); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() got = %v, want 2", p.Len())
+	}
+	p.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer reopened.Close()
+
+	if plain, ok := reopened.Lookup("123456"); !ok || plain != "new:plain" {
+		t.Errorf("Lookup() after reopening got = %v, %v, want new:plain", plain, ok)
+	}
+	if plain, _ := reopened.Lookup("abcdef"); plain != "known" {
+		t.Errorf("Lookup() after reopening got = %v, want known", plain)
+	}
+}
@@ -0,0 +1,83 @@
+package potfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Potfile is a potfile on disk, held in memory for lookups and appended to
+// as hashes are cracked. Hashes are matched case-insensitively.
+type Potfile struct {
+	mu     sync.Mutex
+	f      *os.File
+	plains map[string]string
+}
+
+// Open loads the potfile at path, creating it if it does not exist.
+func Open(path string) (*Potfile, error) {
+	plains := make(map[string]string)
+
+	existing, err := os.Open(path)
+	if err == nil {
+		entries, err := Read(existing)
+		existing.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			plains[strings.ToLower(e.Hash)] = e.Plain
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Potfile{f: f, plains: plains}, nil
+}
+
+func (p *Potfile) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.plains)
+}
+
+func (p *Potfile) Lookup(hash string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plain, ok := p.plains[strings.ToLower(hash)]
+	return plain, ok
+}
+
+// Add records a cracked hash, appending it to the file unless it is already
+// known.
+func (p *Potfile) Add(hash, plain string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(hash)
+	if _, ok := p.plains[key]; ok {
+		return nil
+	}
+
+	err := Write(p.f, []Entry{{Hash: key, Plain: plain}})
+	if err != nil {
+		return fmt.Errorf("error appending to potfile: %w", err)
+	}
+	p.plains[key] = plain
+
+	return nil
+}
+
+func (p *Potfile) Close() error {
+	return p.f.Close()
+}
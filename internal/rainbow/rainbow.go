@@ -0,0 +1,301 @@
+package rainbow
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+const (
+	tableMagic   = "UHRT"
+	tableVersion = 1
+	hashTypeSize = 16
+	chainSize    = 16
+)
+
+// Params define the plaintext space a table covers and how its chains are
+// built. Tables with the same plaintext space but a different Index use
+// different reduction functions, so their chains don't merge with each other.
+type Params struct {
+	HashType    hashtype.HashType
+	Charset     string
+	Length      int
+	ChainLength int
+	Index       int
+}
+
+type chain struct {
+	start uint64
+	end   uint64
+}
+
+// Table is a rainbow table whose chains are kept sorted by endpoint.
+type Table struct {
+	params   Params
+	keyspace uint64
+	chains   []chain
+}
+
+func (p Params) Validate() error {
+	err := p.HashType.Validate()
+	if err != nil {
+		return err
+	}
+	if len(p.HashType) > hashTypeSize {
+		return fmt.Errorf("hash type `%v` name too long for table header", p.HashType)
+	}
+	if p.Charset == "" {
+		return errors.New("charset cannot be empty")
+	}
+	if p.Length <= 0 {
+		return fmt.Errorf("plaintext length must be positive, got %d", p.Length)
+	}
+	if p.ChainLength <= 0 {
+		return fmt.Errorf("chain length must be positive, got %d", p.ChainLength)
+	}
+	if p.Index < 0 {
+		return fmt.Errorf("table index cannot be negative, got %d", p.Index)
+	}
+	if _, err := p.keyspace(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p Params) keyspace() (uint64, error) {
+	keyspace := uint64(1)
+	for i := 0; i < p.Length; i++ {
+		next := keyspace * uint64(len(p.Charset))
+		if next/uint64(len(p.Charset)) != keyspace {
+			return 0, errors.New("plaintext space overflows uint64")
+		}
+		keyspace = next
+	}
+
+	return keyspace, nil
+}
+
+// Generate builds a table of chainCount chains. Chain starts are spread
+// evenly over the plaintext space.
+func Generate(p Params, chainCount int) (*Table, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if chainCount <= 0 {
+		return nil, fmt.Errorf("chain count must be positive, got %d", chainCount)
+	}
+
+	t := &Table{params: p}
+	t.keyspace, _ = p.keyspace()
+
+	stride := t.keyspace / uint64(chainCount)
+	if stride == 0 {
+		stride = 1
+	}
+
+	t.chains = make([]chain, 0, chainCount)
+	for i := 0; i < chainCount; i++ {
+		start := (uint64(i) * stride) % t.keyspace
+		end, err := t.walk(start, 0, p.ChainLength)
+		if err != nil {
+			return nil, err
+		}
+		t.chains = append(t.chains, chain{start: start, end: end})
+	}
+
+	t.sort()
+
+	return t, nil
+}
+
+func (t *Table) Params() Params {
+	return t.params
+}
+
+func (t *Table) HashType() hashtype.HashType {
+	return t.params.HashType
+}
+
+func (t *Table) Len() int {
+	return len(t.chains)
+}
+
+// Lookup recovers the plaintext of digest if it lies on one of the table's
+// chains. It assumes digest sits at each chain position in turn, walks to the
+// end of the chain and, on an endpoint match, regenerates that chain from its
+// start to rule out a false alarm.
+func (t *Table) Lookup(digest []byte) (string, bool, error) {
+	if len(digest) != t.params.HashType.Size() {
+		return "", false, fmt.Errorf("expected %d byte %v digest, got %d bytes", t.params.HashType.Size(), t.params.HashType, len(digest))
+	}
+
+	for pos := t.params.ChainLength - 1; pos >= 0; pos-- {
+		end, err := t.walk(t.reduce(digest, pos), pos+1, t.params.ChainLength)
+		if err != nil {
+			return "", false, err
+		}
+
+		i := sort.Search(len(t.chains), func(n int) bool { return t.chains[n].end >= end })
+		for ; i < len(t.chains) && t.chains[i].end == end; i++ {
+			plain, found, err := t.regenerate(t.chains[i].start, pos, digest)
+			if err != nil {
+				return "", false, err
+			}
+			if found {
+				return plain, true, nil
+			}
+		}
+	}
+
+	return "", false, nil
+}
+
+// walk hashes and reduces from plaintext index idx at chain position from
+// until position to, returning the index reached.
+func (t *Table) walk(idx uint64, from, to int) (uint64, error) {
+	for pos := from; pos < to; pos++ {
+		digest, err := t.params.HashType.Hash(t.plaintext(idx))
+		if err != nil {
+			return 0, err
+		}
+		idx = t.reduce(digest, pos)
+	}
+
+	return idx, nil
+}
+
+func (t *Table) regenerate(start uint64, pos int, digest []byte) (string, bool, error) {
+	idx, err := t.walk(start, 0, pos)
+	if err != nil {
+		return "", false, err
+	}
+
+	plain := t.plaintext(idx)
+	candidate, err := t.params.HashType.Hash(plain)
+	if err != nil {
+		return "", false, err
+	}
+
+	return plain, bytes.Equal(candidate, digest), nil
+}
+
+// reduce maps a digest at chain position pos back into the plaintext space.
+func (t *Table) reduce(digest []byte, pos int) uint64 {
+	offset := uint64(t.params.Index)*uint64(t.params.ChainLength) + uint64(pos)
+	return (binary.LittleEndian.Uint64(digest) + offset) % t.keyspace
+}
+
+func (t *Table) plaintext(idx uint64) string {
+	buf := make([]byte, t.params.Length)
+	n := uint64(len(t.params.Charset))
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = t.params.Charset[idx%n]
+		idx /= n
+	}
+
+	return string(buf)
+}
+
+func (t *Table) sort() {
+	sort.Slice(t.chains, func(i, j int) bool { return t.chains[i].end < t.chains[j].end })
+}
+
+func (t *Table) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, 48+len(t.params.Charset))
+	header = append(header, tableMagic...)
+	header = binary.LittleEndian.AppendUint32(header, tableVersion)
+	header = append(header, make([]byte, hashTypeSize)...)
+	copy(header[8:8+hashTypeSize], t.params.HashType)
+	header = binary.LittleEndian.AppendUint32(header, uint32(t.params.Length))
+	header = binary.LittleEndian.AppendUint32(header, uint32(t.params.ChainLength))
+	header = binary.LittleEndian.AppendUint32(header, uint32(t.params.Index))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(t.chains)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(t.params.Charset)))
+	header = append(header, t.params.Charset...)
+
+	n, err := bw.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	buf := make([]byte, chainSize)
+	for _, c := range t.chains {
+		binary.LittleEndian.PutUint64(buf[0:8], c.end)
+		binary.LittleEndian.PutUint64(buf[8:16], c.start)
+		n, err = bw.Write(buf)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, bw.Flush()
+}
+
+func Read(r io.Reader) (*Table, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 48)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rainbow table header: %w", err)
+	}
+	if string(header[0:4]) != tableMagic {
+		return nil, errors.New("not a rainbow table")
+	}
+	if v := binary.LittleEndian.Uint32(header[4:8]); v != tableVersion {
+		return nil, fmt.Errorf("unsupported rainbow table version %d", v)
+	}
+
+	p := Params{
+		HashType:    hashtype.HashType(bytes.TrimRight(header[8:8+hashTypeSize], "\x00")),
+		Length:      int(binary.LittleEndian.Uint32(header[24:28])),
+		ChainLength: int(binary.LittleEndian.Uint32(header[28:32])),
+		Index:       int(binary.LittleEndian.Uint32(header[32:36])),
+	}
+	count := binary.LittleEndian.Uint64(header[36:44])
+
+	charset := make([]byte, binary.LittleEndian.Uint32(header[44:48]))
+	_, err = io.ReadFull(br, charset)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rainbow table charset: %w", err)
+	}
+	p.Charset = string(charset)
+
+	err = p.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rainbow table parameters: %w", err)
+	}
+
+	t := &Table{params: p, chains: make([]chain, 0, min(count, 1<<20))}
+	t.keyspace, _ = p.keyspace()
+
+	buf := make([]byte, chainSize)
+	for i := uint64(0); i < count; i++ {
+		_, err = io.ReadFull(br, buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading rainbow table chain %d: %w", i, err)
+		}
+		t.chains = append(t.chains, chain{
+			end:   binary.LittleEndian.Uint64(buf[0:8]),
+			start: binary.LittleEndian.Uint64(buf[8:16]),
+		})
+	}
+
+	if !sort.SliceIsSorted(t.chains, func(i, j int) bool { return t.chains[i].end < t.chains[j].end }) {
+		return nil, errors.New("rainbow table chains are not sorted by endpoint")
+	}
+
+	return t, nil
+}
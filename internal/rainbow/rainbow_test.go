@@ -0,0 +1,144 @@
+package rainbow
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/hashtype"
+)
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:   "Test valid",
+			params: Params{HashType: hashtype.HashTypeNTLM, Charset: "abc", Length: 4, ChainLength: 100},
+		},
+		{
+			name:    "Test unknown hash type",
+			params:  Params{HashType: "bogus", Charset: "abc", Length: 4, ChainLength: 100},
+			wantErr: true,
+		},
+		{
+			name:    "Test empty charset",
+			params:  Params{HashType: hashtype.HashTypeMD5, Length: 4, ChainLength: 100},
+			wantErr: true,
+		},
+		{
+			name:    "Test zero chain length",
+			params:  Params{HashType: hashtype.HashTypeMD5, Charset: "abc", Length: 4},
+			wantErr: true,
+		},
+		{
+			name:    "Test plaintext space overflow",
+			params:  Params{HashType: hashtype.HashTypeMD5, Charset: "0123456789abcdef", Length: 17, ChainLength: 100},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTable_Lookup(t *testing.T) {
+	for _, hashType := range []hashtype.HashType{hashtype.HashTypeMD5, hashtype.HashTypeNTLM, hashtype.HashTypeLM} {
+		t.Run(string(hashType), func(t *testing.T) {
+			params := Params{HashType: hashType, Charset: "ABC123", Length: 4, ChainLength: 40}
+			table, err := Generate(params, 100)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			// Every plaintext along a generated chain must be recoverable.
+			covered := make([]string, 0)
+			for _, c := range table.chains[:10] {
+				for pos := 0; pos < params.ChainLength; pos += 7 {
+					idx, err := table.walk(c.start, 0, pos)
+					if err != nil {
+						t.Fatalf("walk() error = %v", err)
+					}
+					covered = append(covered, table.plaintext(idx))
+				}
+			}
+
+			for _, plain := range covered {
+				digest, err := hashType.Hash(plain)
+				if err != nil {
+					t.Fatalf("Hash() error = %v", err)
+				}
+
+				got, found, err := table.Lookup(digest)
+				if err != nil {
+					t.Fatalf("Lookup() error = %v", err)
+				}
+				if !found {
+					t.Errorf("Lookup() of %v found = false, want true", plain)
+					continue
+				}
+
+				gotDigest, _ := hashType.Hash(got)
+				if !bytes.Equal(gotDigest, digest) {
+					t.Errorf("Lookup() got = %v, which does not hash to the digest of %v", got, plain)
+				}
+			}
+
+			outside, _ := hashType.Hash("ZZZZZ")
+			_, found, err := table.Lookup(outside)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if found {
+				t.Errorf("Lookup() of plaintext outside the charset found = true, want false")
+			}
+
+			_, _, err = table.Lookup([]byte{1, 2, 3})
+			if err == nil {
+				t.Errorf("Lookup() with short digest got = nil, want error")
+			}
+		})
+	}
+}
+
+func TestTable_WriteTo(t *testing.T) {
+	params := Params{HashType: hashtype.HashTypeMD5, Charset: "abc", Length: 3, ChainLength: 5, Index: 2}
+	table, err := Generate(params, 9)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, err = table.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if got.Params() != params {
+		t.Errorf("Params() got = %v, want %v", got.Params(), params)
+	}
+	if got.Len() != table.Len() {
+		t.Fatalf("Len() got = %v, want %v", got.Len(), table.Len())
+	}
+	for i := range table.chains {
+		if got.chains[i] != table.chains[i] {
+			t.Errorf("chain %d got = %v, want %v", i, got.chains[i], table.chains[i])
+		}
+	}
+
+	_, err = Read(bytes.NewReader([]byte("not a rainbow table, definitely not one at all")))
+	if err == nil {
+		t.Errorf("Read() got = nil, want error")
+	}
+}
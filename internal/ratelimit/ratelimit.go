@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second and holding at
+// most Burst. Each request takes one token. Buckets of limits with different
+// names are independent.
+type Limit struct {
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available again, set if the
+	// request was not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// BucketStore keeps token buckets and takes tokens from them atomically.
+type BucketStore interface {
+	TakeToken(key string, rate float64, burst int) (*Decision, error)
+}
+
+type Limiter struct {
+	store BucketStore
+}
+
+func NewLimiter(s BucketStore) *Limiter {
+	return &Limiter{store: s}
+}
+
+// Enabled reports whether l limits anything. A zero rate or burst disables it.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+func (l Limit) Validate() error {
+	if l.Name == "" {
+		return errors.New("limit name is required")
+	}
+	if l.Rate < 0 || l.Burst < 0 {
+		return errors.New("limit rate and burst cannot be negative")
+	}
+
+	return nil
+}
+
+// Allow takes a token from client's bucket for l. client identifies the
+// caller, e.g. by user or IP; it is hashed before it is stored.
+func (lim *Limiter) Allow(l Limit, client string) (*Decision, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	if !l.Enabled() {
+		return &Decision{Allowed: true}, nil
+	}
+
+	d, err := lim.store.TakeToken(bucketKey(l.Name, client), l.Rate, l.Burst)
+	if err != nil {
+		return nil, fmt.Errorf("error taking rate limit token: %w", err)
+	}
+
+	return d, nil
+}
+
+func bucketKey(name, client string) string {
+	sum := sha256.Sum256([]byte(client))
+	return name + ":" + hex.EncodeToString(sum[:])
+}
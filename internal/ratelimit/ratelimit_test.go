@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+)
+
+type MockBucketStore struct {
+	Tokens map[string]int
+}
+
+func (m *MockBucketStore) TakeToken(key string, rate float64, burst int) (*Decision, error) {
+	tokens, ok := m.Tokens[key]
+	if !ok {
+		tokens = burst
+	}
+	if tokens == 0 {
+		return &Decision{Limit: burst}, nil
+	}
+	m.Tokens[key] = tokens - 1
+	return &Decision{Allowed: true, Limit: burst, Remaining: tokens - 1}, nil
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	store := &MockBucketStore{Tokens: make(map[string]int)}
+	lim := NewLimiter(store)
+
+	strict := Limit{Name: "strict", Rate: 1, Burst: 1}
+	loose := Limit{Name: "loose", Rate: 1, Burst: 2}
+	tests := []struct {
+		name        string
+		limit       Limit
+		client      string
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{"first request", strict, "alice", true, false},
+		{"bucket empty", strict, "alice", false, false},
+		{"other client", strict, "bob", true, false},
+		{"other limit", loose, "alice", true, false},
+		{"disabled limit", Limit{Name: "off"}, "alice", true, false},
+		{"unnamed limit", Limit{Rate: 1, Burst: 1}, "alice", false, true},
+		{"negative rate", Limit{Name: "bad", Rate: -1, Burst: 1}, "alice", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := lim.Allow(tt.limit, tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Allow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && d.Allowed != tt.wantAllowed {
+				t.Errorf("Allow() got = %+v, want allowed %v", d, tt.wantAllowed)
+			}
+		})
+	}
+
+	for key := range store.Tokens {
+		if key == "strict:alice" || len(key) != len("strict:")+64 && len(key) != len("loose:")+64 {
+			t.Errorf("Allow() stored bucket %v, want the client hashed", key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package rediscache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokenScript refills the token bucket at KEYS[1] by ARGV[1] tokens per
+// second up to ARGV[2] since it was last used, by the server's clock, and
+// takes a token if one is left. It returns whether it did, the whole tokens
+// left, and the milliseconds until a token is available and until the
+// bucket is full.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+local reset = math.ceil((burst - tokens) * 1000 / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+func (r *RedisCache) rateLimitKey(key string) string {
+	return fmt.Sprintf("ratelimit#%v", key)
+}
+
+// TakeToken takes a token from the bucket at key in a single script, so
+// concurrent requests, also from other API processes, cannot overdraw it.
+func (r *RedisCache) TakeToken(key string, rate float64, burst int) (*ratelimit.Decision, error) {
+	res, err := takeTokenScript.Run(r.Context, r.Client, []string{r.rateLimitKey(key)}, rate, burst).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("error running rate limit script: %w", err)
+	}
+	if len(res) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+
+	return &ratelimit.Decision{
+		Allowed:    res[0] == 1,
+		Limit:      burst,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		Reset:      time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
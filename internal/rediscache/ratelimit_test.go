@@ -0,0 +1,40 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCache_TakeToken(t *testing.T) {
+	r := &RedisCache{Client: redis.NewClient(&redis.Options{}), Context: context.Background()}
+	defer clearCache(r)
+
+	for i := 2; i >= 0; i-- {
+		d, err := r.TakeToken("test", 1, 3)
+		if err != nil {
+			t.Fatalf("TakeToken() error = %v", err)
+		}
+		if !d.Allowed || d.Remaining != i || d.Limit != 3 {
+			t.Errorf("TakeToken() got = %+v, want allowed with %d remaining", d, i)
+		}
+	}
+
+	d, err := r.TakeToken("test", 1, 3)
+	if err != nil {
+		t.Fatalf("TakeToken() error = %v", err)
+	}
+	if d.Allowed || d.RetryAfter <= 0 || d.RetryAfter > time.Second {
+		t.Errorf("TakeToken() on empty bucket got = %+v, want refused within a second", d)
+	}
+
+	d, err = r.TakeToken("other", 1, 3)
+	if err != nil {
+		t.Fatalf("TakeToken() error = %v", err)
+	}
+	if !d.Allowed {
+		t.Errorf("TakeToken() on other bucket got = %+v, want allowed", d)
+	}
+}
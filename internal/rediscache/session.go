@@ -0,0 +1,88 @@
+package rediscache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/redis/go-redis/v9"
+)
+
+func (r *RedisCache) sessionKey(tokenHash string) string {
+	return fmt.Sprintf("session#%v", tokenHash)
+}
+
+// userSessionsKey holds the token hashes of a user's sessions, so they can
+// all be ended at once.
+func (r *RedisCache) userSessionsKey(userId string) string {
+	return fmt.Sprintf("usersessions#%v", userId)
+}
+
+func (r *RedisCache) SetSession(tokenHash, userId string, ttl time.Duration) error {
+	_, err := r.Client.TxPipelined(r.Context, func(p redis.Pipeliner) error {
+		p.Set(r.Context, r.sessionKey(tokenHash), userId, ttl)
+		p.SAdd(r.Context, r.userSessionsKey(userId), tokenHash)
+		p.Expire(r.Context, r.userSessionsKey(userId), ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot insert session into cache: %w", &uerr.ErrorCannotInsert{Err: err})
+	}
+
+	return nil
+}
+
+func (r *RedisCache) GetSession(tokenHash string, ttl time.Duration) (string, error) {
+	userId, err := r.Client.GetEx(r.Context, r.sessionKey(tokenHash), ttl).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", fmt.Errorf("session not found: %w", &uerr.ErrorNotFound{Err: err})
+		}
+		return "", fmt.Errorf("error getting session from cache: %w", err)
+	}
+
+	// The session index must outlive every session in it.
+	err = r.Client.Expire(r.Context, r.userSessionsKey(userId), ttl).Err()
+	if err != nil {
+		return "", fmt.Errorf("error extending sessions of user %v: %w", userId, err)
+	}
+
+	return userId, nil
+}
+
+func (r *RedisCache) DeleteSession(tokenHash string) error {
+	userId, err := r.Client.GetDel(r.Context, r.sessionKey(tokenHash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return fmt.Errorf("cannot delete session: %w", &uerr.ErrorCannotDelete{Err: err})
+	}
+
+	err = r.Client.SRem(r.Context, r.userSessionsKey(userId), tokenHash).Err()
+	if err != nil {
+		return fmt.Errorf("cannot delete session: %w", &uerr.ErrorCannotDelete{Err: err})
+	}
+
+	return nil
+}
+
+func (r *RedisCache) DeleteUserSessions(userId string) error {
+	hashes, err := r.Client.SMembers(r.Context, r.userSessionsKey(userId)).Result()
+	if err != nil {
+		return fmt.Errorf("cannot delete sessions of user %v: %w", userId, &uerr.ErrorCannotDelete{Err: err})
+	}
+
+	keys := []string{r.userSessionsKey(userId)}
+	for _, hash := range hashes {
+		keys = append(keys, r.sessionKey(hash))
+	}
+
+	err = r.Client.Del(r.Context, keys...).Err()
+	if err != nil {
+		return fmt.Errorf("cannot delete sessions of user %v: %w", userId, &uerr.ErrorCannotDelete{Err: err})
+	}
+
+	return nil
+}
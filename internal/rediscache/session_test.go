@@ -0,0 +1,52 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCache_Sessions(t *testing.T) {
+	r := &RedisCache{Client: redis.NewClient(&redis.Options{}), Context: context.Background()}
+	defer clearCache(r)
+
+	for _, hash := range []string{"a", "b"} {
+		err := r.SetSession(hash, "alice", time.Minute)
+		if err != nil {
+			t.Fatalf("SetSession() error = %v", err)
+		}
+	}
+
+	userId, err := r.GetSession("a", time.Hour)
+	if err != nil {
+		t.Fatalf("GetSession() error = %v", err)
+	}
+	if userId != "alice" {
+		t.Errorf("GetSession() got = %v, want alice", userId)
+	}
+	if ttl := r.Client.TTL(r.Context, r.sessionKey("a")).Val(); ttl <= time.Minute {
+		t.Errorf("GetSession() left ttl at %v, want it extended to an hour", ttl)
+	}
+
+	err = r.DeleteSession("a")
+	if err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	_, err = r.GetSession("a", time.Hour)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetSession() after DeleteSession() error = %v, want not found", err)
+	}
+
+	err = r.DeleteUserSessions("alice")
+	if err != nil {
+		t.Fatalf("DeleteUserSessions() error = %v", err)
+	}
+	_, err = r.GetSession("b", time.Hour)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetSession() after DeleteUserSessions() error = %v, want not found", err)
+	}
+}
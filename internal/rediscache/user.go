@@ -2,9 +2,11 @@ package rediscache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fmdunlap/unhash/internal/uerr"
 	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/redis/go-redis/v9"
 	"time"
 )
 
@@ -23,7 +25,10 @@ func (r *RedisCache) userEmailKey(userEmail string) string {
 func (r *RedisCache) GetUser(id string) (*user.User, error) {
 	val, err := r.Client.Get(r.Context, r.userIdKey(id)).Result()
 	if err != nil {
-		return nil, fmt.Errorf("key not found %v: %w", id, &uerr.ErrorCannotDelete{Err: err})
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("key not found %v: %w", id, &uerr.ErrorNotFound{Err: err})
+		}
+		return nil, fmt.Errorf("error getting user %v from cache: %w", id, err)
 	}
 
 	u, err := user.Unmarshal([]byte(val))
@@ -37,7 +42,10 @@ func (r *RedisCache) GetUser(id string) (*user.User, error) {
 func (r *RedisCache) GetUserByEmail(email string) (*user.User, error) {
 	val, err := r.Client.Get(r.Context, r.userEmailKey(email)).Result()
 	if err != nil {
-		return nil, fmt.Errorf("key not found %v: %w", email, &uerr.ErrorCannotDelete{Err: err})
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("key not found %v: %w", email, &uerr.ErrorNotFound{Err: err})
+		}
+		return nil, fmt.Errorf("error getting user %v from cache: %w", email, err)
 	}
 
 	u, err := user.Unmarshal([]byte(val))
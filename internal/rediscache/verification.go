@@ -0,0 +1,51 @@
+package rediscache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/redis/go-redis/v9"
+)
+
+func (r *RedisCache) verificationKey(tokenHash string) string {
+	return fmt.Sprintf("verification#%v", tokenHash)
+}
+
+func (r *RedisCache) SetVerification(tokenHash string, v user.Verification, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cannot insert verification into cache: %w", &uerr.ErrorCannotInsert{Err: err})
+	}
+
+	err = r.Client.Set(r.Context, r.verificationKey(tokenHash), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("cannot insert verification into cache: %w", &uerr.ErrorCannotInsert{Err: err})
+	}
+
+	return nil
+}
+
+func (r *RedisCache) GetVerification(tokenHash string) (*user.Verification, error) {
+	val, err := r.Client.Get(r.Context, r.verificationKey(tokenHash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("verification not found: %w", &uerr.ErrorNotFound{Err: err})
+		}
+		return nil, fmt.Errorf("error getting verification from cache: %w", err)
+	}
+
+	return user.UnmarshalVerification([]byte(val))
+}
+
+func (r *RedisCache) DeleteVerification(tokenHash string) error {
+	err := r.Client.Del(r.Context, r.verificationKey(tokenHash)).Err()
+	if err != nil {
+		return fmt.Errorf("cannot delete verification: %w", &uerr.ErrorCannotDelete{Err: err})
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisCache_Verifications(t *testing.T) {
+	r := &RedisCache{Client: redis.NewClient(&redis.Options{}), Context: context.Background()}
+	defer clearCache(r)
+
+	want := user.Verification{UserID: "alice", Email: "alice@example.com"}
+	err := r.SetVerification("a", want, time.Minute)
+	if err != nil {
+		t.Fatalf("SetVerification() error = %v", err)
+	}
+
+	got, err := r.GetVerification("a")
+	if err != nil {
+		t.Fatalf("GetVerification() error = %v", err)
+	}
+	if *got != want {
+		t.Errorf("GetVerification() got = %v, want %v", got, want)
+	}
+	if ttl := r.Client.TTL(r.Context, r.verificationKey("a")).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("SetVerification() ttl = %v, want at most a minute", ttl)
+	}
+
+	err = r.DeleteVerification("a")
+	if err != nil {
+		t.Fatalf("DeleteVerification() error = %v", err)
+	}
+	_, err = r.GetVerification("a")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetVerification() after DeleteVerification() error = %v, want not found", err)
+	}
+}
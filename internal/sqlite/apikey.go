@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func (s *SqliteStore) InsertAPIKey(k *user.APIKey) error {
+	if k.ID == "" {
+		return errors.New("id is required")
+	}
+
+	rawData, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert into apikeys (id, data) values (?, ?)", k.ID, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetAPIKeyByHash(hash string) (*user.APIKey, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from apikeys where data->>'hash' = ?", hash).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+
+		return nil, err
+	}
+
+	return user.UnmarshalAPIKey(data)
+}
+
+func (s *SqliteStore) ListAPIKeys(userId string) ([]user.APIKey, error) {
+	rows, err := s.sq3.Query("select data from apikeys where data->>'userId' = ? order by rowid", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]user.APIKey, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		k, err := user.UnmarshalAPIKey(data)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *k)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SqliteStore) UpdateAPIKey(k *user.APIKey) error {
+	rawData, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.sq3.Exec("update apikeys set data = ? where id = ?", rawData, k.ID)
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+	if n == 0 {
+		return &uerr.ErrorNotFound{Err: errors.New("api key not found")}
+	}
+
+	return nil
+}
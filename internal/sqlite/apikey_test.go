@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func TestSqliteStore_APIKeys(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	keys := []user.APIKey{
+		{ID: "a", UserID: "alice", Name: "ci", Hash: "hash-a"},
+		{ID: "b", UserID: "alice", Name: "laptop", Hash: "hash-b"},
+		{ID: "c", UserID: "bob", Name: "ci", Hash: "hash-c"},
+	}
+	for _, k := range keys {
+		if err := s.InsertAPIKey(&k); err != nil {
+			t.Fatalf("InsertAPIKey() error = %v", err)
+		}
+	}
+
+	err := s.InsertAPIKey(&user.APIKey{ID: "a", Hash: "hash-d"})
+	if !errors.Is(err, &uerr.ErrorCannotInsert{}) {
+		t.Errorf("InsertAPIKey() with duplicate id error = %v, want cannot insert", err)
+	}
+
+	got, err := s.GetAPIKeyByHash("hash-b")
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash() error = %v", err)
+	}
+	if got.ID != "b" || got.UserID != "alice" {
+		t.Errorf("GetAPIKeyByHash() got = %v, want key b of alice", got)
+	}
+
+	_, err = s.GetAPIKeyByHash("missing")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetAPIKeyByHash() error = %v, want not found", err)
+	}
+
+	list, err := s.ListAPIKeys("alice")
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Errorf("ListAPIKeys() got = %v, want keys a and b", list)
+	}
+
+	now := time.Now().UTC()
+	got.RevokedAt = &now
+	if err := s.UpdateAPIKey(got); err != nil {
+		t.Fatalf("UpdateAPIKey() error = %v", err)
+	}
+	got, err = s.GetAPIKeyByHash("hash-b")
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash() error = %v", err)
+	}
+	if got.RevokedAt == nil {
+		t.Errorf("UpdateAPIKey() did not store revocation")
+	}
+
+	err = s.UpdateAPIKey(&user.APIKey{ID: "missing"})
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("UpdateAPIKey() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fmdunlap/unhash/internal/benchmark"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+// SetBenchmark stores r, replacing any earlier result for its hash type.
+func (s *SqliteStore) SetBenchmark(r benchmark.Result) error {
+	if r.HashType == "" {
+		return errors.New("hash type is required")
+	}
+
+	rawData, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert or replace into benchmarks (id, data) values (?, ?)", r.HashType, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetBenchmark(t hashtype.HashType) (*benchmark.Result, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from benchmarks where id = ?", t).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+		return nil, err
+	}
+
+	return benchmark.Unmarshal(data)
+}
+
+func (s *SqliteStore) ListBenchmarks() ([]benchmark.Result, error) {
+	rows, err := s.sq3.Query("select data from benchmarks order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]benchmark.Result, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := benchmark.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *r)
+	}
+
+	return results, rows.Err()
+}
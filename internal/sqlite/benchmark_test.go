@@ -0,0 +1,49 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/benchmark"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+func TestSqliteStore_SetBenchmark(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	results := []benchmark.Result{
+		{HashType: hashtype.HashTypeSHA1, CandidatesPerSecond: 10},
+		{HashType: hashtype.HashTypeMD5, CandidatesPerSecond: 20},
+		{HashType: hashtype.HashTypeMD5, CandidatesPerSecond: 30},
+	}
+	for _, r := range results {
+		if err := s.SetBenchmark(r); err != nil {
+			t.Fatalf("SetBenchmark() error = %v", err)
+		}
+	}
+
+	got, err := s.ListBenchmarks()
+	if err != nil {
+		t.Fatalf("ListBenchmarks() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ListBenchmarks() got = %v, want 2 results", got)
+	}
+	if got[0].HashType != hashtype.HashTypeMD5 || got[0].CandidatesPerSecond != 30 {
+		t.Errorf("ListBenchmarks() got = %v, want latest md5 result first", got[0])
+	}
+
+	md5, err := s.GetBenchmark(hashtype.HashTypeMD5)
+	if err != nil {
+		t.Fatalf("GetBenchmark() error = %v", err)
+	}
+	if md5.CandidatesPerSecond != 30 {
+		t.Errorf("GetBenchmark() got = %v, want 30", md5.CandidatesPerSecond)
+	}
+
+	_, err = s.GetBenchmark(hashtype.HashTypeNTLM)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetBenchmark() error = %v, want not found", err)
+	}
+}
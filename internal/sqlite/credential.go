@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// SetCredential stores c, replacing the user's previous credential.
+func (s *SqliteStore) SetCredential(c *user.Credential) error {
+	if c.UserID == "" {
+		return errors.New("user id is required")
+	}
+
+	rawData, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert or replace into credentials (id, data) values (?, ?)", c.UserID, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetCredential(userId string) (*user.Credential, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from credentials where id = ?", userId).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+
+		return nil, err
+	}
+
+	return user.UnmarshalCredential(data)
+}
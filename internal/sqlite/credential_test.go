@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func TestSqliteStore_SetCredential(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	for _, hash := range []string{"first", "second"} {
+		err := s.SetCredential(&user.Credential{UserID: "alice", Salt: []byte("salt"), Hash: []byte(hash), Params: user.DefaultArgon2Params})
+		if err != nil {
+			t.Fatalf("SetCredential() error = %v", err)
+		}
+	}
+
+	c, err := s.GetCredential("alice")
+	if err != nil {
+		t.Fatalf("GetCredential() error = %v", err)
+	}
+	if string(c.Hash) != "second" || string(c.Salt) != "salt" || c.Params != user.DefaultArgon2Params {
+		t.Errorf("GetCredential() got = %v, want the second credential", c)
+	}
+
+	_, err = s.GetCredential("bob")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetCredential() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+// DeleteUserAndHashJobs removes u with its api keys, credential and team
+// memberships, or writes it back if soft is set, deletes the hashjobs with
+// ids in deleted and writes back reassigned, in a single transaction.
+func (s *SqliteStore) DeleteUserAndHashJobs(u *user.User, soft bool, deleted []string, reassigned []hashjob.HashJob) error {
+	tx, err := s.sq3.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if soft {
+		rawData, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec("update users set data = ? where id = ?", rawData, u.ID)
+		if err != nil {
+			return &uerr.ErrorCannotUpdate{Err: err}
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return &uerr.ErrorNotFound{Err: errors.New("user not found")}
+		}
+	} else {
+		res, err := tx.Exec("delete from users where id = ?", u.ID)
+		if err != nil {
+			return &uerr.ErrorCannotDelete{Err: err}
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return &uerr.ErrorNotFound{Err: errors.New("user not found")}
+		}
+
+		_, err = tx.Exec("delete from apikeys where data->>'userId' = ?", u.ID)
+		if err != nil {
+			return &uerr.ErrorCannotDelete{Err: fmt.Errorf("api keys: %w", err)}
+		}
+		_, err = tx.Exec("delete from credentials where id = ?", u.ID)
+		if err != nil {
+			return &uerr.ErrorCannotDelete{Err: fmt.Errorf("credential: %w", err)}
+		}
+
+		// Teams the user is the last admin of would have nobody left to
+		// manage them; teams it is the only member of are left empty.
+		var orphaned int
+		err = tx.QueryRow(`select count(*) from teams
+			where exists (select 1 from json_each(teams.data, '$.members') where value->>'userId' = ? and value->>'admin')
+			and not exists (select 1 from json_each(teams.data, '$.members') where value->>'userId' != ? and value->>'admin')
+			and exists (select 1 from json_each(teams.data, '$.members') where value->>'userId' != ?)`, u.ID, u.ID, u.ID).Scan(&orphaned)
+		if err != nil {
+			return err
+		}
+		if orphaned > 0 {
+			return user.ErrLastTeamAdmin
+		}
+		_, err = tx.Exec(`update teams set data = json_set(data, '$.members',
+				(select json_group_array(json(value)) from json_each(teams.data, '$.members') where value->>'userId' != ?))
+			where exists (select 1 from json_each(teams.data, '$.members') where value->>'userId' = ?)`, u.ID, u.ID)
+		if err != nil {
+			return &uerr.ErrorCannotUpdate{Err: fmt.Errorf("team memberships: %w", err)}
+		}
+	}
+
+	for _, id := range deleted {
+		_, err = tx.Exec("delete from hashjobs where id = ?", id)
+		if err != nil {
+			return &uerr.ErrorCannotDelete{Err: fmt.Errorf("hashjob %v: %w", id, err)}
+		}
+	}
+
+	for _, hj := range reassigned {
+		rawData, err := json.Marshal(hj)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("update hashjobs set data = ? where id = ?", rawData, hj.ID)
+		if err != nil {
+			return &uerr.ErrorCannotUpdate{Err: fmt.Errorf("hashjob %v: %w", hj.ID, err)}
+		}
+	}
+
+	return tx.Commit()
+}
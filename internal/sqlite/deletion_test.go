@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func TestSqliteStore_DeleteUserAndHashJobs(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	alice := &user.User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: user.RoleAnalyst}
+	if err := s.InsertUser(alice); err != nil {
+		t.Fatalf("InsertUser() error = %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := s.InsertHashJob(hashjob.HashJob{ID: id, OwnerId: "alice"}); err != nil {
+			t.Fatalf("InsertHashJob() error = %v", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	soft := *alice
+	soft.DeletedAt = &now
+	err := s.DeleteUserAndHashJobs(&soft, true, []string{"a"}, []hashjob.HashJob{{ID: "b", OwnerId: "bob"}})
+	if err != nil {
+		t.Fatalf("DeleteUserAndHashJobs() error = %v", err)
+	}
+
+	got, err := s.GetUser("alice")
+	if err != nil || !got.Deleted() {
+		t.Errorf("GetUser() after soft deletion got = %v, %v, want a deleted user", got, err)
+	}
+	if _, err = s.GetHashJob("a"); !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetHashJob() of deleted job error = %v, want not found", err)
+	}
+	if hj, err := s.GetHashJob("b"); err != nil || hj.OwnerId != "bob" {
+		t.Errorf("GetHashJob() of reassigned job got = %v, %v, want it owned by bob", hj, err)
+	}
+
+	for _, id := range []string{"alice", "bob"} {
+		if err := s.InsertAPIKey(&user.APIKey{ID: "key-" + id, UserID: id, Hash: "hash-" + id}); err != nil {
+			t.Fatalf("InsertAPIKey() error = %v", err)
+		}
+		if err := s.SetCredential(&user.Credential{UserID: id}); err != nil {
+			t.Fatalf("SetCredential() error = %v", err)
+		}
+	}
+	team := &user.Team{ID: "red", Name: "red", Members: []user.TeamMember{{UserID: "alice", Admin: true}, {UserID: "bob"}}}
+	solo := &user.Team{ID: "solo", Name: "solo", Members: []user.TeamMember{{UserID: "alice", Admin: true}}}
+	for _, tm := range []*user.Team{team, solo} {
+		if err := s.InsertTeam(tm); err != nil {
+			t.Fatalf("InsertTeam() error = %v", err)
+		}
+	}
+
+	err = s.DeleteUserAndHashJobs(alice, false, nil, nil)
+	if !errors.Is(err, user.ErrLastTeamAdmin) {
+		t.Fatalf("DeleteUserAndHashJobs() of last team admin error = %v, want %v", err, user.ErrLastTeamAdmin)
+	}
+	if _, err = s.GetCredential("alice"); err != nil {
+		t.Errorf("GetCredential() after refused deletion error = %v, want it kept", err)
+	}
+
+	team.Members[1].Admin = true
+	if err := s.UpdateTeam(team); err != nil {
+		t.Fatalf("UpdateTeam() error = %v", err)
+	}
+	err = s.DeleteUserAndHashJobs(alice, false, nil, nil)
+	if err != nil {
+		t.Fatalf("DeleteUserAndHashJobs() error = %v", err)
+	}
+	if _, err = s.GetUser("alice"); !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetUser() after deletion error = %v, want not found", err)
+	}
+	if keys, err := s.ListAPIKeys("alice"); err != nil || len(keys) != 0 {
+		t.Errorf("ListAPIKeys() after deletion got = %v, %v, want none", keys, err)
+	}
+	if _, err = s.GetCredential("alice"); !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetCredential() after deletion error = %v, want not found", err)
+	}
+	if teams, err := s.ListTeamsByMember("alice"); err != nil || len(teams) != 0 {
+		t.Errorf("ListTeamsByMember() after deletion got = %v, %v, want none", teams, err)
+	}
+	if keys, err := s.ListAPIKeys("bob"); err != nil || len(keys) != 1 {
+		t.Errorf("ListAPIKeys() of another user got = %v, %v, want it kept", keys, err)
+	}
+	if _, err = s.GetCredential("bob"); err != nil {
+		t.Errorf("GetCredential() of another user error = %v, want it kept", err)
+	}
+	red, err := s.GetTeam("red")
+	if err != nil || len(red.Members) != 1 || red.Members[0] != (user.TeamMember{UserID: "bob", Admin: true}) {
+		t.Errorf("GetTeam() after deletion got = %v, %v, want only bob left", red, err)
+	}
+
+	err = s.DeleteUserAndHashJobs(alice, false, []string{"b"}, nil)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("DeleteUserAndHashJobs() of missing user error = %v, want not found", err)
+	}
+	if _, err = s.GetHashJob("b"); err != nil {
+		t.Errorf("GetHashJob() after failed deletion error = %v, want the job kept", err)
+	}
+}
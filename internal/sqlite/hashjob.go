@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/fmdunlap/unhash/internal/hashjob"
 	"github.com/fmdunlap/unhash/internal/uerr"
+	"strings"
 )
 
 func (s *SqliteStore) InsertHashJob(h hashjob.HashJob) error {
@@ -57,6 +58,91 @@ func (s *SqliteStore) GetHashJob(id string) (*hashjob.HashJob, error) {
 	return h, nil
 }
 
+func (s *SqliteStore) UpdateHashJob(h hashjob.HashJob) error {
+	rawData, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.sq3.Exec("update hashjobs set data = ? where id = ?", rawData, h.ID)
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+	if n == 0 {
+		return &uerr.ErrorNotFound{Err: errors.New("hashjob not found")}
+	}
+
+	return nil
+}
+
+// ListHashJobsByStatus returns the jobs in status, oldest first.
+func (s *SqliteStore) ListHashJobsByStatus(status hashjob.HashJobStatus) ([]hashjob.HashJob, error) {
+	rows, err := s.sq3.Query("select data from hashjobs where data->>'status' = ? order by rowid", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]hashjob.HashJob, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := hashjob.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *h)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ListHashJobsByOwner returns the jobs owned by ownerId or belonging to one
+// of teamIds, oldest first.
+func (s *SqliteStore) ListHashJobsByOwner(ownerId string, teamIds []string) ([]hashjob.HashJob, error) {
+	query := "select data from hashjobs where data->>'ownerId' = ?"
+	args := []any{ownerId}
+	if len(teamIds) > 0 {
+		query += " or data->>'teamId' in (?" + strings.Repeat(", ?", len(teamIds)-1) + ")"
+		for _, id := range teamIds {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := s.sq3.Query(query+" order by rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]hashjob.HashJob, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := hashjob.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, *h)
+	}
+
+	return jobs, rows.Err()
+}
+
 func (s *SqliteStore) DeleteHashJob(id string) error {
 	statement, err := s.sq3.Prepare("delete from hashjobs where id = ?")
 	if err != nil {
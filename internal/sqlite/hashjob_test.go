@@ -2,7 +2,10 @@ package sqlite
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"strings"
 	"testing"
 )
 
@@ -379,3 +382,89 @@ func TestSqliteStore_DeleteHashJob(t *testing.T) {
 		})
 	}
 }
+
+func TestSqliteStore_UpdateHashJob(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	jobs := []hashjob.HashJob{
+		{ID: "b", OwnerId: "test", Status: hashjob.HashJobStatusPending, Hashes: []string{"test"}},
+		{ID: "a", OwnerId: "test", Status: hashjob.HashJobStatusPending, Hashes: []string{"test"}},
+		{ID: "c", OwnerId: "test", Status: hashjob.HashJobStatusDone, Hashes: []string{"test"}},
+	}
+	for _, h := range jobs {
+		if err := s.InsertHashJob(h); err != nil {
+			t.Fatalf("InsertHashJob() error = %v", err)
+		}
+	}
+
+	updated := jobs[0]
+	updated.Status = hashjob.HashJobStatusTimedOut
+	updated.Cracked = map[string]string{"test": "plain"}
+	if err := s.UpdateHashJob(updated); err != nil {
+		t.Fatalf("UpdateHashJob() error = %v", err)
+	}
+
+	got, err := s.GetHashJob("b")
+	if err != nil {
+		t.Fatalf("GetHashJob() error = %v", err)
+	}
+	if got.Status != hashjob.HashJobStatusTimedOut || got.Cracked["test"] != "plain" {
+		t.Errorf("UpdateHashJob() got = %v, want %v", got, updated)
+	}
+
+	pending, err := s.ListHashJobsByStatus(hashjob.HashJobStatusPending)
+	if err != nil {
+		t.Fatalf("ListHashJobsByStatus() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != "a" {
+		t.Errorf("ListHashJobsByStatus() got = %v, want [a]", pending)
+	}
+
+	err = s.UpdateHashJob(hashjob.HashJob{ID: "missing", OwnerId: "test"})
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("UpdateHashJob() error = %v, want not found", err)
+	}
+}
+
+func TestSqliteStore_ListHashJobsByOwner(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	jobs := []hashjob.HashJob{
+		{ID: "a", OwnerId: "alice"},
+		{ID: "b", OwnerId: "bob", TeamId: "red"},
+		{ID: "c", OwnerId: "bob"},
+		{ID: "d", OwnerId: "carol", TeamId: "blue"},
+	}
+	for _, h := range jobs {
+		if err := s.InsertHashJob(h); err != nil {
+			t.Fatalf("InsertHashJob() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ownerId string
+		teamIds []string
+		want    []string
+	}{
+		{name: "Test ListHashJobsByOwner without teams", ownerId: "bob", want: []string{"b", "c"}},
+		{name: "Test ListHashJobsByOwner with a team", ownerId: "alice", teamIds: []string{"red"}, want: []string{"a", "b"}},
+		{name: "Test ListHashJobsByOwner with teams", ownerId: "dave", teamIds: []string{"red", "blue"}, want: []string{"b", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListHashJobsByOwner(tt.ownerId, tt.teamIds)
+			if err != nil {
+				t.Fatalf("ListHashJobsByOwner() error = %v", err)
+			}
+			ids := make([]string, 0, len(got))
+			for _, h := range got {
+				ids = append(ids, h.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListHashJobsByOwner() got = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
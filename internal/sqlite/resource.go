@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+func (s *SqliteStore) InsertResource(r library.Resource) error {
+	if r.ID == "" {
+		return errors.New("id is required")
+	}
+	if r.SHA256 == "" {
+		return errors.New("sha256 is required")
+	}
+
+	statement, err := s.sq3.Prepare("insert into resources (id, data) values (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	rawData, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = statement.Exec(r.ID, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetResource(id string) (*library.Resource, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from resources where id = ?", id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+		return nil, err
+	}
+
+	r, err := library.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.ID == "" {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("resource not found")}
+	}
+
+	return r, nil
+}
+
+func (s *SqliteStore) ListResources(kind library.ResourceKind, ownerId string) ([]library.Resource, error) {
+	rows, err := s.sq3.Query("select data from resources where data->>'kind' = ? and (data->>'ownerId' = ? or data->>'shared') order by data->>'name'", kind, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resources := make([]library.Resource, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := library.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, *r)
+	}
+
+	return resources, rows.Err()
+}
+
+func (s *SqliteStore) DeleteResource(id string) error {
+	statement, err := s.sq3.Prepare("delete from resources where id = ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(id)
+	if err != nil {
+		return &uerr.ErrorCannotDelete{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) CountResourcesBySHA256(sha string) (int, error) {
+	var count int
+	err := s.sq3.QueryRow("select count(*) from resources where data->>'sha256' = ?", sha).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/library"
+)
+
+func TestSqliteStore_ListResources(t *testing.T) {
+	type fields struct {
+		sq3 *sql.DB
+	}
+	type args struct {
+		kind    library.ResourceKind
+		ownerId string
+	}
+
+	resources := []library.Resource{
+		{ID: "1", Kind: library.ResourceKindWordlist, Name: "b.txt", OwnerId: "alice", SHA256: "aa"},
+		{ID: "2", Kind: library.ResourceKindWordlist, Name: "a.txt", OwnerId: "bob", Shared: true, SHA256: "bb"},
+		{ID: "3", Kind: library.ResourceKindWordlist, Name: "c.txt", OwnerId: "bob", SHA256: "cc"},
+		{ID: "4", Kind: library.ResourceKindRule, Name: "d.rule", OwnerId: "alice", SHA256: "aa"},
+	}
+
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantIds []string
+	}{
+		{
+			name:    "Test ListResources own and shared",
+			fields:  fields{sq3: CreateTestDb()},
+			args:    args{kind: library.ResourceKindWordlist, ownerId: "alice"},
+			wantIds: []string{"2", "1"},
+		},
+		{
+			name:    "Test ListResources other owner",
+			fields:  fields{sq3: CreateTestDb()},
+			args:    args{kind: library.ResourceKindWordlist, ownerId: "bob"},
+			wantIds: []string{"2", "3"},
+		},
+		{
+			name:    "Test ListResources shared only",
+			fields:  fields{sq3: CreateTestDb()},
+			args:    args{kind: library.ResourceKindWordlist, ownerId: ""},
+			wantIds: []string{"2"},
+		},
+		{
+			name:    "Test ListResources rules",
+			fields:  fields{sq3: CreateTestDb()},
+			args:    args{kind: library.ResourceKindRule, ownerId: "alice"},
+			wantIds: []string{"4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SqliteStore{
+				sq3: tt.fields.sq3,
+			}
+
+			for _, r := range resources {
+				err := s.InsertResource(r)
+				if err != nil {
+					t.Fatalf("Error inserting resource: %v", err)
+				}
+			}
+
+			got, err := s.ListResources(tt.args.kind, tt.args.ownerId)
+			if err != nil {
+				t.Fatalf("ListResources() error = %v", err)
+			}
+
+			if len(got) != len(tt.wantIds) {
+				t.Fatalf("ListResources() got = %v, want ids %v", got, tt.wantIds)
+			}
+			for i, id := range tt.wantIds {
+				if got[i].ID != id {
+					t.Errorf("ListResources() got id %v at %d, want %v", got[i].ID, i, id)
+				}
+			}
+		})
+	}
+}
+
+func TestSqliteStore_DeleteResource(t *testing.T) {
+	s := &SqliteStore{
+		sq3: CreateTestDb(),
+	}
+
+	for _, r := range []library.Resource{
+		{ID: "1", Kind: library.ResourceKindWordlist, SHA256: "aa"},
+		{ID: "2", Kind: library.ResourceKindWordlist, SHA256: "aa"},
+	} {
+		err := s.InsertResource(r)
+		if err != nil {
+			t.Fatalf("Error inserting resource: %v", err)
+		}
+	}
+
+	count, err := s.CountResourcesBySHA256("aa")
+	if err != nil || count != 2 {
+		t.Errorf("CountResourcesBySHA256() got = %v, %v, want 2", count, err)
+	}
+
+	err = s.DeleteResource("1")
+	if err != nil {
+		t.Fatalf("DeleteResource() error = %v", err)
+	}
+
+	_, err = s.GetResource("1")
+	if err == nil {
+		t.Errorf("GetResource() after delete got = nil, want error")
+	}
+
+	count, err = s.CountResourcesBySHA256("aa")
+	if err != nil || count != 1 {
+		t.Errorf("CountResourcesBySHA256() got = %v, %v, want 1", count, err)
+	}
+}
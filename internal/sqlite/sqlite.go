@@ -32,7 +32,15 @@ func NewSqliteStore(dataStore string, clearOnStartup bool) *SqliteStore {
 	}
 
 	if clearOnStartup {
-		_, err := db.Exec("create table users (id text, data jsonb)")
+		_, err := db.Exec("create table users (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create unique index users_email on users (lower(data->>'email'))")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create unique index users_username on users (data->>'username')")
 		if err != nil {
 			panic(err)
 		}
@@ -40,6 +48,34 @@ func NewSqliteStore(dataStore string, clearOnStartup bool) *SqliteStore {
 		if err != nil {
 			panic(err)
 		}
+		_, err = db.Exec("create table resources (id text, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table benchmarks (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table apikeys (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table credentials (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table teams (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table quotas (id text primary key, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
+		_, err = db.Exec("create table usage (id text, data jsonb)")
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	return &SqliteStore{sq3: db}
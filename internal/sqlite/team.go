@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func (s *SqliteStore) InsertTeam(t *user.Team) error {
+	if t.ID == "" {
+		return errors.New("id is required")
+	}
+
+	rawData, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert into teams (id, data) values (?, ?)", t.ID, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetTeam(id string) (*user.Team, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from teams where id = ?", id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+
+		return nil, err
+	}
+
+	return user.UnmarshalTeam(data)
+}
+
+func (s *SqliteStore) UpdateTeam(t *user.Team) error {
+	rawData, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.sq3.Exec("update teams set data = ? where id = ?", rawData, t.ID)
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: err}
+	}
+	if n == 0 {
+		return &uerr.ErrorNotFound{Err: errors.New("team not found")}
+	}
+
+	return nil
+}
+
+// ListTeamsByMember returns the teams userId is a member of, oldest first.
+func (s *SqliteStore) ListTeamsByMember(userId string) ([]user.Team, error) {
+	rows, err := s.sq3.Query(`select data from teams
+		where exists (select 1 from json_each(teams.data, '$.members') where value->>'userId' = ?)
+		order by rowid`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := make([]user.Team, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := user.UnmarshalTeam(data)
+		if err != nil {
+			return nil, err
+		}
+
+		teams = append(teams, *t)
+	}
+
+	return teams, rows.Err()
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func TestSqliteStore_Teams(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	teams := []user.Team{
+		{ID: "red", Name: "red", Members: []user.TeamMember{{UserID: "alice", Admin: true}, {UserID: "bob"}}},
+		{ID: "blue", Name: "blue", Members: []user.TeamMember{{UserID: "bob", Admin: true}}},
+	}
+	for _, team := range teams {
+		if err := s.InsertTeam(&team); err != nil {
+			t.Fatalf("InsertTeam() error = %v", err)
+		}
+	}
+
+	got, err := s.ListTeamsByMember("bob")
+	if err != nil {
+		t.Fatalf("ListTeamsByMember() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "red" || got[1].ID != "blue" {
+		t.Errorf("ListTeamsByMember() got = %v, want red and blue", got)
+	}
+
+	got, err = s.ListTeamsByMember("alice")
+	if err != nil {
+		t.Fatalf("ListTeamsByMember() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "red" {
+		t.Errorf("ListTeamsByMember() got = %v, want red", got)
+	}
+
+	team := teams[1]
+	team.Members = append(team.Members, user.TeamMember{UserID: "alice"})
+	if err := s.UpdateTeam(&team); err != nil {
+		t.Fatalf("UpdateTeam() error = %v", err)
+	}
+	blue, err := s.GetTeam("blue")
+	if err != nil {
+		t.Fatalf("GetTeam() error = %v", err)
+	}
+	if !blue.IsMember("alice") {
+		t.Errorf("GetTeam() got = %v, want alice added", blue)
+	}
+
+	_, err = s.GetTeam("green")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetTeam() error = %v, want not found", err)
+	}
+	err = s.UpdateTeam(&user.Team{ID: "green"})
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("UpdateTeam() error = %v, want not found", err)
+	}
+}
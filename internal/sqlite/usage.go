@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+)
+
+// SetQuota stores q, replacing the subject's previous quota.
+func (s *SqliteStore) SetQuota(subject string, q usage.Quota) error {
+	rawData, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert or replace into quotas (id, data) values (?, ?)", subject, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+func (s *SqliteStore) GetQuota(subject string) (*usage.Quota, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from quotas where id = ?", subject).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+		return nil, err
+	}
+
+	return usage.UnmarshalQuota(data)
+}
+
+func (s *SqliteStore) InsertRecord(r usage.Record) error {
+	rawData, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sq3.Exec("insert into usage (id, data) values (?, ?)", r.JobID, rawData)
+	if err != nil {
+		return &uerr.ErrorCannotInsert{Err: err}
+	}
+
+	return nil
+}
+
+// ListRecords returns the records charged to subject from from up to, but
+// not including, to, oldest first.
+func (s *SqliteStore) ListRecords(subject string, from, to time.Time) ([]usage.Record, error) {
+	var field string
+	switch {
+	case strings.HasPrefix(subject, usage.UserSubject("")):
+		field = "userId"
+	case strings.HasPrefix(subject, usage.TeamSubject("")):
+		field = "teamId"
+	default:
+		return nil, fmt.Errorf("invalid usage subject %v", subject)
+	}
+	_, id, _ := strings.Cut(subject, ":")
+
+	rows, err := s.sq3.Query(
+		"select data from usage where data->>? = ? and julianday(data->>'recordedAt') >= julianday(?) and julianday(data->>'recordedAt') <= julianday(?) order by julianday(data->>'recordedAt')",
+		"$."+field, id, from.UTC().Format(time.RFC3339Nano), to.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]usage.Record, 0)
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := usage.UnmarshalRecord(data)
+		if err != nil {
+			return nil, err
+		}
+		// julianday only resolves milliseconds, so the range is checked again.
+		if r.RecordedAt.Before(from) || !r.RecordedAt.Before(to) {
+			continue
+		}
+		records = append(records, *r)
+	}
+
+	return records, rows.Err()
+}
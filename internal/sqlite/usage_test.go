@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+)
+
+func TestSqliteStore_SetQuota(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	for _, n := range []int{1, 2} {
+		err := s.SetQuota(usage.UserSubject("alice"), usage.Quota{MaxConcurrentJobs: n})
+		if err != nil {
+			t.Fatalf("SetQuota() error = %v", err)
+		}
+	}
+
+	q, err := s.GetQuota(usage.UserSubject("alice"))
+	if err != nil {
+		t.Fatalf("GetQuota() error = %v", err)
+	}
+	if q.MaxConcurrentJobs != 2 {
+		t.Errorf("GetQuota() got = %v, want the second quota", q)
+	}
+
+	_, err = s.GetQuota(usage.TeamSubject("alice"))
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetQuota() error = %v, want not found", err)
+	}
+}
+
+func TestSqliteStore_ListRecords(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []usage.Record{
+		{JobID: "a", UserID: "alice", CPUSeconds: 1, RecordedAt: start.Add(-time.Nanosecond)},
+		{JobID: "b", UserID: "alice", TeamID: "red", CPUSeconds: 2, RecordedAt: start.Add(time.Hour)},
+		{JobID: "c", UserID: "alice", CPUSeconds: 3, RecordedAt: start.Add(time.Millisecond)},
+		{JobID: "d", UserID: "bob", TeamID: "red", CPUSeconds: 4, RecordedAt: start.Add(time.Minute)},
+		{JobID: "e", UserID: "alice", CPUSeconds: 5, RecordedAt: start.Add(2 * time.Hour)},
+	}
+	for _, r := range records {
+		err := s.InsertRecord(r)
+		if err != nil {
+			t.Fatalf("InsertRecord() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		want    []string
+		wantErr bool
+	}{
+		{"user", usage.UserSubject("alice"), []string{"c", "b"}, false},
+		{"team", usage.TeamSubject("red"), []string{"d", "b"}, false},
+		{"none", usage.UserSubject("carol"), []string{}, false},
+		{"invalid subject", "alice", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListRecords(tt.subject, start, start.Add(2*time.Hour))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			ids := make([]string, 0, len(got))
+			for _, r := range got {
+				ids = append(ids, r.JobID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("ListRecords() got = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("ListRecords() got = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/fmdunlap/unhash/internal/uerr"
 	"github.com/fmdunlap/unhash/internal/user"
+	"github.com/mattn/go-sqlite3"
+	"strings"
 )
 
+// userConflict returns the user error for err if it violates the uniqueness
+// of emails or usernames, and err otherwise.
+func userConflict(err error) error {
+	var se sqlite3.Error
+	if !errors.As(err, &se) || se.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+
+	switch {
+	case strings.Contains(se.Error(), "users_email"):
+		return user.ErrEmailTaken
+	case strings.Contains(se.Error(), "users_username"):
+		return user.ErrUsernameTaken
+	default:
+		return err
+	}
+}
+
 func (s *SqliteStore) InsertUser(u *user.User) error {
 	if u.ID == "" {
 		return errors.New("id is required")
@@ -26,7 +47,7 @@ func (s *SqliteStore) InsertUser(u *user.User) error {
 
 	_, err = statement.Exec(u.ID, rawData)
 	if err != nil {
-		return &uerr.ErrorCannotInsert{Err: err}
+		return &uerr.ErrorCannotInsert{Err: userConflict(err)}
 	}
 
 	return nil
@@ -38,10 +59,18 @@ func (s *SqliteStore) UpdateUser(u *user.User) error {
 		return err
 	}
 
-	_, err = s.sq3.Query("UPDATE users SET data = ? WHERE id = ?", u.ID, rawData)
+	res, err := s.sq3.Exec("update users set data = ? where id = ?", rawData, u.ID)
+	if err != nil {
+		return &uerr.ErrorCannotUpdate{Err: userConflict(err)}
+	}
+
+	n, err := res.RowsAffected()
 	if err != nil {
 		return &uerr.ErrorCannotUpdate{Err: err}
 	}
+	if n == 0 {
+		return &uerr.ErrorNotFound{Err: errors.New("user not found")}
+	}
 
 	return nil
 }
@@ -71,7 +100,7 @@ func (s *SqliteStore) GetUser(id string) (*user.User, error) {
 
 func (s *SqliteStore) GetUserByEmail(email string) (*user.User, error) {
 	var data []byte
-	err := s.sq3.QueryRow("select data from users where data->>'email' = ?", email).Scan(&data)
+	err := s.sq3.QueryRow("select data from users where lower(data->>'email') = lower(?)", email).Scan(&data)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &uerr.ErrorNotFound{Err: err}
@@ -92,14 +121,87 @@ func (s *SqliteStore) GetUserByEmail(email string) (*user.User, error) {
 	return u, nil
 }
 
-func (s *SqliteStore) ListUsers() ([]user.User, error) {
-	rows, err := s.sq3.Query("select data from users")
+func (s *SqliteStore) GetUserByUsername(username string) (*user.User, error) {
+	var data []byte
+	err := s.sq3.QueryRow("select data from users where data->>'username' = ?", username).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &uerr.ErrorNotFound{Err: err}
+		}
+
+		return nil, err
+	}
+
+	u, err := user.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.ID == "" {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("user not found")}
+	}
+
+	return u, nil
+}
+
+// userSortColumns are the expressions users are ordered by for each sort.
+// They match the expressions of the unique indexes, so those can be used.
+var userSortColumns = map[user.UserSort]string{
+	user.UserSortID:       "id",
+	user.UserSortUsername: "data->>'username'",
+	user.UserSortEmail:    "lower(data->>'email')",
+}
+
+// likePrefix returns a LIKE pattern matching strings starting with prefix.
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+// ListUsers returns a page of users using the keyset of sort value and id, so
+// a page costs the same however deep into the listing it is.
+func (s *SqliteStore) ListUsers(q user.UserQuery) (*user.UserPage, error) {
+	column, ok := userSortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q", user.ErrInvalidUserQuery, q.Sort)
+	}
+	if q.Limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", user.ErrInvalidUserQuery)
+	}
+	cursor, err := q.DecodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var filter []string
+	var args []any
+	if q.Search != "" {
+		filter = append(filter, `(data->>'username' like ? escape '\' or data->>'email' like ? escape '\')`)
+		args = append(args, likePrefix(q.Search), likePrefix(q.Search))
+	}
+
+	page := &user.UserPage{Users: make([]user.User, 0)}
+	err = s.sq3.QueryRow("select count(*) from users"+where(filter), args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	order, cmp := "asc", ">"
+	if q.Desc {
+		order, cmp = "desc", "<"
+	}
+	if cursor != nil {
+		filter = append(filter, fmt.Sprintf("(%[1]s %[2]s ? or (%[1]s = ? and id %[2]s ?))", column, cmp))
+		args = append(args, cursor.Value, cursor.Value, cursor.ID)
+	}
+	args = append(args, q.Limit+1)
+
+	rows, err := s.sq3.Query(fmt.Sprintf("select data from users%s order by %s %s, id %s limit ?", where(filter), column, order, order), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	users := make([]user.User, 0)
 	for rows.Next() {
 		var data []byte
 		err = rows.Scan(&data)
@@ -116,10 +218,27 @@ func (s *SqliteStore) ListUsers() ([]user.User, error) {
 			return nil, &uerr.ErrorNotFound{Err: errors.New("user not found")}
 		}
 
-		users = append(users, *u)
+		page.Users = append(page.Users, *u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > q.Limit {
+		page.Users = page.Users[:q.Limit]
+		page.NextCursor = user.NewUserCursor(&page.Users[q.Limit-1], q)
 	}
 
-	return users, nil
+	return page, nil
+}
+
+// where joins conditions into a where clause, or returns "" if there are none.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " where " + strings.Join(conditions, " and ")
 }
 
 func (s *SqliteStore) DeleteUser(id string) error {
@@ -138,7 +257,7 @@ func (s *SqliteStore) DeleteUser(id string) error {
 }
 
 func (s *SqliteStore) UserExists(u *user.User) bool {
-	rows, err := s.sq3.Query("SELECT id FROM users WHERE id = ? OR data->>'email' = ? OR data->>'username' = ? LIMIT 1", u.ID, u.Email, u.Username)
+	rows, err := s.sq3.Query("SELECT id FROM users WHERE id = ? OR lower(data->>'email') = lower(?) OR data->>'username' = ? LIMIT 1", u.ID, u.Email, u.Username)
 	if err != nil {
 		return false
 	}
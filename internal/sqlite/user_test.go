@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/user"
+)
+
+func TestSqliteStore_UpdateUser(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	u := &user.User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: user.RoleAnalyst}
+	err := s.InsertUser(u)
+	if err != nil {
+		t.Fatalf("InsertUser() error = %v", err)
+	}
+
+	u.Username = "alicia"
+	u.Email = "alicia@example.com"
+	err = s.UpdateUser(u)
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	got, err := s.GetUser("alice")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if *got != *u {
+		t.Errorf("GetUser() got = %v, want %v", got, u)
+	}
+
+	got, err = s.GetUserByUsername("alicia")
+	if err != nil || got.ID != "alice" {
+		t.Errorf("GetUserByUsername() got = %v, %v, want alice", got, err)
+	}
+	_, err = s.GetUserByEmail("alice@example.com")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetUserByEmail() of old email error = %v, want not found", err)
+	}
+
+	err = s.UpdateUser(&user.User{ID: "bob"})
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("UpdateUser() of missing user error = %v, want not found", err)
+	}
+}
+
+func TestSqliteStore_UserUniqueness(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	alice := &user.User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: user.RoleAnalyst}
+	bob := &user.User{ID: "bob", Username: "bob", Email: "bob@example.com", Role: user.RoleAnalyst}
+	for _, u := range []*user.User{alice, bob} {
+		if err := s.InsertUser(u); err != nil {
+			t.Fatalf("InsertUser() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		insert  bool
+		u       user.User
+		wantErr error
+	}{
+		{"insert taken email", true, user.User{ID: "carol", Username: "carol", Email: "alice@example.com"}, user.ErrEmailTaken},
+		{"insert taken email in another case", true, user.User{ID: "carol", Username: "carol", Email: "Alice@Example.com"}, user.ErrEmailTaken},
+		{"insert taken username", true, user.User{ID: "carol", Username: "alice", Email: "carol@example.com"}, user.ErrUsernameTaken},
+		{"insert taken id", true, user.User{ID: "alice", Username: "carol", Email: "carol@example.com"}, &uerr.ErrorCannotInsert{}},
+		{"update to taken email", false, user.User{ID: "bob", Username: "bob", Email: "alice@example.com"}, user.ErrEmailTaken},
+		{"update to taken username", false, user.User{ID: "bob", Username: "alice", Email: "bob@example.com"}, user.ErrUsernameTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.insert {
+				err = s.InsertUser(&tt.u)
+			} else {
+				err = s.UpdateUser(&tt.u)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSqliteStore_ListUsers(t *testing.T) {
+	s := &SqliteStore{sq3: CreateTestDb()}
+
+	for _, name := range []string{"dave", "alice", "carol", "bob", "al_x", "eve"} {
+		err := s.InsertUser(&user.User{ID: "id-" + name, Username: name, Email: name + "@example.com", Role: user.RoleAnalyst})
+		if err != nil {
+			t.Fatalf("InsertUser() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    user.UserQuery
+		want []string
+	}{
+		{"all by username", user.UserQuery{Sort: user.UserSortUsername}, []string{"al_x", "alice", "bob", "carol", "dave", "eve"}},
+		{"descending", user.UserQuery{Sort: user.UserSortUsername, Desc: true}, []string{"eve", "dave", "carol", "bob", "alice", "al_x"}},
+		{"username prefix", user.UserQuery{Search: "al", Sort: user.UserSortUsername}, []string{"al_x", "alice"}},
+		{"underscore is literal", user.UserQuery{Search: "al_", Sort: user.UserSortUsername}, []string{"al_x"}},
+		{"email prefix", user.UserQuery{Search: "carol@", Sort: user.UserSortEmail}, []string{"carol"}},
+		{"no match", user.UserQuery{Search: "zed", Sort: user.UserSortID}, []string{}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 4, 10} {
+			t.Run(fmt.Sprintf("%v limit %d", tt.name, limit), func(t *testing.T) {
+				q := tt.q
+				q.Limit = limit
+				got := make([]string, 0)
+				for pages := 0; ; pages++ {
+					page, err := s.ListUsers(q)
+					if err != nil {
+						t.Fatalf("ListUsers() error = %v", err)
+					}
+					if page.Total != len(tt.want) {
+						t.Errorf("ListUsers() total = %v, want %v", page.Total, len(tt.want))
+					}
+					if len(page.Users) > limit {
+						t.Fatalf("ListUsers() returned %d users, limit %d", len(page.Users), limit)
+					}
+					for _, u := range page.Users {
+						got = append(got, u.Username)
+					}
+					if page.NextCursor == "" {
+						break
+					}
+					if pages > len(tt.want) {
+						t.Fatalf("ListUsers() did not reach the last page")
+					}
+					q.Cursor = page.NextCursor
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ListUsers() got = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+
+	page, err := s.ListUsers(user.UserQuery{Sort: user.UserSortUsername, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	_, err = s.ListUsers(user.UserQuery{Sort: user.UserSortEmail, Limit: 2, Cursor: page.NextCursor})
+	if !errors.Is(err, user.ErrInvalidUserQuery) {
+		t.Errorf("ListUsers() with cursor of another sort error = %v, want %v", err, user.ErrInvalidUserQuery)
+	}
+	for _, limit := range []int{0, -1} {
+		_, err = s.ListUsers(user.UserQuery{Sort: user.UserSortUsername, Limit: limit})
+		if !errors.Is(err, user.ErrInvalidUserQuery) {
+			t.Errorf("ListUsers() with limit %d error = %v, want %v", limit, err, user.ErrInvalidUserQuery)
+		}
+	}
+}
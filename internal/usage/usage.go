@@ -0,0 +1,267 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota limits what a user or team may use. Zero values are unlimited.
+type Quota struct {
+	MaxConcurrentJobs   int     `json:"maxConcurrentJobs,omitempty"`
+	MaxHashesPerJob     int     `json:"maxHashesPerJob,omitempty"`
+	MaxCPUSecondsPerDay float64 `json:"maxCpuSecondsPerDay,omitempty"`
+}
+
+// Record is the work a job did in one stage, charged to the user who owns
+// the job and to its team, if any.
+type Record struct {
+	JobID      string    `json:"jobId"`
+	UserID     string    `json:"userId"`
+	TeamID     string    `json:"teamId,omitempty"`
+	CPUSeconds float64   `json:"cpuSeconds"`
+	Candidates uint64    `json:"candidates"`
+	Cracked    int       `json:"cracked"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// JobUsage is the work done by one job within a summary's range.
+type JobUsage struct {
+	JobID      string  `json:"jobId"`
+	CPUSeconds float64 `json:"cpuSeconds"`
+	Candidates uint64  `json:"candidates"`
+	Cracked    int     `json:"cracked"`
+}
+
+// Summary is the work charged to a user or team between From and To.
+type Summary struct {
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	CPUSeconds float64    `json:"cpuSeconds"`
+	Candidates uint64     `json:"candidates"`
+	Cracked    int        `json:"cracked"`
+	Jobs       []JobUsage `json:"jobs"`
+	Quota      Quota      `json:"quota"`
+}
+
+// UsageStore keeps quotas and usage records by subject, see UserSubject and
+// TeamSubject.
+type UsageStore interface {
+	SetQuota(subject string, q Quota) error
+	GetQuota(subject string) (*Quota, error)
+	InsertRecord(r Record) error
+	ListRecords(subject string, from, to time.Time) ([]Record, error)
+}
+
+// UsageService enforces quotas and accounts for the work jobs do. Users
+// without a quota of their own get the default one; teams without one are
+// unlimited.
+type UsageService struct {
+	store    UsageStore
+	defaults Quota
+	now      func() time.Time
+}
+
+func NewUsageService(s UsageStore, defaults Quota) *UsageService {
+	return &UsageService{store: s, defaults: defaults, now: time.Now}
+}
+
+func UserSubject(userId string) string {
+	return "user:" + userId
+}
+
+func TeamSubject(teamId string) string {
+	return "team:" + teamId
+}
+
+func UnmarshalQuota(data []byte) (*Quota, error) {
+	var q Quota
+	err := json.Unmarshal(data, &q)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling quota: %w", err)
+	}
+
+	return &q, nil
+}
+
+func UnmarshalRecord(data []byte) (*Record, error) {
+	var r Record
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling usage record: %w", err)
+	}
+
+	return &r, nil
+}
+
+func (q *Quota) Validate() error {
+	if q.MaxConcurrentJobs < 0 || q.MaxHashesPerJob < 0 || q.MaxCPUSecondsPerDay < 0 {
+		return errors.New("quota limits cannot be negative")
+	}
+
+	return nil
+}
+
+func (s *UsageService) SetQuota(subject string, q Quota) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+
+	return s.store.SetQuota(subject, q)
+}
+
+// Quota returns the quota that applies to subject.
+func (s *UsageService) Quota(subject string) (Quota, error) {
+	q, err := s.store.GetQuota(subject)
+	if err == nil {
+		return *q, nil
+	}
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		return Quota{}, fmt.Errorf("error getting quota: %w", err)
+	}
+
+	if strings.HasPrefix(subject, UserSubject("")) {
+		return s.defaults, nil
+	}
+	return Quota{}, nil
+}
+
+// subjects lists whose quotas apply to a job of userId shared with teamId.
+func subjects(userId, teamId string) []string {
+	if teamId == "" {
+		return []string{UserSubject(userId)}
+	}
+
+	return []string{UserSubject(userId), TeamSubject(teamId)}
+}
+
+// CheckHashes returns ErrQuotaExceeded if a job of userId shared with teamId
+// may not hold n hashes.
+func (s *UsageService) CheckHashes(userId, teamId string, n int) error {
+	for _, subject := range subjects(userId, teamId) {
+		q, err := s.Quota(subject)
+		if err != nil {
+			return err
+		}
+		if q.MaxHashesPerJob > 0 && n > q.MaxHashesPerJob {
+			return fmt.Errorf("%w: %v may submit at most %d hashes per job", ErrQuotaExceeded, subject, q.MaxHashesPerJob)
+		}
+	}
+
+	return nil
+}
+
+// CheckConcurrency returns ErrQuotaExceeded if userId, with activeUser jobs
+// pending or running, or teamId, with activeTeam, may not start another.
+func (s *UsageService) CheckConcurrency(userId, teamId string, activeUser, activeTeam int) error {
+	active := []int{activeUser, activeTeam}
+	for i, subject := range subjects(userId, teamId) {
+		q, err := s.Quota(subject)
+		if err != nil {
+			return err
+		}
+		if q.MaxConcurrentJobs > 0 && active[i] >= q.MaxConcurrentJobs {
+			return fmt.Errorf("%w: %v may run at most %d jobs at once", ErrQuotaExceeded, subject, q.MaxConcurrentJobs)
+		}
+	}
+
+	return nil
+}
+
+// CPUAllowance returns how much CPU time a job of userId shared with teamId
+// may still use today, UTC. It reports false if no daily limit applies.
+func (s *UsageService) CPUAllowance(userId, teamId string) (time.Duration, bool, error) {
+	now := s.now().UTC()
+	midnight := now.Truncate(24 * time.Hour)
+
+	var allowance time.Duration
+	limited := false
+	for _, subject := range subjects(userId, teamId) {
+		q, err := s.Quota(subject)
+		if err != nil {
+			return 0, false, err
+		}
+		if q.MaxCPUSecondsPerDay == 0 {
+			continue
+		}
+
+		records, err := s.store.ListRecords(subject, midnight, now)
+		if err != nil {
+			return 0, false, fmt.Errorf("error listing usage: %w", err)
+		}
+		used := 0.0
+		for _, r := range records {
+			used += r.CPUSeconds
+		}
+
+		left := time.Duration((q.MaxCPUSecondsPerDay - used) * float64(time.Second))
+		if !limited || left < allowance {
+			allowance = left
+		}
+		limited = true
+	}
+
+	return max(allowance, 0), limited, nil
+}
+
+// Record accounts for work a job did.
+func (s *UsageService) Record(r Record) error {
+	if r.RecordedAt.IsZero() {
+		r.RecordedAt = s.now().UTC()
+	}
+
+	return s.store.InsertRecord(r)
+}
+
+// Usage sums up the work charged to subject between from and to, by job.
+func (s *UsageService) Usage(subject string, from, to time.Time) (*Summary, error) {
+	if to.Before(from) {
+		return nil, errors.New("usage range cannot end before it starts")
+	}
+
+	q, err := s.Quota(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.store.ListRecords(subject, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error listing usage: %w", err)
+	}
+
+	summary := &Summary{From: from, To: to, Jobs: make([]JobUsage, 0), Quota: q}
+	jobs := make(map[string]*JobUsage)
+	for _, r := range records {
+		summary.CPUSeconds += r.CPUSeconds
+		summary.Candidates += r.Candidates
+		summary.Cracked += r.Cracked
+
+		job, ok := jobs[r.JobID]
+		if !ok {
+			job = &JobUsage{JobID: r.JobID}
+			jobs[r.JobID] = job
+		}
+		job.CPUSeconds += r.CPUSeconds
+		job.Candidates += r.Candidates
+		job.Cracked += r.Cracked
+	}
+
+	for _, job := range jobs {
+		summary.Jobs = append(summary.Jobs, *job)
+	}
+	sort.Slice(summary.Jobs, func(i, j int) bool {
+		if summary.Jobs[i].CPUSeconds != summary.Jobs[j].CPUSeconds {
+			return summary.Jobs[i].CPUSeconds > summary.Jobs[j].CPUSeconds
+		}
+		return summary.Jobs[i].JobID < summary.Jobs[j].JobID
+	})
+
+	return summary, nil
+}
@@ -0,0 +1,219 @@
+package usage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockUsageStore struct {
+	Quotas  map[string]Quota
+	Records []Record
+}
+
+func (m *MockUsageStore) SetQuota(subject string, q Quota) error {
+	m.Quotas[subject] = q
+	return nil
+}
+
+func (m *MockUsageStore) GetQuota(subject string) (*Quota, error) {
+	q, ok := m.Quotas[subject]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("quota not found")}
+	}
+	return &q, nil
+}
+
+func (m *MockUsageStore) InsertRecord(r Record) error {
+	m.Records = append(m.Records, r)
+	return nil
+}
+
+func (m *MockUsageStore) ListRecords(subject string, from, to time.Time) ([]Record, error) {
+	_, id, _ := strings.Cut(subject, ":")
+	records := make([]Record, 0)
+	for _, r := range m.Records {
+		owner := r.UserID
+		if strings.HasPrefix(subject, TeamSubject("")) {
+			owner = r.TeamID
+		}
+		if owner == id && !r.RecordedAt.Before(from) && r.RecordedAt.Before(to) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func newTestUsageService(quotas map[string]Quota, defaults Quota, now time.Time) (*UsageService, *MockUsageStore) {
+	store := &MockUsageStore{Quotas: quotas}
+	s := NewUsageService(store, defaults)
+	s.now = func() time.Time { return now }
+	return s, store
+}
+
+func TestUsageService_CheckHashes(t *testing.T) {
+	s, _ := newTestUsageService(map[string]Quota{
+		UserSubject("alice"): {MaxHashesPerJob: 10},
+		TeamSubject("red"):   {MaxHashesPerJob: 5},
+	}, Quota{MaxHashesPerJob: 2}, time.Now())
+
+	tests := []struct {
+		name    string
+		userId  string
+		teamId  string
+		n       int
+		wantErr bool
+	}{
+		{"within user quota", "alice", "", 10, false},
+		{"over user quota", "alice", "", 11, true},
+		{"over team quota", "alice", "red", 6, true},
+		{"within default quota", "bob", "", 2, false},
+		{"over default quota", "bob", "", 3, true},
+		{"team without quota", "alice", "blue", 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckHashes(tt.userId, tt.teamId, tt.n)
+			if errors.Is(err, ErrQuotaExceeded) != tt.wantErr {
+				t.Errorf("CheckHashes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUsageService_CheckConcurrency(t *testing.T) {
+	s, _ := newTestUsageService(map[string]Quota{
+		UserSubject("alice"): {MaxConcurrentJobs: 2},
+		TeamSubject("red"):   {MaxConcurrentJobs: 3},
+	}, Quota{}, time.Now())
+
+	tests := []struct {
+		name       string
+		userId     string
+		teamId     string
+		activeUser int
+		activeTeam int
+		wantErr    bool
+	}{
+		{"below user quota", "alice", "", 1, 0, false},
+		{"at user quota", "alice", "", 2, 0, true},
+		{"at team quota", "alice", "red", 1, 3, true},
+		{"unlimited", "bob", "", 100, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckConcurrency(tt.userId, tt.teamId, tt.activeUser, tt.activeTeam)
+			if errors.Is(err, ErrQuotaExceeded) != tt.wantErr {
+				t.Errorf("CheckConcurrency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUsageService_CPUAllowance(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s, _ := newTestUsageService(map[string]Quota{
+		UserSubject("alice"): {MaxCPUSecondsPerDay: 100},
+		TeamSubject("red"):   {MaxCPUSecondsPerDay: 50},
+	}, Quota{}, now)
+
+	records := []Record{
+		{JobID: "a", UserID: "alice", CPUSeconds: 500, RecordedAt: now.Add(-13 * time.Hour)},
+		{JobID: "b", UserID: "alice", CPUSeconds: 30, RecordedAt: now.Add(-time.Hour)},
+		{JobID: "c", UserID: "bob", TeamID: "red", CPUSeconds: 40, RecordedAt: now.Add(-time.Hour)},
+	}
+	for _, r := range records {
+		if err := s.Record(r); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		userId      string
+		teamId      string
+		want        time.Duration
+		wantLimited bool
+	}{
+		{"user quota", "alice", "", 70 * time.Second, true},
+		{"team quota is lower", "alice", "red", 10 * time.Second, true},
+		{"unlimited", "bob", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, limited, err := s.CPUAllowance(tt.userId, tt.teamId)
+			if err != nil {
+				t.Fatalf("CPUAllowance() error = %v", err)
+			}
+			if got != tt.want || limited != tt.wantLimited {
+				t.Errorf("CPUAllowance() got = %v, %v, want %v, %v", got, limited, tt.want, tt.wantLimited)
+			}
+		})
+	}
+
+	s.Record(Record{JobID: "d", UserID: "alice", CPUSeconds: 100, RecordedAt: now})
+	s.now = func() time.Time { return now.Add(time.Second) }
+	got, limited, err := s.CPUAllowance("alice", "")
+	if err != nil || got != 0 || !limited {
+		t.Errorf("CPUAllowance() over quota got = %v, %v, %v, want 0, true", got, limited, err)
+	}
+}
+
+func TestUsageService_Usage(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s, _ := newTestUsageService(map[string]Quota{}, Quota{MaxConcurrentJobs: 1}, now)
+
+	records := []Record{
+		{JobID: "a", UserID: "alice", CPUSeconds: 1, Candidates: 10, Cracked: 1, RecordedAt: now.Add(-time.Hour)},
+		{JobID: "b", UserID: "alice", CPUSeconds: 5, Candidates: 50, RecordedAt: now.Add(-time.Hour)},
+		{JobID: "a", UserID: "alice", CPUSeconds: 2, Candidates: 20, Cracked: 2, RecordedAt: now.Add(-time.Minute)},
+		{JobID: "c", UserID: "alice", CPUSeconds: 9, RecordedAt: now.Add(-48 * time.Hour)},
+		{JobID: "d", UserID: "bob", CPUSeconds: 9, RecordedAt: now.Add(-time.Minute)},
+	}
+	for _, r := range records {
+		if err := s.Record(r); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	got, err := s.Usage(UserSubject("alice"), now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if got.CPUSeconds != 8 || got.Candidates != 80 || got.Cracked != 3 {
+		t.Errorf("Usage() totals got = %v, %v, %v, want 8, 80, 3", got.CPUSeconds, got.Candidates, got.Cracked)
+	}
+	want := []JobUsage{{JobID: "b", CPUSeconds: 5, Candidates: 50}, {JobID: "a", CPUSeconds: 3, Candidates: 30, Cracked: 3}}
+	if len(got.Jobs) != len(want) || got.Jobs[0] != want[0] || got.Jobs[1] != want[1] {
+		t.Errorf("Usage() jobs got = %v, want %v", got.Jobs, want)
+	}
+	if got.Quota.MaxConcurrentJobs != 1 {
+		t.Errorf("Usage() quota got = %v, want the default quota", got.Quota)
+	}
+
+	_, err = s.Usage(UserSubject("alice"), now, now.Add(-time.Hour))
+	if err == nil {
+		t.Errorf("Usage() with reversed range error = nil, want an error")
+	}
+}
+
+func TestUsageService_SetQuota(t *testing.T) {
+	s, _ := newTestUsageService(map[string]Quota{}, Quota{}, time.Now())
+
+	err := s.SetQuota(UserSubject("alice"), Quota{MaxHashesPerJob: -1})
+	if err == nil {
+		t.Errorf("SetQuota() with negative limit error = nil, want an error")
+	}
+
+	err = s.SetQuota(TeamSubject("red"), Quota{MaxHashesPerJob: 3})
+	if err != nil {
+		t.Fatalf("SetQuota() error = %v", err)
+	}
+	q, err := s.Quota(TeamSubject("red"))
+	if err != nil || q.MaxHashesPerJob != 3 {
+		t.Errorf("Quota() got = %v, %v, want the quota set", q, err)
+	}
+}
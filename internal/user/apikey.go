@@ -0,0 +1,175 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix marks unhash API keys, so leaked ones are easy to scan for.
+const apiKeyPrefix = "uh_"
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// APIKey is a key a user authenticates with. Only a hash of the key is
+// stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+type APIKeyStore interface {
+	InsertAPIKey(k *APIKey) error
+	GetAPIKeyByHash(hash string) (*APIKey, error)
+	ListAPIKeys(userId string) ([]APIKey, error)
+	UpdateAPIKey(k *APIKey) error
+}
+
+// AuthService authenticates callers as users, by API key or by password
+// and session token.
+type AuthService struct {
+	keys        APIKeyStore
+	credentials CredentialStore
+	sessions    SessionStore
+	users       *UserService
+	sessionTTL  time.Duration
+	argon2      Argon2Params
+}
+
+func NewAuthService(keys APIKeyStore, credentials CredentialStore, sessions SessionStore, users *UserService, sessionTTL time.Duration) *AuthService {
+	return &AuthService{
+		keys:        keys,
+		credentials: credentials,
+		sessions:    sessions,
+		users:       users,
+		sessionTTL:  sessionTTL,
+		argon2:      DefaultArgon2Params,
+	}
+}
+
+func UnmarshalAPIKey(data []byte) (*APIKey, error) {
+	var k APIKey
+	err := json.Unmarshal(data, &k)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling api key: %w", err)
+	}
+
+	return &k, nil
+}
+
+// hashAPIKey hashes key for storage. Keys are long and random, so a fast
+// hash is enough to keep them from being recovered.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey generates a new key for u, returning the key along with its
+// stored record.
+func (a *AuthService) CreateAPIKey(u *User, name string) (string, *APIKey, error) {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating api key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	k := &APIKey{
+		ID:        uuid.New().String(),
+		UserID:    u.ID,
+		Name:      name,
+		Hash:      hashAPIKey(key),
+		CreatedAt: time.Now().UTC(),
+	}
+	err = a.keys.InsertAPIKey(k)
+	if err != nil {
+		return "", nil, err
+	}
+
+	k.Hash = ""
+	return key, k, nil
+}
+
+// AuthenticateAPIKey resolves key to the user it belongs to. Unknown and
+// revoked keys, and keys of deleted and soft-deleted users, give
+// ErrInvalidCredentials.
+func (a *AuthService) AuthenticateAPIKey(key string) (*User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidCredentials
+	}
+
+	k, err := a.keys.GetAPIKeyByHash(hashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("error getting api key: %w", err)
+	}
+	if k.RevokedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	u, err := a.users.GetUser(k.UserID)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if u.Deleted() {
+		return nil, ErrInvalidCredentials
+	}
+
+	return u, nil
+}
+
+// ListAPIKeys lists the keys of u, without their hashes.
+func (a *AuthService) ListAPIKeys(u *User) ([]APIKey, error) {
+	keys, err := a.keys.ListAPIKeys(u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the key of u with the given id. Revoking a key twice
+// is not an error.
+func (a *AuthService) RevokeAPIKey(u *User, id string) error {
+	keys, err := a.keys.ListAPIKeys(u.ID)
+	if err != nil {
+		return fmt.Errorf("error listing api keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if k.ID != id {
+			continue
+		}
+		if k.RevokedAt != nil {
+			return nil
+		}
+
+		now := time.Now().UTC()
+		k.RevokedAt = &now
+		return a.keys.UpdateAPIKey(&k)
+	}
+
+	return fmt.Errorf("api key not found: %w", &uerr.ErrorNotFound{Err: errors.New("api key not owned by user")})
+}
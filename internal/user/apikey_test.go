@@ -0,0 +1,196 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockUserStore struct {
+	Users map[string]User
+	// Query is the last query ListUsers was called with.
+	Query UserQuery
+}
+
+func (m *MockUserStore) InsertUser(u *User) error {
+	m.Users[u.ID] = *u
+	return nil
+}
+
+func (m *MockUserStore) GetUser(id string) (*User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("user not found")}
+	}
+	return &u, nil
+}
+
+func (m *MockUserStore) GetUserByEmail(email string) (*User, error) {
+	for _, u := range m.Users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, &uerr.ErrorNotFound{Err: errors.New("user not found")}
+}
+
+func (m *MockUserStore) GetUserByUsername(username string) (*User, error) {
+	for _, u := range m.Users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, &uerr.ErrorNotFound{Err: errors.New("user not found")}
+}
+
+func (m *MockUserStore) UpdateUser(u *User) error {
+	if _, ok := m.Users[u.ID]; !ok {
+		return &uerr.ErrorNotFound{Err: errors.New("user not found")}
+	}
+	m.Users[u.ID] = *u
+	return nil
+}
+
+func (m *MockUserStore) DeleteUser(id string) error {
+	delete(m.Users, id)
+	return nil
+}
+
+func (m *MockUserStore) ListUsers(q UserQuery) (*UserPage, error) {
+	m.Query = q
+	page := &UserPage{Users: make([]User, 0, len(m.Users)), Total: len(m.Users)}
+	for _, u := range m.Users {
+		page.Users = append(page.Users, u)
+	}
+	return page, nil
+}
+
+// MockUserCache keys users by id and email like the Redis cache does.
+type MockUserCache struct {
+	ByID    map[string]User
+	ByEmail map[string]User
+}
+
+func (m *MockUserCache) GetUser(id string) (*User, error) {
+	u, ok := m.ByID[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("user not cached")}
+	}
+	return &u, nil
+}
+
+func (m *MockUserCache) GetUserByEmail(email string) (*User, error) {
+	u, ok := m.ByEmail[email]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("user not cached")}
+	}
+	return &u, nil
+}
+
+func (m *MockUserCache) SetUser(u *User) error {
+	if m.ByID == nil {
+		m.ByID = make(map[string]User)
+		m.ByEmail = make(map[string]User)
+	}
+	m.ByID[u.ID] = *u
+	m.ByEmail[u.Email] = *u
+	return nil
+}
+
+func (m *MockUserCache) ClearUser(u *User) error {
+	delete(m.ByID, u.ID)
+	delete(m.ByEmail, u.Email)
+	return nil
+}
+
+type MockAPIKeyStore struct {
+	Keys []APIKey
+}
+
+func (m *MockAPIKeyStore) InsertAPIKey(k *APIKey) error {
+	m.Keys = append(m.Keys, *k)
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	for _, k := range m.Keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+	return nil, &uerr.ErrorNotFound{Err: errors.New("api key not found")}
+}
+
+func (m *MockAPIKeyStore) ListAPIKeys(userId string) ([]APIKey, error) {
+	keys := make([]APIKey, 0)
+	for _, k := range m.Keys {
+		if k.UserID == userId {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MockAPIKeyStore) UpdateAPIKey(k *APIKey) error {
+	for i := range m.Keys {
+		if m.Keys[i].ID == k.ID {
+			m.Keys[i] = *k
+			return nil
+		}
+	}
+	return &uerr.ErrorNotFound{Err: errors.New("api key not found")}
+}
+
+func TestAuthService_APIKeys(t *testing.T) {
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com"}
+	bob := User{ID: "bob", Username: "bob", Email: "bob@example.com"}
+	users := NewUserService(&MockUserStore{Users: map[string]User{"alice": alice, "bob": bob}}, &MockUserCache{})
+	keys := &MockAPIKeyStore{}
+	a := NewAuthService(keys, &MockCredentialStore{}, &MockSessionStore{}, users, DefaultSessionTTL)
+
+	key, k, err := a.CreateAPIKey(&alice, "ci")
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if k.Hash != "" || keys.Keys[0].Hash == "" || keys.Keys[0].Hash == key {
+		t.Errorf("CreateAPIKey() stored hash = %v, returned hash = %v, want only a hash of the key stored", keys.Keys[0].Hash, k.Hash)
+	}
+
+	u, err := a.AuthenticateAPIKey(key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() error = %v", err)
+	}
+	if u.ID != alice.ID {
+		t.Errorf("AuthenticateAPIKey() got = %v, want %v", u.ID, alice.ID)
+	}
+
+	for _, bad := range []string{"", "uh_nope", key + "x", "x" + key} {
+		_, err = a.AuthenticateAPIKey(bad)
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("AuthenticateAPIKey(%q) error = %v, want %v", bad, err, ErrInvalidCredentials)
+		}
+	}
+
+	err = a.RevokeAPIKey(&bob, k.ID)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("RevokeAPIKey() of another user's key error = %v, want not found", err)
+	}
+
+	err = a.RevokeAPIKey(&alice, k.ID)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	_, err = a.AuthenticateAPIKey(key)
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("AuthenticateAPIKey() with revoked key error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	list, err := a.ListAPIKeys(&alice)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if len(list) != 1 || list[0].RevokedAt == nil || list[0].Hash != "" {
+		t.Errorf("ListAPIKeys() got = %v, want one revoked key without its hash", list)
+	}
+}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// UserSort is the field users are listed in order of. Users with the same
+// value are ordered by id, so every listing has a total order to page through.
+type UserSort string
+
+const (
+	UserSortID       UserSort = "id"
+	UserSortUsername UserSort = "username"
+	UserSortEmail    UserSort = "email"
+)
+
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 500
+)
+
+var ErrInvalidUserQuery = errors.New("invalid user query")
+
+// UserQuery selects a page of users. Search matches a prefix of the username
+// or email. Cursor is the NextCursor of the previous page, and must come from
+// a query with the same Sort and Desc.
+type UserQuery struct {
+	Search string
+	Sort   UserSort
+	Desc   bool
+	Limit  int
+	Cursor string
+}
+
+// UserPage is a page of users. Total counts every user matching the search,
+// not just those on the page. NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// UserCursor is the position after the last user of a page.
+type UserCursor struct {
+	Sort  UserSort `json:"s"`
+	Desc  bool     `json:"d,omitempty"`
+	Value string   `json:"v"`
+	ID    string   `json:"id"`
+}
+
+// SortValue returns the value of the field users are sorted by.
+func (user *User) SortValue(sort UserSort) string {
+	switch sort {
+	case UserSortUsername:
+		return user.Username
+	case UserSortEmail:
+		return user.Email
+	default:
+		return user.ID
+	}
+}
+
+// NewUserCursor returns the cursor of the page following user.
+func NewUserCursor(user *User, q UserQuery) string {
+	raw, _ := json.Marshal(UserCursor{Sort: q.Sort, Desc: q.Desc, Value: user.SortValue(q.Sort), ID: user.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor returns the position q.Cursor points at, or nil for the first
+// page.
+func (q UserQuery) DecodeCursor() (*UserCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidUserQuery)
+	}
+
+	var c UserCursor
+	err = json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidUserQuery)
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, fmt.Errorf("%w: cursor is for a different sort order", ErrInvalidUserQuery)
+	}
+
+	return &c, nil
+}
+
+// normalize fills in the defaults of q and checks it is well formed.
+func (q *UserQuery) normalize() error {
+	switch q.Sort {
+	case "":
+		q.Sort = UserSortUsername
+	case UserSortID, UserSortUsername, UserSortEmail:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", ErrInvalidUserQuery, q.Sort)
+	}
+
+	switch {
+	case q.Limit < 0:
+		return fmt.Errorf("%w: negative limit", ErrInvalidUserQuery)
+	case q.Limit == 0:
+		q.Limit = DefaultUserPageSize
+	case q.Limit > MaxUserPageSize:
+		q.Limit = MaxUserPageSize
+	}
+
+	_, err := q.DecodeCursor()
+	return err
+}
+
+// ListUsers returns a page of users, soft-deleted ones included so they can
+// be found and restored.
+func (u *UserService) ListUsers(q UserQuery) (*UserPage, error) {
+	err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := u.store.ListUsers(q)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users: %w", err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,235 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// sessionTokenPrefix tells session tokens apart from API keys.
+	sessionTokenPrefix = "uhs_"
+
+	minPasswordLength = 8
+	maxPasswordLength = 1024
+
+	DefaultSessionTTL = 24 * time.Hour
+)
+
+var ErrWeakPassword = fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+
+// Argon2Params are the Argon2id parameters a password was hashed with. They
+// are stored with each credential so they can be raised without
+// invalidating existing passwords.
+type Argon2Params struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keyLen"`
+}
+
+// DefaultArgon2Params follow the second recommended option of RFC 9106.
+var DefaultArgon2Params = Argon2Params{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: 32}
+
+// Credential is a user's hashed password.
+type Credential struct {
+	UserID    string       `json:"userId"`
+	Salt      []byte       `json:"salt"`
+	Hash      []byte       `json:"hash"`
+	Params    Argon2Params `json:"params"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+type CredentialStore interface {
+	SetCredential(c *Credential) error
+	GetCredential(userId string) (*Credential, error)
+}
+
+// SessionStore keeps sessions by the hash of their token. GetSession
+// extends the session's expiry to ttl from now.
+type SessionStore interface {
+	SetSession(tokenHash, userId string, ttl time.Duration) error
+	GetSession(tokenHash string, ttl time.Duration) (string, error)
+	DeleteSession(tokenHash string) error
+	DeleteUserSessions(userId string) error
+}
+
+func UnmarshalCredential(data []byte) (*Credential, error) {
+	var c Credential
+	err := json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling credential: %w", err)
+	}
+
+	return &c, nil
+}
+
+// ValidatePassword returns ErrWeakPassword unless password is an acceptable
+// length, so it can be checked before anything is stored for a new user.
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+func newCredential(userId, password string, params Argon2Params) (*Credential, error) {
+	err := ValidatePassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("error generating salt: %w", err)
+	}
+
+	return &Credential{
+		UserID:    userId,
+		Salt:      salt,
+		Hash:      argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen),
+		Params:    params,
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Matches reports whether password is the one c was created from.
+func (c *Credential) Matches(password string) bool {
+	hash := argon2.IDKey([]byte(password), c.Salt, c.Params.Time, c.Params.Memory, c.Params.Threads, uint32(len(c.Hash)))
+	return subtle.ConstantTimeCompare(hash, c.Hash) == 1
+}
+
+// hashSessionToken hashes token for storage, so a leaked cache does not
+// leak usable sessions.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsSessionToken reports whether token is a session token rather than an API
+// key.
+func IsSessionToken(token string) bool {
+	return strings.HasPrefix(token, sessionTokenPrefix)
+}
+
+// SetPassword sets the password of u.
+func (a *AuthService) SetPassword(u *User, password string) error {
+	c, err := newCredential(u.ID, password, a.argon2)
+	if err != nil {
+		return err
+	}
+
+	return a.credentials.SetCredential(c)
+}
+
+// ChangePassword replaces the password of u after checking the current one,
+// ending every session u has open.
+func (a *AuthService) ChangePassword(u *User, current, password string) error {
+	c, err := a.credentials.GetCredential(u.ID)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return ErrInvalidCredentials
+		}
+		return fmt.Errorf("error getting credential: %w", err)
+	}
+	if !c.Matches(current) {
+		return ErrInvalidCredentials
+	}
+
+	err = a.SetPassword(u, password)
+	if err != nil {
+		return err
+	}
+
+	return a.sessions.DeleteUserSessions(u.ID)
+}
+
+// EndSessions logs u out everywhere by deleting every session it has open.
+func (a *AuthService) EndSessions(u *User) error {
+	return a.sessions.DeleteUserSessions(u.ID)
+}
+
+// Login checks the password of the user with email and opens a session,
+// returning its token.
+func (a *AuthService) Login(email, password string) (string, *User, error) {
+	u, err := a.users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return "", nil, ErrInvalidCredentials
+		}
+		return "", nil, err
+	}
+	if u.Deleted() {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	c, err := a.credentials.GetCredential(u.ID)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return "", nil, ErrInvalidCredentials
+		}
+		return "", nil, fmt.Errorf("error getting credential: %w", err)
+	}
+	if !c.Matches(password) {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating session token: %w", err)
+	}
+	token := sessionTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	err = a.sessions.SetSession(hashSessionToken(token), u.ID, a.sessionTTL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, u, nil
+}
+
+// Logout ends the session of token.
+func (a *AuthService) Logout(token string) error {
+	return a.sessions.DeleteSession(hashSessionToken(token))
+}
+
+// Authenticate resolves an API key or session token to the user it belongs
+// to, extending the session if it is one.
+func (a *AuthService) Authenticate(token string) (*User, error) {
+	if !IsSessionToken(token) {
+		return a.AuthenticateAPIKey(token)
+	}
+
+	userId, err := a.sessions.GetSession(hashSessionToken(token), a.sessionTTL)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+
+	u, err := a.users.GetUser(userId)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if u.Deleted() {
+		return nil, ErrInvalidCredentials
+	}
+
+	return u, nil
+}
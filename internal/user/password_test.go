@@ -0,0 +1,201 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockCredentialStore struct {
+	Credentials map[string]Credential
+}
+
+func (m *MockCredentialStore) SetCredential(c *Credential) error {
+	if m.Credentials == nil {
+		m.Credentials = make(map[string]Credential)
+	}
+	m.Credentials[c.UserID] = *c
+	return nil
+}
+
+func (m *MockCredentialStore) GetCredential(userId string) (*Credential, error) {
+	c, ok := m.Credentials[userId]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("credential not found")}
+	}
+	return &c, nil
+}
+
+type MockSessionStore struct {
+	Sessions map[string]string
+	TTLs     map[string]time.Duration
+}
+
+func (m *MockSessionStore) SetSession(tokenHash, userId string, ttl time.Duration) error {
+	if m.Sessions == nil {
+		m.Sessions = make(map[string]string)
+		m.TTLs = make(map[string]time.Duration)
+	}
+	m.Sessions[tokenHash] = userId
+	m.TTLs[tokenHash] = ttl
+	return nil
+}
+
+func (m *MockSessionStore) GetSession(tokenHash string, ttl time.Duration) (string, error) {
+	userId, ok := m.Sessions[tokenHash]
+	if !ok {
+		return "", &uerr.ErrorNotFound{Err: errors.New("session not found")}
+	}
+	m.TTLs[tokenHash] = ttl
+	return userId, nil
+}
+
+func (m *MockSessionStore) DeleteSession(tokenHash string) error {
+	delete(m.Sessions, tokenHash)
+	return nil
+}
+
+func (m *MockSessionStore) DeleteUserSessions(userId string) error {
+	for hash, id := range m.Sessions {
+		if id == userId {
+			delete(m.Sessions, hash)
+		}
+	}
+	return nil
+}
+
+func newTestAuthService(users ...User) (*AuthService, *MockCredentialStore, *MockSessionStore) {
+	userMap := make(map[string]User)
+	for _, u := range users {
+		userMap[u.ID] = u
+	}
+
+	credentials := &MockCredentialStore{}
+	sessions := &MockSessionStore{}
+	a := NewAuthService(&MockAPIKeyStore{}, credentials, sessions, NewUserService(&MockUserStore{Users: userMap}, &MockUserCache{}), time.Hour)
+	// Keep tests fast; the parameters are stored with each credential.
+	a.argon2 = Argon2Params{Time: 1, Memory: 64, Threads: 1, KeyLen: 32}
+
+	return a, credentials, sessions
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"Test ValidatePassword", "correct horse", false},
+		{"Test ValidatePassword too short", "short", true},
+		{"Test ValidatePassword too long", strings.Repeat("a", maxPasswordLength+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			if tt.wantErr != errors.Is(err, ErrWeakPassword) {
+				t.Errorf("ValidatePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthService_Login(t *testing.T) {
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com"}
+	bob := User{ID: "bob", Username: "bob", Email: "bob@example.com"}
+	a, credentials, sessions := newTestAuthService(alice, bob)
+
+	err := a.SetPassword(&alice, "short")
+	if !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("SetPassword() error = %v, want %v", err, ErrWeakPassword)
+	}
+	err = a.SetPassword(&alice, "correct horse")
+	if err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if string(credentials.Credentials["alice"].Hash) == "correct horse" || len(credentials.Credentials["alice"].Salt) == 0 {
+		t.Errorf("SetPassword() stored %v, want a salted hash", credentials.Credentials["alice"])
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{name: "Test Login with wrong password", email: alice.Email, password: "wrong horse"},
+		{name: "Test Login with unknown email", email: "carol@example.com", password: "correct horse"},
+		{name: "Test Login without password set", email: bob.Email, password: "correct horse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := a.Login(tt.email, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+
+	token, u, err := a.Login(alice.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if u.ID != alice.ID || !IsSessionToken(token) {
+		t.Errorf("Login() got = %v, %v, want a session token for alice", token, u.ID)
+	}
+	if _, ok := sessions.Sessions[token]; ok {
+		t.Errorf("Login() stored the session token unhashed")
+	}
+
+	u, err = a.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if u.ID != alice.ID {
+		t.Errorf("Authenticate() got = %v, want %v", u.ID, alice.ID)
+	}
+
+	err = a.Logout(token)
+	if err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	_, err = a.Authenticate(token)
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate() after Logout() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com"}
+	a, _, _ := newTestAuthService(alice)
+
+	err := a.SetPassword(&alice, "correct horse")
+	if err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	token, _, err := a.Login(alice.Email, "correct horse")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	err = a.ChangePassword(&alice, "wrong horse", "battery staple")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("ChangePassword() with wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	err = a.ChangePassword(&alice, "correct horse", "battery staple")
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err = a.Authenticate(token); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate() after ChangePassword() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, _, err = a.Login(alice.Email, "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with old password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, _, err = a.Login(alice.Email, "battery staple"); err != nil {
+		t.Errorf("Login() with new password error = %v", err)
+	}
+}
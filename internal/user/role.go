@@ -0,0 +1,47 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Role decides what a user may do. Each role may do everything the roles
+// below it may.
+type Role string
+
+const (
+	// RoleViewer may read the jobs and resources visible to them.
+	RoleViewer Role = "viewer"
+	// RoleAnalyst may also create and manage their own jobs and resources.
+	RoleAnalyst Role = "analyst"
+	// RoleAdmin may also manage users and access every job.
+	RoleAdmin Role = "admin"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+var roleRanks = map[Role]int{
+	RoleViewer:  1,
+	RoleAnalyst: 2,
+	RoleAdmin:   3,
+}
+
+func (r Role) Validate() error {
+	if _, ok := roleRanks[r]; !ok {
+		return fmt.Errorf("%w: `%v`", ErrInvalidRole, r)
+	}
+
+	return nil
+}
+
+// AtLeast reports whether r grants everything min does. Unknown roles grant
+// nothing.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[min]
+}
+
+// IsAdmin reports whether u is an admin.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
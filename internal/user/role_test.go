@@ -0,0 +1,27 @@
+package user
+
+import "testing"
+
+func TestRole_AtLeast(t *testing.T) {
+	tests := []struct {
+		role Role
+		min  Role
+		want bool
+	}{
+		{role: RoleAdmin, min: RoleViewer, want: true},
+		{role: RoleAdmin, min: RoleAdmin, want: true},
+		{role: RoleAnalyst, min: RoleAnalyst, want: true},
+		{role: RoleAnalyst, min: RoleAdmin, want: false},
+		{role: RoleViewer, min: RoleAnalyst, want: false},
+		{role: Role(""), min: RoleViewer, want: false},
+		{role: Role("root"), min: RoleViewer, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run("Test AtLeast "+string(tt.role)+" "+string(tt.min), func(t *testing.T) {
+			if got := tt.role.AtLeast(tt.min); got != tt.want {
+				t.Errorf("AtLeast() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
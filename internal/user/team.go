@@ -0,0 +1,204 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotTeamAdmin  = errors.New("only team admins may manage the team")
+	ErrLastTeamAdmin = errors.New("a team must keep at least one admin")
+	ErrEmptyTeamName = errors.New("team name cannot be empty")
+)
+
+// TeamMember is a user in a team. Team admins may manage the team's
+// membership and delete its jobs.
+type TeamMember struct {
+	UserID string `json:"userId"`
+	Admin  bool   `json:"admin"`
+}
+
+// Team is a group of users sharing the jobs owned by the team.
+type Team struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Members   []TeamMember `json:"members"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type TeamStore interface {
+	InsertTeam(t *Team) error
+	GetTeam(id string) (*Team, error)
+	UpdateTeam(t *Team) error
+	ListTeamsByMember(userId string) ([]Team, error)
+}
+
+type TeamService struct {
+	store TeamStore
+	users *UserService
+}
+
+func NewTeamService(s TeamStore, users *UserService) *TeamService {
+	return &TeamService{store: s, users: users}
+}
+
+func UnmarshalTeam(data []byte) (*Team, error) {
+	var t Team
+	err := json.Unmarshal(data, &t)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling team: %w", err)
+	}
+
+	return &t, nil
+}
+
+// Member returns the membership of the user with userId, if any.
+func (t *Team) Member(userId string) (TeamMember, bool) {
+	i := slices.IndexFunc(t.Members, func(m TeamMember) bool { return m.UserID == userId })
+	if i < 0 {
+		return TeamMember{}, false
+	}
+
+	return t.Members[i], true
+}
+
+// IsMember reports whether the user with userId is in t.
+func (t *Team) IsMember(userId string) bool {
+	_, ok := t.Member(userId)
+	return ok
+}
+
+// ManageableBy reports whether u may manage t: its team admins and admins
+// may.
+func (t *Team) ManageableBy(u *User) bool {
+	m, ok := t.Member(u.ID)
+	return u.IsAdmin() || ok && m.Admin
+}
+
+func (t *Team) admins() int {
+	n := 0
+	for _, m := range t.Members {
+		if m.Admin {
+			n++
+		}
+	}
+
+	return n
+}
+
+// CreateTeam creates a team with creator as its first admin.
+func (s *TeamService) CreateTeam(name string, creator *User) (*Team, error) {
+	if name == "" {
+		return nil, ErrEmptyTeamName
+	}
+
+	t := &Team{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Members:   []TeamMember{{UserID: creator.ID, Admin: true}},
+		CreatedAt: time.Now().UTC(),
+	}
+	err := s.store.InsertTeam(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// GetTeam returns the team with id if u is a member or an admin; otherwise
+// it is reported as not found.
+func (s *TeamService) GetTeam(id string, u *User) (*Team, error) {
+	t, err := s.store.GetTeam(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("team not found: %w", err)
+		}
+		return nil, fmt.Errorf("error getting team: %w", err)
+	}
+
+	if !u.IsAdmin() && !t.IsMember(u.ID) {
+		return nil, fmt.Errorf("team not found: %w", &uerr.ErrorNotFound{Err: errors.New("user is not a member")})
+	}
+
+	return t, nil
+}
+
+// ListTeams lists the teams u is a member of.
+func (s *TeamService) ListTeams(u *User) ([]Team, error) {
+	teams, err := s.store.ListTeamsByMember(u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing teams: %w", err)
+	}
+
+	return teams, nil
+}
+
+// SetMember adds the user with userId to the team, or changes whether they
+// are a team admin if they already are a member. Only those who may manage
+// the team may do so.
+func (s *TeamService) SetMember(id string, caller *User, userId string, admin bool) (*Team, error) {
+	t, err := s.GetTeam(id, caller)
+	if err != nil {
+		return nil, err
+	}
+	if !t.ManageableBy(caller) {
+		return nil, ErrNotTeamAdmin
+	}
+
+	_, err = s.users.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	i := slices.IndexFunc(t.Members, func(m TeamMember) bool { return m.UserID == userId })
+	if i < 0 {
+		t.Members = append(t.Members, TeamMember{UserID: userId, Admin: admin})
+	} else {
+		if t.Members[i].Admin && !admin && t.admins() == 1 {
+			return nil, ErrLastTeamAdmin
+		}
+		t.Members[i].Admin = admin
+	}
+
+	err = s.store.UpdateTeam(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// RemoveMember removes the user with userId from the team. Members may
+// leave on their own; removing others takes a team admin.
+func (s *TeamService) RemoveMember(id string, caller *User, userId string) (*Team, error) {
+	t, err := s.GetTeam(id, caller)
+	if err != nil {
+		return nil, err
+	}
+	if userId != caller.ID && !t.ManageableBy(caller) {
+		return nil, ErrNotTeamAdmin
+	}
+
+	m, ok := t.Member(userId)
+	if !ok {
+		return nil, fmt.Errorf("member not found: %w", &uerr.ErrorNotFound{Err: errors.New("user is not a member")})
+	}
+	if m.Admin && t.admins() == 1 {
+		return nil, ErrLastTeamAdmin
+	}
+
+	t.Members = slices.DeleteFunc(t.Members, func(m TeamMember) bool { return m.UserID == userId })
+	err = s.store.UpdateTeam(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
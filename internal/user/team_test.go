@@ -0,0 +1,108 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockTeamStore struct {
+	Teams map[string]Team
+}
+
+func (m *MockTeamStore) InsertTeam(t *Team) error {
+	m.Teams[t.ID] = *t
+	return nil
+}
+
+func (m *MockTeamStore) GetTeam(id string) (*Team, error) {
+	t, ok := m.Teams[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("team not found")}
+	}
+	t.Members = append([]TeamMember(nil), t.Members...)
+	return &t, nil
+}
+
+func (m *MockTeamStore) UpdateTeam(t *Team) error {
+	m.Teams[t.ID] = *t
+	return nil
+}
+
+func (m *MockTeamStore) ListTeamsByMember(userId string) ([]Team, error) {
+	teams := make([]Team, 0)
+	for _, t := range m.Teams {
+		if t.IsMember(userId) {
+			teams = append(teams, t)
+		}
+	}
+	return teams, nil
+}
+
+func TestTeamService(t *testing.T) {
+	alice := User{ID: "alice", Role: RoleAnalyst}
+	bob := User{ID: "bob", Role: RoleAnalyst}
+	carol := User{ID: "carol", Role: RoleViewer}
+	root := User{ID: "root", Role: RoleAdmin}
+	users := NewUserService(&MockUserStore{Users: map[string]User{"alice": alice, "bob": bob, "carol": carol, "root": root}}, &MockUserCache{})
+	store := &MockTeamStore{Teams: make(map[string]Team)}
+	s := NewTeamService(store, users)
+
+	_, err := s.CreateTeam("", &alice)
+	if !errors.Is(err, ErrEmptyTeamName) {
+		t.Errorf("CreateTeam() error = %v, want %v", err, ErrEmptyTeamName)
+	}
+
+	team, err := s.CreateTeam("red", &alice)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+	if !team.ManageableBy(&alice) {
+		t.Errorf("CreateTeam() got = %v, want alice as admin", team)
+	}
+
+	_, err = s.GetTeam(team.ID, &bob)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("GetTeam() by non-member error = %v, want not found", err)
+	}
+	if _, err = s.GetTeam(team.ID, &root); err != nil {
+		t.Errorf("GetTeam() by admin error = %v", err)
+	}
+
+	if _, err = s.SetMember(team.ID, &alice, "bob", false); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	_, err = s.SetMember(team.ID, &bob, "carol", false)
+	if !errors.Is(err, ErrNotTeamAdmin) {
+		t.Errorf("SetMember() by member error = %v, want %v", err, ErrNotTeamAdmin)
+	}
+	_, err = s.SetMember(team.ID, &alice, "nobody", false)
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("SetMember() of unknown user error = %v, want not found", err)
+	}
+	_, err = s.SetMember(team.ID, &alice, "alice", false)
+	if !errors.Is(err, ErrLastTeamAdmin) {
+		t.Errorf("SetMember() demoting last admin error = %v, want %v", err, ErrLastTeamAdmin)
+	}
+	_, err = s.RemoveMember(team.ID, &alice, "alice")
+	if !errors.Is(err, ErrLastTeamAdmin) {
+		t.Errorf("RemoveMember() of last admin error = %v, want %v", err, ErrLastTeamAdmin)
+	}
+
+	teams, err := s.ListTeams(&bob)
+	if err != nil {
+		t.Fatalf("ListTeams() error = %v", err)
+	}
+	if len(teams) != 1 || teams[0].ID != team.ID {
+		t.Errorf("ListTeams() got = %v, want red", teams)
+	}
+
+	team, err = s.RemoveMember(team.ID, &bob, "bob")
+	if err != nil {
+		t.Fatalf("RemoveMember() of self error = %v", err)
+	}
+	if team.IsMember("bob") {
+		t.Errorf("RemoveMember() got = %v, want bob removed", team)
+	}
+}
@@ -7,12 +7,20 @@ import (
 	"github.com/fmdunlap/unhash/internal/uerr"
 	"io"
 	"log"
+	"net/mail"
+	"strings"
+	"time"
 )
 
 type User struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Role          Role   `json:"role"`
+	// DeletedAt is set while the user is soft-deleted. Soft-deleted users
+	// cannot authenticate but can be restored.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
 type UserCache interface {
@@ -26,10 +34,25 @@ type UserStore interface {
 	InsertUser(u *User) error
 	GetUser(id string) (*User, error)
 	GetUserByEmail(email string) (*User, error)
+	GetUserByUsername(username string) (*User, error)
+	UpdateUser(u *User) error
 	DeleteUser(id string) error
-	ListUsers() ([]User, error)
+	ListUsers(q UserQuery) (*UserPage, error)
 }
 
+// UserUpdate lists the fields of a user to change. Nil fields are kept.
+type UserUpdate struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+var (
+	ErrInvalidUser    = errors.New("invalid user")
+	ErrUsernameTaken  = errors.New("username is already taken")
+	ErrEmailTaken     = errors.New("email is already taken")
+	ErrUserNotDeleted = errors.New("user is not deleted")
+)
+
 type UserService struct {
 	store UserStore
 	cache UserCache
@@ -60,6 +83,11 @@ func Unmarshal(data []byte) (*User, error) {
 	return &u, nil
 }
 
+// Deleted reports whether user is soft-deleted.
+func (user *User) Deleted() bool {
+	return user.DeletedAt != nil
+}
+
 func (user *User) Validate() error {
 	if user.ID == "" {
 		return fmt.Errorf("user ID cannot be empty")
@@ -70,10 +98,26 @@ func (user *User) Validate() error {
 	if user.Email == "" {
 		return fmt.Errorf("email cannot be empty")
 	}
+	if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+		return fmt.Errorf("invalid email address %q", user.Email)
+	}
+	if err := user.Role.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
-func (u *UserService) CreateUser(id, name, email string) error {
+// NormalizeEmail returns the form emails are stored and looked up in, so
+// addresses differing only in case or surrounding space belong to one user.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *UserService) CreateUser(id, name, email string, role Role) error {
+	if err := role.Validate(); err != nil {
+		return err
+	}
+
 	existingUser, err := u.store.GetUser(id)
 	if err != nil {
 		if errors.Is(err, &uerr.ErrorCannotInsert{}) {
@@ -85,7 +129,11 @@ func (u *UserService) CreateUser(id, name, email string) error {
 		return fmt.Errorf("user already exists")
 	}
 
-	user := &User{ID: id, Username: name, Email: email}
+	user := &User{ID: id, Username: name, Email: NormalizeEmail(email), Role: role}
+	if err := user.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
+
 	err = u.store.InsertUser(user)
 	if err != nil {
 		return err
@@ -122,6 +170,7 @@ func (u *UserService) GetUser(id string) (*User, error) {
 }
 
 func (u *UserService) GetUserByEmail(email string) (*User, error) {
+	email = NormalizeEmail(email)
 	user, err := u.cache.GetUserByEmail(email)
 	if err == nil {
 		return user, nil
@@ -144,19 +193,146 @@ func (u *UserService) GetUserByEmail(email string) (*User, error) {
 	return user, nil
 }
 
-func (u *UserService) DeleteUser(user *User) error {
-	err := u.cache.ClearUser(user)
+// UpdateUser applies update to the user with id and returns the result. The
+// new username and email must not belong to another user, and a new email
+// is unverified. Cached copies under the user's id and old email are
+// replaced.
+func (u *UserService) UpdateUser(id string, update UserUpdate) (*User, error) {
+	old, err := u.store.GetUser(id)
 	if err != nil {
-		log.Printf("Encountered error when clearing user from cache: %v", err)
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
 	}
-	return u.store.DeleteUser(user.ID)
+
+	user := *old
+	if update.Username != nil {
+		user.Username = *update.Username
+	}
+	if update.Email != nil && NormalizeEmail(*update.Email) != old.Email {
+		user.Email = NormalizeEmail(*update.Email)
+		user.EmailVerified = false
+	}
+	if err := user.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
+
+	if user.Username != old.Username {
+		existing, err := u.store.GetUserByUsername(user.Username)
+		err = checkUnique(id, existing, err, ErrUsernameTaken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if user.Email != old.Email {
+		existing, err := u.store.GetUserByEmail(user.Email)
+		err = checkUnique(id, existing, err, ErrEmailTaken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = u.saveUser(old, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// MarkEmailVerified records that the user with id controls email. It returns
+// ErrorNotFound if the user no longer has that email.
+func (u *UserService) MarkEmailVerified(id, email string) (*User, error) {
+	old, err := u.store.GetUser(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if old.Email != NormalizeEmail(email) {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("user email has changed")}
+	}
+
+	user := *old
+	user.EmailVerified = true
+	err = u.saveUser(old, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// saveUser writes user, which was old, to the store and replaces the cached
+// copies of old.
+func (u *UserService) saveUser(old, user *User) error {
+	err := u.store.UpdateUser(user)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return fmt.Errorf("user not found: %w", err)
+		}
+		return fmt.Errorf("error updating user: %w", err)
+	}
+
+	err = u.cache.ClearUser(old)
+	if err != nil {
+		return err
+	}
+
+	return u.cache.SetUser(user)
+}
+
+// checkUnique turns the result of looking up a user by a field that must be
+// unique into errTaken if it found a user other than id.
+func checkUnique(id string, existing *User, err error, errTaken error) error {
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil
+		}
+		return fmt.Errorf("error checking for existing user: %w", err)
+	}
+	if existing.ID != id {
+		return errTaken
+	}
+
+	return nil
 }
 
-func (u *UserService) ListAllUsers() ([]User, error) {
-	users, err := u.store.ListUsers()
+// RestoreUser undoes the soft deletion of the user with id.
+func (u *UserService) RestoreUser(id string) (*User, error) {
+	old, err := u.store.GetUser(id)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if !old.Deleted() {
+		return nil, ErrUserNotDeleted
+	}
+
+	user := *old
+	user.DeletedAt = nil
+	err = u.saveUser(old, &user)
 	if err != nil {
-		return nil, fmt.Errorf("error listing users: %w", err)
+		return nil, err
 	}
 
-	return users, nil
+	return &user, nil
+}
+
+// ClearCache drops the cached copies of user, for callers that changed it in
+// the store directly.
+func (u *UserService) ClearCache(user *User) error {
+	return u.cache.ClearUser(user)
+}
+
+func (u *UserService) DeleteUser(user *User) error {
+	err := u.cache.ClearUser(user)
+	if err != nil {
+		log.Printf("Encountered error when clearing user from cache: %v", err)
+	}
+	return u.store.DeleteUser(user.ID)
 }
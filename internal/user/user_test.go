@@ -0,0 +1,193 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+func TestUserService_UpdateUser(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		id      string
+		update  UserUpdate
+		want    User
+		wantErr error
+	}{
+		{
+			name:   "Test UpdateUser changes username and email",
+			id:     "alice",
+			update: UserUpdate{Username: ptr("alicia"), Email: ptr("alicia@example.com")},
+			want:   User{ID: "alice", Username: "alicia", Email: "alicia@example.com", Role: RoleAnalyst},
+		},
+		{
+			name:   "Test UpdateUser keeps unset fields",
+			id:     "alice",
+			update: UserUpdate{Username: ptr("alicia")},
+			want:   User{ID: "alice", Username: "alicia", Email: "alice@example.com", Role: RoleAnalyst},
+		},
+		{
+			name:   "Test UpdateUser to own email",
+			id:     "alice",
+			update: UserUpdate{Email: ptr("alice@example.com")},
+			want:   User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: RoleAnalyst},
+		},
+		{
+			name:    "Test UpdateUser to taken username",
+			id:      "alice",
+			update:  UserUpdate{Username: ptr("bob")},
+			wantErr: ErrUsernameTaken,
+		},
+		{
+			name:    "Test UpdateUser to taken email",
+			id:      "alice",
+			update:  UserUpdate{Email: ptr("bob@example.com")},
+			wantErr: ErrEmailTaken,
+		},
+		{
+			name:   "Test UpdateUser normalizes email",
+			id:     "alice",
+			update: UserUpdate{Email: ptr(" Alicia@Example.COM ")},
+			want:   User{ID: "alice", Username: "alice", Email: "alicia@example.com", Role: RoleAnalyst},
+		},
+		{
+			name:    "Test UpdateUser to taken email in another case",
+			id:      "alice",
+			update:  UserUpdate{Email: ptr("Bob@Example.com")},
+			wantErr: ErrEmailTaken,
+		},
+		{
+			name:    "Test UpdateUser to empty username",
+			id:      "alice",
+			update:  UserUpdate{Username: ptr("")},
+			wantErr: ErrInvalidUser,
+		},
+		{
+			name:    "Test UpdateUser to invalid email",
+			id:      "alice",
+			update:  UserUpdate{Email: ptr("Alice <alice@example.com>")},
+			wantErr: ErrInvalidUser,
+		},
+		{
+			name:    "Test UpdateUser of missing user",
+			id:      "carol",
+			update:  UserUpdate{Username: ptr("carol")},
+			wantErr: &uerr.ErrorNotFound{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alice := User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: RoleAnalyst}
+			bob := User{ID: "bob", Username: "bob", Email: "bob@example.com", Role: RoleAnalyst}
+			store := &MockUserStore{Users: map[string]User{"alice": alice, "bob": bob}}
+			cache := &MockUserCache{}
+			cache.SetUser(&alice)
+			s := NewUserService(store, cache)
+
+			got, err := s.UpdateUser(tt.id, tt.update)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("UpdateUser() error = %v, want %v", err, tt.wantErr)
+				}
+				if store.Users["alice"] != alice {
+					t.Errorf("UpdateUser() stored = %v, want it unchanged", store.Users["alice"])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+
+			if *got != tt.want || store.Users[tt.id] != tt.want {
+				t.Errorf("UpdateUser() got = %v, stored %v, want %v", got, store.Users[tt.id], tt.want)
+			}
+			if cache.ByID[tt.id] != tt.want || cache.ByEmail[tt.want.Email] != tt.want {
+				t.Errorf("UpdateUser() cached = %v, want %v under id and email", cache.ByID, tt.want)
+			}
+			if tt.want.Email != alice.Email {
+				if _, ok := cache.ByEmail[alice.Email]; ok {
+					t.Errorf("UpdateUser() left the old email %v cached", alice.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	deletedAt := time.Now()
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: RoleAnalyst, DeletedAt: &deletedAt}
+	bob := User{ID: "bob", Username: "bob", Email: "bob@example.com", Role: RoleAnalyst}
+	store := &MockUserStore{Users: map[string]User{"alice": alice, "bob": bob}}
+	users := NewUserService(store, &MockUserCache{})
+	a := NewAuthService(&MockAPIKeyStore{}, &MockCredentialStore{}, &MockSessionStore{}, users, DefaultSessionTTL)
+
+	key, _, err := a.CreateAPIKey(&alice, "ci")
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	_, err = a.Authenticate(key)
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate() of soft-deleted user error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	u, err := users.RestoreUser("alice")
+	if err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if u.Deleted() || store.Users["alice"].DeletedAt != nil {
+		t.Errorf("RestoreUser() got = %v, want the user restored", u)
+	}
+	if _, err = a.Authenticate(key); err != nil {
+		t.Errorf("Authenticate() of restored user error = %v", err)
+	}
+
+	_, err = users.RestoreUser("bob")
+	if !errors.Is(err, ErrUserNotDeleted) {
+		t.Errorf("RestoreUser() of live user error = %v, want %v", err, ErrUserNotDeleted)
+	}
+	_, err = users.RestoreUser("carol")
+	if !errors.Is(err, &uerr.ErrorNotFound{}) {
+		t.Errorf("RestoreUser() of missing user error = %v, want not found", err)
+	}
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: RoleAnalyst}
+	store := &MockUserStore{Users: map[string]User{"alice": alice}}
+	users := NewUserService(store, &MockUserCache{})
+
+	tests := []struct {
+		name    string
+		q       UserQuery
+		want    UserQuery
+		wantErr error
+	}{
+		{"defaults", UserQuery{}, UserQuery{Sort: UserSortUsername, Limit: DefaultUserPageSize}, nil},
+		{"limit capped", UserQuery{Sort: UserSortEmail, Limit: MaxUserPageSize + 1}, UserQuery{Sort: UserSortEmail, Limit: MaxUserPageSize}, nil},
+		{"cursor", UserQuery{Limit: 1, Cursor: NewUserCursor(&alice, UserQuery{Sort: UserSortUsername})}, UserQuery{Sort: UserSortUsername, Limit: 1, Cursor: NewUserCursor(&alice, UserQuery{Sort: UserSortUsername})}, nil},
+		{"unknown sort", UserQuery{Sort: "role"}, UserQuery{}, ErrInvalidUserQuery},
+		{"negative limit", UserQuery{Limit: -1}, UserQuery{}, ErrInvalidUserQuery},
+		{"malformed cursor", UserQuery{Cursor: "not a cursor"}, UserQuery{}, ErrInvalidUserQuery},
+		{"cursor of other order", UserQuery{Desc: true, Cursor: NewUserCursor(&alice, UserQuery{Sort: UserSortUsername})}, UserQuery{}, ErrInvalidUserQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.Query = UserQuery{}
+			page, err := users.ListUsers(tt.q)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListUsers() error = %v, want %v", err, tt.wantErr)
+			}
+			if store.Query != tt.want {
+				t.Errorf("ListUsers() queried store with %v, want %v", store.Query, tt.want)
+			}
+			if err == nil && page.Total != 1 {
+				t.Errorf("ListUsers() total = %v, want 1", page.Total)
+			}
+		})
+	}
+}
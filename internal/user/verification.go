@@ -0,0 +1,119 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+const DefaultVerificationTTL = 24 * time.Hour
+
+const verificationTokenPrefix = "uhv_"
+
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// Verification is a pending check that the user with UserID controls Email.
+type Verification struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// VerificationStore keeps pending verifications by the hash of their token
+// until they expire.
+type VerificationStore interface {
+	SetVerification(tokenHash string, v Verification, ttl time.Duration) error
+	GetVerification(tokenHash string) (*Verification, error)
+	DeleteVerification(tokenHash string) error
+}
+
+// Mailer delivers messages to an email address, implemented by
+// mail.LogMailer and mail.FileMailer.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// VerificationService checks that users control their email addresses by
+// mailing them a token to send back.
+type VerificationService struct {
+	store  VerificationStore
+	users  *UserService
+	mailer Mailer
+	ttl    time.Duration
+}
+
+func NewVerificationService(s VerificationStore, users *UserService, mailer Mailer, ttl time.Duration) *VerificationService {
+	return &VerificationService{store: s, users: users, mailer: mailer, ttl: ttl}
+}
+
+func UnmarshalVerification(data []byte) (*Verification, error) {
+	var v Verification
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling verification: %w", err)
+	}
+
+	return &v, nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SendVerification mails u a token that verifies its current email.
+func (v *VerificationService) SendVerification(u *User) error {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return fmt.Errorf("error generating verification token: %w", err)
+	}
+	token := verificationTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	err = v.store.SetVerification(hashVerificationToken(token), Verification{UserID: u.ID, Email: u.Email}, v.ttl)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nTo verify your email, send this token to POST /v1/user/verify within %v:\n\n%s\n", u.Username, v.ttl, token)
+	err = v.mailer.Send(u.Email, "Verify your unhash email", body)
+	if err != nil {
+		return fmt.Errorf("error sending verification email: %w", err)
+	}
+
+	return nil
+}
+
+// Verify marks the email the token was sent to as verified and returns its
+// user. Tokens work once, and not at all once the user's email changed.
+func (v *VerificationService) Verify(token string) (*User, error) {
+	hash := hashVerificationToken(token)
+	pending, err := v.store.GetVerification(hash)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("error getting verification: %w", err)
+	}
+
+	err = v.store.DeleteVerification(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := v.users.MarkEmailVerified(pending.UserID, pending.Email)
+	if err != nil {
+		if errors.Is(err, &uerr.ErrorNotFound{}) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
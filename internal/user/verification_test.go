@@ -0,0 +1,95 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/uerr"
+)
+
+type MockVerificationStore struct {
+	Verifications map[string]Verification
+}
+
+func (m *MockVerificationStore) SetVerification(tokenHash string, v Verification, ttl time.Duration) error {
+	m.Verifications[tokenHash] = v
+	return nil
+}
+
+func (m *MockVerificationStore) GetVerification(tokenHash string) (*Verification, error) {
+	v, ok := m.Verifications[tokenHash]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{Err: errors.New("verification not found")}
+	}
+	return &v, nil
+}
+
+func (m *MockVerificationStore) DeleteVerification(tokenHash string) error {
+	delete(m.Verifications, tokenHash)
+	return nil
+}
+
+type MockMailer struct {
+	To    []string
+	Token string
+}
+
+func (m *MockMailer) Send(to, subject, body string) error {
+	m.To = append(m.To, to)
+	i := strings.Index(body, verificationTokenPrefix)
+	m.Token = strings.TrimSpace(body[i:])
+	return nil
+}
+
+func TestVerificationService(t *testing.T) {
+	alice := User{ID: "alice", Username: "alice", Email: "alice@example.com", Role: RoleAnalyst}
+	store := &MockUserStore{Users: map[string]User{"alice": alice}}
+	users := NewUserService(store, &MockUserCache{})
+	mailer := &MockMailer{}
+	v := NewVerificationService(&MockVerificationStore{Verifications: make(map[string]Verification)}, users, mailer, time.Hour)
+
+	_, err := v.Verify("uhv_unknown")
+	if !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Verify() of unknown token error = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+
+	err = v.SendVerification(&alice)
+	if err != nil {
+		t.Fatalf("SendVerification() error = %v", err)
+	}
+	if len(mailer.To) != 1 || mailer.To[0] != alice.Email {
+		t.Errorf("SendVerification() mailed %v, want %v", mailer.To, alice.Email)
+	}
+
+	u, err := v.Verify(mailer.Token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !u.EmailVerified || !store.Users["alice"].EmailVerified {
+		t.Errorf("Verify() got = %v, want email verified", u)
+	}
+
+	_, err = v.Verify(mailer.Token)
+	if !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Verify() of used token error = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+
+	err = v.SendVerification(&alice)
+	if err != nil {
+		t.Fatalf("SendVerification() error = %v", err)
+	}
+	email := "alicia@example.com"
+	updated, err := users.UpdateUser("alice", UserUpdate{Email: &email})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if updated.EmailVerified {
+		t.Errorf("UpdateUser() got = %v, want new email unverified", updated)
+	}
+	_, err = v.Verify(mailer.Token)
+	if !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("Verify() of token for old email error = %v, want %v", err, ErrInvalidVerificationToken)
+	}
+}
@@ -0,0 +1,282 @@
+package wordlist
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+type Compression string
+
+const (
+	CompressionNone  Compression = "none"
+	CompressionGzip  Compression = "gzip"
+	CompressionBzip2 Compression = "bzip2"
+	CompressionXz    Compression = "xz"
+	CompressionZstd  Compression = "zstd"
+)
+
+var magics = []struct {
+	compression Compression
+	magic       []byte
+}{
+	{CompressionGzip, []byte{0x1f, 0x8b}},
+	{CompressionBzip2, []byte("BZh")},
+	{CompressionXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// Detect identifies the compression of a file from its first bytes. Library
+// files are stored without their original names, so extensions can't be used.
+func Detect(header []byte) Compression {
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.compression
+		}
+	}
+	return CompressionNone
+}
+
+func decompress(c Compression, r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case CompressionXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// Reader streams the lines of a wordlist file, transparently decompressing
+// it. Its position can be checkpointed as a line number plus the matching
+// offset into the decompressed stream.
+type Reader struct {
+	path        string
+	compression Compression
+	file        *os.File
+	dec         io.ReadCloser
+	br          *bufio.Reader
+	line        uint64
+	offset      uint64
+}
+
+func Open(path string) (*Reader, error) {
+	r := &Reader{path: path}
+
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Reader) open() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+
+	raw := bufio.NewReader(f)
+	header, err := raw.Peek(6)
+	if err != nil && !errors.Is(err, io.EOF) {
+		f.Close()
+		return fmt.Errorf("error reading wordlist header: %w", err)
+	}
+	r.compression = Detect(header)
+
+	dec, err := decompress(r.compression, raw)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error opening %v wordlist: %w", r.compression, err)
+	}
+
+	r.file = f
+	r.dec = dec
+	r.br = bufio.NewReader(dec)
+	r.line = 0
+	r.offset = 0
+
+	return nil
+}
+
+func (r *Reader) Compression() Compression {
+	return r.compression
+}
+
+// Line is the number of lines read so far.
+func (r *Reader) Line() uint64 {
+	return r.line
+}
+
+// Offset is the number of decompressed bytes read so far.
+func (r *Reader) Offset() uint64 {
+	return r.offset
+}
+
+// Next returns the next line without its line ending, or io.EOF.
+func (r *Reader) Next() (string, error) {
+	raw, err := r.br.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && raw != "") {
+		return "", err
+	}
+
+	r.line++
+	r.offset += uint64(len(raw))
+
+	return strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r"), nil
+}
+
+// Seek positions the reader before the given line by reading up to it.
+func (r *Reader) Seek(line uint64) error {
+	if line < r.line {
+		err := r.reopen()
+		if err != nil {
+			return err
+		}
+	}
+
+	for r.line < line {
+		raw, err := r.br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			r.offset += uint64(len(raw))
+			continue
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && len(raw) > 0) {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("line %d is past the end of the wordlist", line)
+			}
+			return err
+		}
+
+		r.line++
+		r.offset += uint64(len(raw))
+	}
+
+	return nil
+}
+
+// SeekOffset resumes from a checkpoint taken with Line and Offset. Plain
+// files seek straight to the offset, compressed ones skip decompressed bytes
+// without splitting them into lines.
+func (r *Reader) SeekOffset(line, offset uint64) error {
+	if r.compression == CompressionNone {
+		_, err := r.file.Seek(int64(offset), io.SeekStart)
+		if err != nil {
+			return err
+		}
+		r.br.Reset(r.file)
+		r.line = line
+		r.offset = offset
+		return nil
+	}
+
+	if offset < r.offset {
+		err := r.reopen()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := io.CopyN(io.Discard, r.br, int64(offset-r.offset))
+	if err != nil {
+		return fmt.Errorf("error skipping to offset %d: %w", offset, err)
+	}
+	r.line = line
+	r.offset = offset
+
+	return nil
+}
+
+func (r *Reader) reopen() error {
+	err := r.Close()
+	if err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *Reader) Close() error {
+	err := r.dec.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// CountLines counts the lines of a wordlist file after decompression. A final
+// line without a line ending is counted.
+func CountLines(path string) (uint64, error) {
+	r, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var lines uint64
+	var last byte
+	empty := true
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.br.Read(buf)
+		if n > 0 {
+			lines += uint64(bytes.Count(buf[:n], []byte("\n")))
+			last = buf[n-1]
+			empty = false
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if !empty && last != '\n' {
+		lines++
+	}
+
+	return lines, nil
+}
+
+// Load reads a whole wordlist file into memory.
+func Load(path string) ([]string, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	words := make([]string, 0)
+	for {
+		w, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return words, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+}
@@ -0,0 +1,214 @@
+package wordlist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const testContents = "password\n123456\r\nletmein\nlast"
+
+var testWords = []string{"password", "123456", "letmein", "last"}
+
+// testBzip2 is testContents compressed with bzip2, which the standard library
+// can only decompress.
+const testBzip2 = "425a68393141592653593b2b6ad800000ac98000123f002627dc8020003140d34323262111a0d341a64c86943adae58354651ee41da05dc20a61f1772453850903b2b6ad80"
+
+func compress(t *testing.T, c Compression) []byte {
+	var buf bytes.Buffer
+
+	switch c {
+	case CompressionNone:
+		buf.WriteString(testContents)
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(testContents))
+		w.Close()
+	case CompressionBzip2:
+		data, err := hex.DecodeString(testBzip2)
+		if err != nil {
+			t.Fatalf("DecodeString() error = %v", err)
+		}
+		buf.Write(data)
+	case CompressionXz:
+		w, err := xz.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("xz.NewWriter() error = %v", err)
+		}
+		w.Write([]byte(testContents))
+		w.Close()
+	case CompressionZstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("zstd.NewWriter() error = %v", err)
+		}
+		w.Write([]byte(testContents))
+		w.Close()
+	}
+
+	return buf.Bytes()
+}
+
+func writeTestWordlist(t *testing.T, c Compression) string {
+	path := filepath.Join(t.TempDir(), "wordlist")
+	err := os.WriteFile(path, compress(t, c), 0o644)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+var compressions = []Compression{CompressionNone, CompressionGzip, CompressionBzip2, CompressionXz, CompressionZstd}
+
+func TestDetect(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			if got := Detect(compress(t, c)); got != c {
+				t.Errorf("Detect() got = %v, want %v", got, c)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			got, err := CountLines(writeTestWordlist(t, c))
+			if err != nil {
+				t.Fatalf("CountLines() error = %v", err)
+			}
+			if got != uint64(len(testWords)) {
+				t.Errorf("CountLines() got = %v, want %v", got, len(testWords))
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty")
+		os.WriteFile(path, nil, 0o644)
+
+		got, err := CountLines(path)
+		if err != nil {
+			t.Fatalf("CountLines() error = %v", err)
+		}
+		if got != 0 {
+			t.Errorf("CountLines() got = %v, want 0", got)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			got, err := Load(writeTestWordlist(t, c))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(got) != len(testWords) {
+				t.Fatalf("Load() got = %v, want %v", got, testWords)
+			}
+			for i := range testWords {
+				if got[i] != testWords[i] {
+					t.Errorf("Load() got = %v at %d, want %v", got[i], i, testWords[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReader_Seek(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			r, err := Open(writeTestWordlist(t, c))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer r.Close()
+
+			if r.Compression() != c {
+				t.Errorf("Compression() got = %v, want %v", r.Compression(), c)
+			}
+
+			err = r.Seek(2)
+			if err != nil {
+				t.Fatalf("Seek() error = %v", err)
+			}
+			got, err := r.Next()
+			if err != nil || got != "letmein" {
+				t.Errorf("Next() after Seek(2) got = %v, %v, want letmein", got, err)
+			}
+
+			err = r.Seek(1)
+			if err != nil {
+				t.Fatalf("Seek() backwards error = %v", err)
+			}
+			got, err = r.Next()
+			if err != nil || got != "123456" {
+				t.Errorf("Next() after Seek(1) got = %v, %v, want 123456", got, err)
+			}
+
+			err = r.Seek(5)
+			if err == nil {
+				t.Errorf("Seek() past end got = nil, want error")
+			}
+		})
+	}
+}
+
+func TestReader_SeekOffset(t *testing.T) {
+	for _, c := range compressions {
+		t.Run(string(c), func(t *testing.T) {
+			path := writeTestWordlist(t, c)
+
+			r, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer r.Close()
+
+			r.Next()
+			r.Next()
+			line, offset := r.Line(), r.Offset()
+			if line != 2 || offset != uint64(len("password\n123456\r\n")) {
+				t.Errorf("checkpoint got = %v, %v", line, offset)
+			}
+
+			resumed, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer resumed.Close()
+
+			err = resumed.SeekOffset(line, offset)
+			if err != nil {
+				t.Fatalf("SeekOffset() error = %v", err)
+			}
+			if resumed.Line() != line {
+				t.Errorf("Line() got = %v, want %v", resumed.Line(), line)
+			}
+
+			rest := make([]string, 0)
+			for {
+				w, err := resumed.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				rest = append(rest, w)
+			}
+			if len(rest) != 2 || rest[0] != "letmein" || rest[1] != "last" {
+				t.Errorf("Next() after SeekOffset() got = %v, want [letmein last]", rest)
+			}
+		})
+	}
+}
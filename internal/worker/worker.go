@@ -0,0 +1,567 @@
+package worker
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/crack"
+	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+	"github.com/fmdunlap/unhash/internal/wordlist"
+)
+
+const DefaultPollInterval = 5 * time.Second
+
+// checkpointInterval is how often long running stages save their progress,
+// so a crashed worker does not lose more than that.
+const checkpointInterval = 30 * time.Second
+
+// JobQueue is where the worker takes jobs from and reports their progress
+// to, implemented by hashjob.HashJobService.
+type JobQueue interface {
+	ClaimHashJob() (*hashjob.HashJob, error)
+	GetHashJob(id string) (*hashjob.HashJob, error)
+	UpdateHashJob(h hashjob.HashJob) error
+	RequeueHashJobs() error
+}
+
+// Potfile remembers every hash cracked by any job, implemented by
+// potfile.Potfile.
+type Potfile interface {
+	Lookup(hash string) (string, bool)
+	Add(hash, plain string) error
+}
+
+// ResourceLocator resolves the library resources an attack references,
+// implemented by library.LibraryService.
+type ResourceLocator interface {
+	Locate(kind library.ResourceKind, id string) (*library.Resource, string, error)
+}
+
+// RainbowTable recovers plaintexts of digests of one hash type, implemented
+// by rainbow.Table.
+type RainbowTable interface {
+	HashType() hashtype.HashType
+	Lookup(digest []byte) (string, bool, error)
+}
+
+// Benchmarks sizes attack chunks from the measured speed of each hash type,
+// implemented by benchmark.BenchmarkService.
+type Benchmarks interface {
+	ChunkSize(t hashtype.HashType, d time.Duration) (uint64, error)
+}
+
+// UsageMeter accounts for the work jobs do and bounds it by their owners'
+// daily CPU time, implemented by usage.UsageService.
+type UsageMeter interface {
+	CPUAllowance(userId, teamId string) (time.Duration, bool, error)
+	Record(r usage.Record) error
+}
+
+// Worker runs the pipelines of pending hash jobs one at a time.
+type Worker struct {
+	jobs         JobQueue
+	library      ResourceLocator
+	potfile      Potfile
+	usage        UsageMeter
+	benchmarks   Benchmarks
+	rainbows     []RainbowTable
+	modelDir     string
+	pollInterval time.Duration
+	logger       *log.Logger
+}
+
+// NewWorker returns a worker taking jobs from jobs. pot may be nil, in which
+// case potfile stages crack nothing, and so may meter, in which case usage is
+// neither recorded nor limited. Attack stages are checkpointed every chunk of
+// candidates sized by bench; without one they only save progress when they
+// end. Rainbow stages look hashes up in rainbows.
+func NewWorker(jobs JobQueue, lib ResourceLocator, pot Potfile, meter UsageMeter, bench Benchmarks, rainbows []RainbowTable, modelDir string, pollInterval time.Duration, logger *log.Logger) *Worker {
+	return &Worker{
+		jobs:         jobs,
+		library:      lib,
+		potfile:      pot,
+		usage:        meter,
+		benchmarks:   bench,
+		rainbows:     rainbows,
+		modelDir:     modelDir,
+		pollInterval: pollInterval,
+		logger:       logger,
+	}
+}
+
+// Run processes pending jobs until ctx is done. Jobs interrupted by a
+// previous shutdown are resumed first.
+func (w *Worker) Run(ctx context.Context) error {
+	err := w.jobs.RequeueHashJobs()
+	if err != nil {
+		return err
+	}
+
+	for {
+		hj, err := w.jobs.ClaimHashJob()
+		if err == nil {
+			w.Process(ctx, hj)
+			continue
+		}
+		if !errors.Is(err, &uerr.ErrorNotFound{}) {
+			w.logger.Printf("Error claiming hashjob: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+// Process runs the pipeline of a claimed job within its budget and records
+// the outcome. Cracked hashes are kept whichever way the pipeline ends.
+func (w *Worker) Process(ctx context.Context, hj *hashjob.HashJob) {
+	err := w.run(ctx, hj)
+
+	switch {
+	case err == nil:
+		hj.Status = hashjob.HashJobStatusDone
+	case errors.Is(err, crack.ErrCandidateLimit):
+		hj.Status = hashjob.HashJobStatusExhausted
+	case errors.Is(err, usage.ErrQuotaExceeded):
+		hj.Status = hashjob.HashJobStatusQuotaExceeded
+	case ctx.Err() != nil:
+		// The worker is shutting down, not the job running out of time.
+		hj.Status = hashjob.HashJobStatusPending
+	case errors.Is(err, context.DeadlineExceeded):
+		hj.Status = hashjob.HashJobStatusTimedOut
+	default:
+		w.logger.Printf("Error running hashjob %v: %v", hj.ID, err)
+		hj.Status = hashjob.HashJobStatusError
+		hj.Error = err.Error()
+	}
+
+	err = w.jobs.UpdateHashJob(*hj)
+	if err != nil {
+		w.logger.Printf("Error saving hashjob %v: %v", hj.ID, err)
+	}
+}
+
+// run works through the job's remaining stages within its budget and the
+// CPU time left to its owner and team today.
+func (w *Worker) run(ctx context.Context, hj *hashjob.HashJob) error {
+	if maxRuntime := hj.Budget.MaxRuntime(); maxRuntime > 0 {
+		if hj.Stats.Elapsed >= maxRuntime {
+			return context.DeadlineExceeded
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxRuntime-hj.Stats.Elapsed)
+		defer cancel()
+	}
+
+	if w.usage != nil {
+		allowance, limited, err := w.usage.CPUAllowance(hj.OwnerId, hj.TeamId)
+		if err != nil {
+			return fmt.Errorf("error checking cpu quota: %w", err)
+		}
+		if limited {
+			if allowance <= 0 {
+				return usage.ErrQuotaExceeded
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, allowance, usage.ErrQuotaExceeded)
+			defer cancel()
+		}
+	}
+
+	for ; hj.Stage < len(hj.Stages); hj.Stage++ {
+		w.refresh(hj)
+		if len(uncracked(hj)) == 0 {
+			for i := hj.Stage; i < len(hj.Stages); i++ {
+				hj.Stages[i].Status = hashjob.StageStatusSkipped
+			}
+			hj.Stage = len(hj.Stages)
+			return nil
+		}
+
+		stage := &hj.Stages[hj.Stage]
+		stage.Status = hashjob.StageStatusRunning
+
+		var err error
+		switch stage.Type {
+		case hashjob.StageTypePotfile:
+			err = w.potfileStage(hj, stage)
+		case hashjob.StageTypeRainbow:
+			err = w.rainbowStage(ctx, hj, stage)
+		default:
+			err = w.attackStage(ctx, hj, stage)
+		}
+		if errors.Is(context.Cause(ctx), usage.ErrQuotaExceeded) {
+			return usage.ErrQuotaExceeded
+		}
+		if err != nil {
+			return err
+		}
+
+		stage.Status = hashjob.StageStatusDone
+		err = w.jobs.UpdateHashJob(*hj)
+		if err != nil {
+			w.logger.Printf("Error saving progress of hashjob %v: %v", hj.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// potfileStage resolves hashes cracked before from the potfile, checking
+// each plaintext against the job's hash type.
+func (w *Worker) potfileStage(hj *hashjob.HashJob, stage *hashjob.Stage) error {
+	if w.potfile == nil {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() { stage.Stats.Elapsed += time.Since(start) }()
+
+	for _, hash := range uncracked(hj) {
+		plain, ok := w.potfile.Lookup(hash)
+		if !ok {
+			continue
+		}
+
+		digest, err := hj.HashType.Hash(plain)
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(digest) != strings.ToLower(hash) {
+			continue
+		}
+
+		stage.Stats.Cracked++
+		hj.Stats.Cracked++
+		w.record(hj, hash, plain)
+	}
+
+	return nil
+}
+
+// rainbowStage looks the job's uncracked hashes up in the rainbow tables of
+// its hash type, resuming from the stage's position. Lookups take a chain
+// walk per chain position, so progress is saved every checkpointInterval.
+func (w *Worker) rainbowStage(ctx context.Context, hj *hashjob.HashJob, stage *hashjob.Stage) error {
+	tables := make([]RainbowTable, 0, len(w.rainbows))
+	for _, t := range w.rainbows {
+		if t.HashType() == hj.HashType {
+			tables = append(tables, t)
+		}
+	}
+
+	stage.Keyspace = uint64(len(hj.Hashes))
+	if len(tables) == 0 {
+		stage.Position = stage.Keyspace
+		return nil
+	}
+
+	var stats crack.Stats
+	start := time.Now()
+	checkpoint := start
+	defer func() {
+		stats.Elapsed = time.Since(start)
+		stage.Stats.Cracked += stats.Cracked
+		stage.Stats.Elapsed += stats.Elapsed
+		hj.Stats.Cracked += stats.Cracked
+		hj.Stats.Elapsed += stats.Elapsed
+		w.meter(hj, stats)
+	}()
+
+	for ; stage.Position < stage.Keyspace; stage.Position++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if time.Since(checkpoint) >= checkpointInterval {
+			checkpoint = time.Now()
+			err := w.jobs.UpdateHashJob(*hj)
+			if err != nil {
+				w.logger.Printf("Error saving progress of hashjob %v: %v", hj.ID, err)
+			}
+		}
+
+		hash := hj.Hashes[stage.Position]
+		if _, ok := hj.Cracked[hash]; ok {
+			continue
+		}
+		digest, err := hex.DecodeString(strings.ToLower(hash))
+		if err != nil || len(digest) != hj.HashType.Size() {
+			continue
+		}
+
+		for _, t := range tables {
+			plain, found, err := t.Lookup(digest)
+			if err != nil {
+				return fmt.Errorf("error looking up hash %v: %w", hash, err)
+			}
+			if found {
+				stats.Cracked++
+				w.record(hj, hash, plain)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// attackStage runs the stage's attack against the uncracked hashes, resuming
+// from the stage's position.
+func (w *Worker) attackStage(ctx context.Context, hj *hashjob.HashJob, stage *hashjob.Stage) error {
+	targets, err := crack.NewTargets(hj.HashType, uncracked(hj))
+	if err != nil {
+		return err
+	}
+
+	res, closeResources, err := w.resources(stage.Attack)
+	if err != nil {
+		return err
+	}
+	defer closeResources()
+
+	gen, err := stage.Attack.Generator(res)
+	if err != nil {
+		return err
+	}
+	stage.Keyspace = gen.Keyspace()
+
+	err = resume(gen, stage)
+	if err != nil {
+		return fmt.Errorf("error resuming attack: %w", err)
+	}
+
+	chunk := w.chunkSize(hj.HashType)
+	for {
+		limit, budgeted := chunk, false
+		if hj.Budget != nil && hj.Budget.MaxCandidates > 0 {
+			if hj.Stats.Candidates >= hj.Budget.MaxCandidates {
+				return crack.ErrCandidateLimit
+			}
+			left := hj.Budget.MaxCandidates - hj.Stats.Candidates
+			if limit == 0 || left <= limit {
+				limit, budgeted = left, true
+			}
+		}
+
+		stats, err := crack.Run(ctx, gen, targets, limit, func(hash, plain string) {
+			w.record(hj, hash, plain)
+		})
+
+		checkpoint(gen, stage)
+		stage.Stats.Candidates += stats.Candidates
+		stage.Stats.Cracked += stats.Cracked
+		stage.Stats.Elapsed += stats.Elapsed
+		hj.Stats.Candidates += stats.Candidates
+		hj.Stats.Cracked += stats.Cracked
+		hj.Stats.Elapsed += stats.Elapsed
+		w.meter(hj, stats)
+
+		if budgeted || !errors.Is(err, crack.ErrCandidateLimit) {
+			return err
+		}
+
+		err = w.jobs.UpdateHashJob(*hj)
+		if err != nil {
+			w.logger.Printf("Error saving progress of hashjob %v: %v", hj.ID, err)
+		}
+	}
+}
+
+// chunkSize returns how many candidates of t the worker tries between
+// checkpoints, or 0 to run stages without checkpoints if t was never
+// benchmarked.
+func (w *Worker) chunkSize(t hashtype.HashType) uint64 {
+	if w.benchmarks == nil {
+		return 0
+	}
+
+	size, err := w.benchmarks.ChunkSize(t, checkpointInterval)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// resume moves gen to the stage's position, straight to the recorded offset
+// if gen streams a wordlist.
+func resume(gen attack.Generator, stage *hashjob.Stage) error {
+	if s, ok := gen.(attack.OffsetSeeker); ok && stage.Offset > 0 {
+		return s.SeekOffset(stage.Position, stage.Offset)
+	}
+	return gen.Seek(stage.Position)
+}
+
+// checkpoint records the position of gen in the stage.
+func checkpoint(gen attack.Generator, stage *hashjob.Stage) {
+	stage.Position = gen.Position()
+	if s, ok := gen.(attack.OffsetSeeker); ok {
+		stage.Offset = s.Offset()
+	}
+}
+
+// meter records the work an attack did for the job. crack.Run hashes on a
+// single goroutine, so its elapsed time is the CPU time it used.
+func (w *Worker) meter(hj *hashjob.HashJob, stats crack.Stats) {
+	if w.usage == nil {
+		return
+	}
+
+	err := w.usage.Record(usage.Record{
+		JobID:      hj.ID,
+		UserID:     hj.OwnerId,
+		TeamID:     hj.TeamId,
+		CPUSeconds: stats.Elapsed.Seconds(),
+		Candidates: stats.Candidates,
+		Cracked:    stats.Cracked,
+	})
+	if err != nil {
+		w.logger.Printf("Error recording usage of hashjob %v: %v", hj.ID, err)
+	}
+}
+
+// record stores a hash cracked by the job's current stage and adds it to the
+// potfile.
+func (w *Worker) record(hj *hashjob.HashJob, hash, plain string) {
+	if hj.Cracked == nil {
+		hj.Cracked = make(map[string]string)
+	}
+	if hj.CrackedBy == nil {
+		hj.CrackedBy = make(map[string]int)
+	}
+	hj.Cracked[hash] = plain
+	hj.CrackedBy[hash] = hj.Stage
+
+	if w.potfile != nil {
+		err := w.potfile.Add(hash, plain)
+		if err != nil {
+			w.logger.Printf("Error adding hash of hashjob %v to potfile: %v", hj.ID, err)
+		}
+	}
+}
+
+// refresh picks up hashes appended to the job, and the labels and plaintexts
+// looked up for them, since the worker last read it.
+func (w *Worker) refresh(hj *hashjob.HashJob) {
+	stored, err := w.jobs.GetHashJob(hj.ID)
+	if err != nil {
+		w.logger.Printf("Error refreshing hashjob %v: %v", hj.ID, err)
+		return
+	}
+
+	if len(stored.Hashes) > len(hj.Hashes) {
+		hj.Hashes = stored.Hashes
+	}
+	for hash, plain := range stored.Cracked {
+		if _, ok := hj.Cracked[hash]; ok {
+			continue
+		}
+		if hj.Cracked == nil {
+			hj.Cracked = make(map[string]string)
+		}
+		hj.Cracked[hash] = plain
+	}
+	hj.AddLabels(stored.Labels)
+}
+
+// uncracked returns the hashes of the job no lookup or stage has cracked yet.
+func uncracked(hj *hashjob.HashJob) []string {
+	remaining := make([]string, 0, len(hj.Hashes))
+	for _, h := range hj.Hashes {
+		if _, ok := hj.Cracked[h]; !ok {
+			remaining = append(remaining, h)
+		}
+	}
+
+	return remaining
+}
+
+// resources loads everything atk references. The returned func releases
+// what was opened and must be called once the attack is done.
+func (w *Worker) resources(atk *attack.Attack) (attack.Resources, func(), error) {
+	var res attack.Resources
+	closeResources := func() {
+		for _, s := range res.Streams {
+			s.Close()
+		}
+	}
+
+	for _, id := range atk.Wordlists {
+		r, path, err := w.library.Locate(library.ResourceKindWordlist, id)
+		if err != nil {
+			closeResources()
+			return res, nil, err
+		}
+
+		if atk.Type == attack.AttackTypeDictionary {
+			stream, err := wordlist.Open(path)
+			if err != nil {
+				closeResources()
+				return res, nil, err
+			}
+			res.Streams = append(res.Streams, stream)
+			res.Lines = append(res.Lines, r.Lines)
+			continue
+		}
+
+		words, err := wordlist.Load(path)
+		if err != nil {
+			closeResources()
+			return res, nil, err
+		}
+		res.Wordlists = append(res.Wordlists, words)
+	}
+
+	if atk.Rules != "" {
+		_, path, err := w.library.Locate(library.ResourceKindRule, atk.Rules)
+		if err != nil {
+			closeResources()
+			return res, nil, err
+		}
+
+		lines, err := wordlist.Load(path)
+		if err != nil {
+			closeResources()
+			return res, nil, err
+		}
+		res.Rules, err = attack.ParseRules(lines)
+		if err != nil {
+			closeResources()
+			return res, nil, fmt.Errorf("invalid rule file: %w", err)
+		}
+	}
+
+	if atk.Model != "" {
+		f, err := os.Open(filepath.Join(w.modelDir, filepath.Base(atk.Model)))
+		if err != nil {
+			closeResources()
+			return res, nil, fmt.Errorf("error opening markov model: %w", err)
+		}
+		defer f.Close()
+
+		res.Markov, err = attack.ReadMarkovModel(f)
+		if err != nil {
+			closeResources()
+			return res, nil, err
+		}
+	}
+
+	return res, closeResources, nil
+}
@@ -0,0 +1,694 @@
+package worker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fmdunlap/unhash/internal/attack"
+	"github.com/fmdunlap/unhash/internal/crack"
+	"github.com/fmdunlap/unhash/internal/hashjob"
+	"github.com/fmdunlap/unhash/internal/hashtype"
+	"github.com/fmdunlap/unhash/internal/library"
+	"github.com/fmdunlap/unhash/internal/rainbow"
+	"github.com/fmdunlap/unhash/internal/uerr"
+	"github.com/fmdunlap/unhash/internal/usage"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// MockJobQueue implements JobQueue
+
+type MockJobQueue struct {
+	Pending  []hashjob.HashJob
+	Updated  map[string]hashjob.HashJob
+	Stored   map[string]hashjob.HashJob
+	OnUpdate func(h hashjob.HashJob)
+}
+
+func (m *MockJobQueue) ClaimHashJob() (*hashjob.HashJob, error) {
+	if len(m.Pending) == 0 {
+		return nil, &uerr.ErrorNotFound{}
+	}
+	hj := m.Pending[0]
+	m.Pending = m.Pending[1:]
+	hj.Status = hashjob.HashJobStatusRunning
+	return &hj, nil
+}
+
+func (m *MockJobQueue) GetHashJob(id string) (*hashjob.HashJob, error) {
+	h, ok := m.Stored[id]
+	if !ok {
+		return nil, &uerr.ErrorNotFound{}
+	}
+	return &h, nil
+}
+
+func (m *MockJobQueue) UpdateHashJob(h hashjob.HashJob) error {
+	if m.Updated == nil {
+		m.Updated = make(map[string]hashjob.HashJob)
+	}
+	m.Updated[h.ID] = h
+	if m.OnUpdate != nil {
+		m.OnUpdate(h)
+	}
+	return nil
+}
+
+func (m *MockJobQueue) RequeueHashJobs() error {
+	return nil
+}
+
+// MockResourceLocator implements ResourceLocator
+
+type MockResourceLocator struct {
+	Paths map[string]string
+	// Lines are the line counts of resources other than 2.
+	Lines map[string]uint64
+}
+
+func (m *MockResourceLocator) Locate(kind library.ResourceKind, id string) (*library.Resource, string, error) {
+	path, ok := m.Paths[id]
+	if !ok {
+		return nil, "", &uerr.ErrorNotFound{}
+	}
+	lines, ok := m.Lines[id]
+	if !ok {
+		lines = 2
+	}
+	return &library.Resource{ID: id, Kind: kind, Lines: lines}, path, nil
+}
+
+// MockPotfile implements Potfile
+
+type MockPotfile struct {
+	Plains map[string]string
+}
+
+func (m *MockPotfile) Lookup(hash string) (string, bool) {
+	plain, ok := m.Plains[hash]
+	return plain, ok
+}
+
+func (m *MockPotfile) Add(hash, plain string) error {
+	m.Plains[hash] = plain
+	return nil
+}
+
+// MockUsageMeter implements UsageMeter
+
+type MockUsageMeter struct {
+	Allowance time.Duration
+	Limited   bool
+	Records   []usage.Record
+}
+
+func (m *MockUsageMeter) CPUAllowance(userId, teamId string) (time.Duration, bool, error) {
+	return m.Allowance, m.Limited, nil
+}
+
+func (m *MockUsageMeter) Record(r usage.Record) error {
+	m.Records = append(m.Records, r)
+	return nil
+}
+
+// MockBenchmarks implements Benchmarks
+
+type MockBenchmarks struct {
+	Size uint64
+}
+
+func (m *MockBenchmarks) ChunkSize(t hashtype.HashType, d time.Duration) (uint64, error) {
+	if m.Size == 0 {
+		return 0, &uerr.ErrorNotFound{}
+	}
+	return m.Size, nil
+}
+
+func newTestWorker(t *testing.T, queue *MockJobQueue, pot Potfile) *Worker {
+	dir := t.TempDir()
+	files := map[string]string{
+		"words": "pin\ncode\n",
+		"rules": ":\nc\n",
+	}
+
+	locator := &MockResourceLocator{Paths: make(map[string]string)}
+	for id, content := range files {
+		path := filepath.Join(dir, id)
+		err := os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		locator.Paths[id] = path
+	}
+
+	return NewWorker(queue, locator, pot, nil, nil, nil, t.TempDir(), DefaultPollInterval, log.New(io.Discard, "", 0))
+}
+
+func attackStage(a *attack.Attack) hashjob.Stage {
+	return hashjob.Stage{Type: hashjob.StageTypeAttack, Attack: a, Status: hashjob.StageStatusPending}
+}
+
+func TestWorker_Process(t *testing.T) {
+	hybrid := func(position uint64) []hashjob.Stage {
+		stage := attackStage(&attack.Attack{Type: attack.AttackTypeHybridWordlistMask, Wordlists: []string{"words"}, Mask: "?d?d?d"})
+		stage.Position = position
+		return []hashjob.Stage{stage}
+	}
+
+	tests := []struct {
+		name          string
+		job           hashjob.HashJob
+		cancel        bool
+		wantStatus    hashjob.HashJobStatus
+		wantCracked   int
+		wantPosition  uint64
+		wantCandidate uint64
+	}{
+		{
+			name: "Test Process cracks every hash",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042"), md5Hex("code999")},
+				Stages: hybrid(0),
+			},
+			wantStatus:    hashjob.HashJobStatusDone,
+			wantCracked:   2,
+			wantPosition:  2000,
+			wantCandidate: 2000,
+		},
+		{
+			name: "Test Process without stages",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042")},
+			},
+			wantStatus: hashjob.HashJobStatusDone,
+		},
+		{
+			name: "Test Process exhausts candidate budget",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042"), md5Hex("code999")},
+				Stages: hybrid(0),
+				Budget: &hashjob.Budget{MaxCandidates: 500},
+			},
+			wantStatus:    hashjob.HashJobStatusExhausted,
+			wantCracked:   1,
+			wantPosition:  500,
+			wantCandidate: 500,
+		},
+		{
+			name: "Test Process resumes within candidate budget",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042"), md5Hex("code999")},
+				Stages: hybrid(1000),
+				Budget: &hashjob.Budget{MaxCandidates: 1500},
+				Stats:  crack.Stats{Candidates: 1000},
+			},
+			wantStatus:    hashjob.HashJobStatusExhausted,
+			wantCracked:   0,
+			wantPosition:  1500,
+			wantCandidate: 1500,
+		},
+		{
+			name: "Test Process out of runtime budget",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042")},
+				Stages: hybrid(0),
+				Budget: &hashjob.Budget{MaxRuntimeSeconds: 1},
+				Stats:  crack.Stats{Elapsed: time.Second},
+			},
+			wantStatus: hashjob.HashJobStatusTimedOut,
+		},
+		{
+			name: "Test Process requeues on shutdown",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042")},
+				Stages: hybrid(0),
+			},
+			cancel:     true,
+			wantStatus: hashjob.HashJobStatusPending,
+		},
+		{
+			name: "Test Process with missing wordlist",
+			job: hashjob.HashJob{
+				Hashes: []string{md5Hex("pin042")},
+				Stages: []hashjob.Stage{attackStage(&attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"missing"}})},
+			},
+			wantStatus: hashjob.HashJobStatusError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.job.ID = "test"
+			tt.job.OwnerId = "test"
+			tt.job.HashType = hashtype.HashTypeMD5
+
+			queue := &MockJobQueue{}
+			w := newTestWorker(t, queue, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			hj := tt.job
+			w.Process(ctx, &hj)
+
+			got, ok := queue.Updated["test"]
+			if !ok {
+				t.Fatalf("Process() did not save the job")
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Process() status = %v, want %v (error %v)", got.Status, tt.wantStatus, got.Error)
+			}
+			if len(got.Cracked) != tt.wantCracked {
+				t.Errorf("Process() cracked = %v, want %v", got.Cracked, tt.wantCracked)
+			}
+			var position uint64
+			if len(got.Stages) > 0 {
+				position = got.Stages[0].Position
+			}
+			if position != tt.wantPosition {
+				t.Errorf("Process() position = %v, want %v", position, tt.wantPosition)
+			}
+			if got.Stats.Candidates != tt.wantCandidate {
+				t.Errorf("Process() candidates = %v, want %v", got.Stats.Candidates, tt.wantCandidate)
+			}
+			if got.Status == hashjob.HashJobStatusError && got.Error == "" {
+				t.Errorf("Process() error status without error message")
+			}
+		})
+	}
+}
+
+func TestWorker_Process_Usage(t *testing.T) {
+	tests := []struct {
+		name        string
+		meter       *MockUsageMeter
+		mask        string
+		wantStatus  hashjob.HashJobStatus
+		wantRecords int
+	}{
+		{"unlimited", &MockUsageMeter{}, "?d?d?d", hashjob.HashJobStatusDone, 1},
+		{"within quota", &MockUsageMeter{Allowance: time.Hour, Limited: true}, "?d?d?d", hashjob.HashJobStatusDone, 1},
+		{"quota used up", &MockUsageMeter{Limited: true}, "?d?d?d", hashjob.HashJobStatusQuotaExceeded, 0},
+		{"quota runs out", &MockUsageMeter{Allowance: 10 * time.Millisecond, Limited: true}, "?a?a?a?a?a?a", hashjob.HashJobStatusQuotaExceeded, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &MockJobQueue{}
+			w := newTestWorker(t, queue, nil)
+			w.usage = tt.meter
+
+			hj := hashjob.HashJob{
+				ID:       "test",
+				OwnerId:  "alice",
+				TeamId:   "red",
+				HashType: hashtype.HashTypeMD5,
+				Hashes:   []string{md5Hex("pin042"), md5Hex("nope")},
+				Stages:   []hashjob.Stage{attackStage(&attack.Attack{Type: attack.AttackTypeHybridWordlistMask, Wordlists: []string{"words"}, Mask: tt.mask})},
+			}
+			if tt.wantStatus == hashjob.HashJobStatusDone {
+				hj.Hashes = hj.Hashes[:1]
+			}
+			w.Process(context.Background(), &hj)
+
+			got := queue.Updated["test"]
+			if got.Status != tt.wantStatus {
+				t.Errorf("Process() status = %v, want %v (error %v)", got.Status, tt.wantStatus, got.Error)
+			}
+			if len(tt.meter.Records) != tt.wantRecords {
+				t.Fatalf("Process() records = %v, want %d", tt.meter.Records, tt.wantRecords)
+			}
+			for _, r := range tt.meter.Records {
+				if r.JobID != "test" || r.UserID != "alice" || r.TeamID != "red" || r.Candidates != got.Stats.Candidates {
+					t.Errorf("Process() record = %+v, want the job's usage", r)
+				}
+			}
+		})
+	}
+}
+
+func TestWorker_Run(t *testing.T) {
+	queue := &MockJobQueue{
+		Pending: []hashjob.HashJob{
+			{ID: "a", OwnerId: "test", HashType: hashtype.HashTypeMD5, Hashes: []string{md5Hex("pin042")}},
+		},
+	}
+	w := newTestWorker(t, queue, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.jobs = &cancelingQueue{MockJobQueue: queue, cancel: cancel}
+
+	err := w.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if queue.Updated["a"].Status != hashjob.HashJobStatusDone {
+		t.Errorf("Run() status = %v, want %v", queue.Updated["a"].Status, hashjob.HashJobStatusDone)
+	}
+}
+
+// cancelingQueue stops the worker once the queue is drained.
+type cancelingQueue struct {
+	*MockJobQueue
+	cancel context.CancelFunc
+}
+
+func (c *cancelingQueue) ClaimHashJob() (*hashjob.HashJob, error) {
+	hj, err := c.MockJobQueue.ClaimHashJob()
+	if err != nil {
+		c.cancel()
+	}
+	return hj, err
+}
+
+func TestWorker_Process_Pipeline(t *testing.T) {
+	pot := &MockPotfile{Plains: map[string]string{
+		md5Hex("pin042"):  "pin042",
+		md5Hex("secret"):  "wrong",
+		md5Hex("missing"): "missing",
+	}}
+	queue := &MockJobQueue{}
+	w := newTestWorker(t, queue, pot)
+
+	hashes := []string{md5Hex("pin042"), md5Hex("code"), md5Hex("Pin"), md5Hex("code77"), md5Hex("zz9"), md5Hex("secret")}
+	hj := hashjob.HashJob{
+		ID:       "test",
+		OwnerId:  "test",
+		HashType: hashtype.HashTypeMD5,
+		Hashes:   hashes,
+		Stages: []hashjob.Stage{
+			{Type: hashjob.StageTypePotfile, Status: hashjob.StageStatusPending},
+			attackStage(&attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"words"}}),
+			attackStage(&attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"words"}, Rules: "rules"}),
+			attackStage(&attack.Attack{Type: attack.AttackTypeHybridWordlistMask, Wordlists: []string{"words"}, Mask: "?d?d"}),
+			attackStage(&attack.Attack{Type: attack.AttackTypeMask, Mask: "?l?l?d"}),
+		},
+	}
+
+	w.Process(context.Background(), &hj)
+
+	got := queue.Updated["test"]
+	if got.Status != hashjob.HashJobStatusDone {
+		t.Fatalf("Process() status = %v, want %v (error %v)", got.Status, hashjob.HashJobStatusDone, got.Error)
+	}
+
+	wantBy := map[string]int{
+		md5Hex("pin042"): 0,
+		md5Hex("code"):   1,
+		md5Hex("Pin"):    2,
+		md5Hex("code77"): 3,
+		md5Hex("zz9"):    4,
+	}
+	if len(got.CrackedBy) != len(wantBy) {
+		t.Errorf("Process() crackedBy = %v, want %v", got.CrackedBy, wantBy)
+	}
+	for hash, stage := range wantBy {
+		if by, ok := got.CrackedBy[hash]; !ok || by != stage {
+			t.Errorf("Process() crackedBy[%v] = %v, want %v", hash, by, stage)
+		}
+	}
+	if _, ok := got.Cracked[md5Hex("secret")]; ok {
+		t.Errorf("Process() cracked secret from a wrong potfile entry")
+	}
+
+	for i, stage := range got.Stages {
+		if stage.Status != hashjob.StageStatusDone {
+			t.Errorf("Process() stage %d status = %v, want %v", i, stage.Status, hashjob.StageStatusDone)
+		}
+		if stage.Stats.Cracked != 1 {
+			t.Errorf("Process() stage %d cracked = %v, want 1", i, stage.Stats.Cracked)
+		}
+	}
+	if got.Stages[2].Keyspace != 4 {
+		t.Errorf("Process() rules stage keyspace = %v, want 4", got.Stages[2].Keyspace)
+	}
+	if got.Stats.Cracked != 5 {
+		t.Errorf("Process() cracked = %v, want 5", got.Stats.Cracked)
+	}
+	if pot.Plains[md5Hex("zz9")] != "zz9" {
+		t.Errorf("Process() did not add cracked hashes to the potfile")
+	}
+}
+
+func TestWorker_Process_SkipsStages(t *testing.T) {
+	pot := &MockPotfile{Plains: map[string]string{md5Hex("pin042"): "pin042"}}
+	queue := &MockJobQueue{}
+	w := newTestWorker(t, queue, pot)
+
+	hj := hashjob.HashJob{
+		ID:       "test",
+		OwnerId:  "test",
+		HashType: hashtype.HashTypeMD5,
+		Hashes:   []string{md5Hex("pin042")},
+		Stages: []hashjob.Stage{
+			{Type: hashjob.StageTypePotfile, Status: hashjob.StageStatusPending},
+			attackStage(&attack.Attack{Type: attack.AttackTypeMask, Mask: "?a?a?a?a?a?a"}),
+		},
+	}
+
+	w.Process(context.Background(), &hj)
+
+	got := queue.Updated["test"]
+	if got.Status != hashjob.HashJobStatusDone {
+		t.Errorf("Process() status = %v, want %v", got.Status, hashjob.HashJobStatusDone)
+	}
+	if got.Stages[1].Status != hashjob.StageStatusSkipped || got.Stages[1].Stats.Candidates != 0 {
+		t.Errorf("Process() stage 1 = %v, want skipped", got.Stages[1])
+	}
+	if got.Stage != 2 {
+		t.Errorf("Process() stage = %v, want 2", got.Stage)
+	}
+}
+
+func TestWorker_Process_AppendedHashes(t *testing.T) {
+	hashes := []string{md5Hex("code")}
+	queue := &MockJobQueue{
+		Stored: map[string]hashjob.HashJob{"test": {ID: "test", Hashes: hashes}},
+	}
+	// Hashes appended after the first stage are picked up by the second, and
+	// their labels kept.
+	queue.OnUpdate = func(h hashjob.HashJob) {
+		if h.Stage == 0 {
+			queue.Stored["test"] = hashjob.HashJob{
+				ID:     "test",
+				Hashes: append(hashes, md5Hex("ab1")),
+				Labels: map[string][]string{md5Hex("ab1"): {"alice"}},
+			}
+		}
+	}
+	w := newTestWorker(t, queue, nil)
+
+	hj := hashjob.HashJob{
+		ID:       "test",
+		OwnerId:  "test",
+		HashType: hashtype.HashTypeMD5,
+		Hashes:   hashes,
+		Stages: []hashjob.Stage{
+			attackStage(&attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"words"}}),
+			attackStage(&attack.Attack{Type: attack.AttackTypeMask, Mask: "?l?l?d"}),
+		},
+	}
+
+	w.Process(context.Background(), &hj)
+
+	got := queue.Updated["test"]
+	if got.Status != hashjob.HashJobStatusDone {
+		t.Fatalf("Process() status = %v, want %v (error %v)", got.Status, hashjob.HashJobStatusDone, got.Error)
+	}
+	if len(got.Hashes) != 2 {
+		t.Errorf("Process() hashes = %v, want 2", got.Hashes)
+	}
+	if got.Cracked[md5Hex("ab1")] != "ab1" || got.CrackedBy[md5Hex("ab1")] != 1 {
+		t.Errorf("Process() did not crack appended hash in stage 1, cracked = %v by %v", got.Cracked, got.CrackedBy)
+	}
+	if labels := got.Labels[md5Hex("ab1")]; len(labels) != 1 || labels[0] != "alice" {
+		t.Errorf("Process() labels = %v, want the appended hash's label kept", got.Labels)
+	}
+}
+
+func TestWorker_Process_Rainbow(t *testing.T) {
+	tables := make([]RainbowTable, 0, 2)
+	for _, hashType := range []hashtype.HashType{hashtype.HashTypeNTLM, hashtype.HashTypeMD5} {
+		// Every plaintext of the space starts a chain, so every one is found.
+		table, err := rainbow.Generate(rainbow.Params{HashType: hashType, Charset: "abc", Length: 3, ChainLength: 5}, 27)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		tables = append(tables, table)
+	}
+
+	hashes := []string{md5Hex("abc"), md5Hex("zzz"), md5Hex("cab"), md5Hex("bca")}
+	tests := []struct {
+		name         string
+		ctx          func() context.Context
+		position     uint64
+		wantStatus   hashjob.HashJobStatus
+		wantCracked  []string
+		wantPosition uint64
+	}{
+		{"from the start", context.Background, 0, hashjob.HashJobStatusDone, []string{"abc", "cab", "bca"}, 4},
+		{"resumed", context.Background, 2, hashjob.HashJobStatusDone, []string{"cab", "bca"}, 4},
+		{"interrupted", func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, 1, hashjob.HashJobStatusPending, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &MockJobQueue{}
+			w := newTestWorker(t, queue, nil)
+			w.rainbows = tables
+
+			hj := hashjob.HashJob{
+				ID:       "test",
+				OwnerId:  "test",
+				HashType: hashtype.HashTypeMD5,
+				Hashes:   hashes,
+				Stages:   []hashjob.Stage{{Type: hashjob.StageTypeRainbow, Status: hashjob.StageStatusPending, Position: tt.position}},
+			}
+			w.Process(tt.ctx(), &hj)
+
+			got := queue.Updated["test"]
+			if got.Status != tt.wantStatus {
+				t.Fatalf("Process() status = %v, want %v (error %v)", got.Status, tt.wantStatus, got.Error)
+			}
+			if len(got.Cracked) != len(tt.wantCracked) {
+				t.Errorf("Process() cracked = %v, want %v", got.Cracked, tt.wantCracked)
+			}
+			for _, plain := range tt.wantCracked {
+				if got.Cracked[md5Hex(plain)] != plain {
+					t.Errorf("Process() did not crack %v, cracked = %v", plain, got.Cracked)
+				}
+			}
+			if got.Stages[0].Position != tt.wantPosition || got.Stages[0].Keyspace != uint64(len(hashes)) {
+				t.Errorf("Process() position = %v of %v, want %v of %v", got.Stages[0].Position, got.Stages[0].Keyspace, tt.wantPosition, len(hashes))
+			}
+		})
+	}
+}
+
+func TestWorker_Process_ResumesCompressedWordlist(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("one\ntwo\nthree\nfour\n"))
+	zw.Close()
+
+	tests := []struct {
+		name   string
+		offset uint64
+	}{
+		{"from offset", uint64(len("one\ntwo\n"))},
+		{"checkpoint without offset", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &MockJobQueue{}
+			w := newTestWorker(t, queue, nil)
+			locator := w.library.(*MockResourceLocator)
+			path := filepath.Join(t.TempDir(), "words.gz")
+			err := os.WriteFile(path, buf.Bytes(), 0o644)
+			if err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			locator.Paths["gz"] = path
+			locator.Lines = map[string]uint64{"gz": 4}
+
+			stage := attackStage(&attack.Attack{Type: attack.AttackTypeDictionary, Wordlists: []string{"gz"}})
+			stage.Position = 2
+			stage.Offset = tt.offset
+			hj := hashjob.HashJob{
+				ID:       "test",
+				OwnerId:  "test",
+				HashType: hashtype.HashTypeMD5,
+				Hashes:   []string{md5Hex("one"), md5Hex("three")},
+				Stages:   []hashjob.Stage{stage},
+			}
+			w.Process(context.Background(), &hj)
+
+			got := queue.Updated["test"]
+			if got.Status != hashjob.HashJobStatusDone {
+				t.Fatalf("Process() status = %v, want %v (error %v)", got.Status, hashjob.HashJobStatusDone, got.Error)
+			}
+			if _, ok := got.Cracked[md5Hex("one")]; ok || got.Cracked[md5Hex("three")] != "three" {
+				t.Errorf("Process() cracked = %v, want only three", got.Cracked)
+			}
+			s := got.Stages[0]
+			if s.Stats.Candidates != 2 || s.Position != 4 || s.Offset != uint64(len("one\ntwo\nthree\nfour\n")) {
+				t.Errorf("Process() stage tried %d candidates up to %d at offset %d, want 2 up to 4 at the end", s.Stats.Candidates, s.Position, s.Offset)
+			}
+		})
+	}
+}
+
+func TestWorker_Process_Chunks(t *testing.T) {
+	tests := []struct {
+		name            string
+		size            uint64
+		budget          *hashjob.Budget
+		wantStatus      hashjob.HashJobStatus
+		wantCheckpoints []uint64
+		wantCandidates  uint64
+	}{
+		{"chunked", 500, nil, hashjob.HashJobStatusDone, []uint64{500, 1000, 1500}, 2000},
+		{"budget within chunk", 500, &hashjob.Budget{MaxCandidates: 700}, hashjob.HashJobStatusExhausted, []uint64{500}, 700},
+		{"without benchmark", 0, nil, hashjob.HashJobStatusDone, nil, 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checkpoints []uint64
+			queue := &MockJobQueue{OnUpdate: func(h hashjob.HashJob) {
+				if h.Status == hashjob.HashJobStatusRunning && h.Stages[0].Status == hashjob.StageStatusRunning {
+					checkpoints = append(checkpoints, h.Stages[0].Position)
+				}
+			}}
+			w := newTestWorker(t, queue, nil)
+			w.benchmarks = &MockBenchmarks{Size: tt.size}
+
+			hj := hashjob.HashJob{
+				ID:       "test",
+				OwnerId:  "test",
+				HashType: hashtype.HashTypeMD5,
+				Hashes:   []string{md5Hex("pin042"), md5Hex("nope")},
+				Status:   hashjob.HashJobStatusRunning,
+				Budget:   tt.budget,
+				Stages:   []hashjob.Stage{attackStage(&attack.Attack{Type: attack.AttackTypeHybridWordlistMask, Wordlists: []string{"words"}, Mask: "?d?d?d"})},
+			}
+			w.Process(context.Background(), &hj)
+
+			got := queue.Updated["test"]
+			if got.Status != tt.wantStatus {
+				t.Fatalf("Process() status = %v, want %v (error %v)", got.Status, tt.wantStatus, got.Error)
+			}
+			if got.Cracked[md5Hex("pin042")] != "pin042" {
+				t.Errorf("Process() cracked = %v, want pin042", got.Cracked)
+			}
+			if got.Stats.Candidates != tt.wantCandidates {
+				t.Errorf("Process() candidates = %v, want %v", got.Stats.Candidates, tt.wantCandidates)
+			}
+			if fmt.Sprint(checkpoints) != fmt.Sprint(tt.wantCheckpoints) {
+				t.Errorf("Process() checkpoints = %v, want %v", checkpoints, tt.wantCheckpoints)
+			}
+		})
+	}
+}